@@ -0,0 +1,826 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Array adapts a slice to a PostgreSQL array, for use as a parameter or as
+// a scan destination:
+//
+//	db.Query("SELECT * FROM t WHERE id = ANY($1)", pq.Array([]int64{1, 2}))
+//
+//	var names []string
+//	row.Scan(pq.Array(&names))
+//
+// Elements may be strings, []byte, bools, numbers, times, pointers to any
+// of those, with nil for NULL, or types implementing driver.Valuer and
+// sql.Scanner, such as sql.NullTime. Times come from timestamp,
+// timestamptz and date arrays. Nested slices are multidimensional arrays. Slices of int64, float64, bool, string and []byte, and
+// pointers to them, use the typed arrays such as Int64Array, which avoid
+// reflection.
+func Array(a interface{}) interface {
+	driver.Valuer
+	sql.Scanner
+} {
+	switch a := a.(type) {
+	case []int64:
+		return (*Int64Array)(&a)
+	case *[]int64:
+		return (*Int64Array)(a)
+	case []float64:
+		return (*Float64Array)(&a)
+	case *[]float64:
+		return (*Float64Array)(a)
+	case []bool:
+		return (*BoolArray)(&a)
+	case *[]bool:
+		return (*BoolArray)(a)
+	case []string:
+		return (*StringArray)(&a)
+	case *[]string:
+		return (*StringArray)(a)
+	case [][]byte:
+		return (*ByteaArray)(&a)
+	case *[][]byte:
+		return (*ByteaArray)(a)
+	}
+	return GenericArray{a}
+}
+
+// GenericArray is the array returned by Array. A is a slice, or a pointer
+// to one; Scan needs the pointer.
+type GenericArray struct {
+	A interface{}
+}
+
+// Value implements driver.Valuer, formatting the slice as an array
+// literal. A nil slice is NULL.
+func (a GenericArray) Value() (v driver.Value, err error) {
+	defer recoverErr(&err)
+
+	rv := reflect.ValueOf(a.A)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, errf("cannot convert %T to an array", a.A)
+	}
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
+		return nil, nil
+	}
+
+	b := []byte{'{'}
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendArrayElem(b, rv.Index(i))
+	}
+	return string(append(b, '}')), nil
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// appendArrayElem appends the array literal form of the element v to b.
+func appendArrayElem(b []byte, v reflect.Value) []byte {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return append(b, "NULL"...)
+	}
+	if e, ok := v.Interface().(Enum); ok {
+		return appendArrayQuoted(b, e.EnumLabel())
+	}
+	if v.Type().Implements(valuerType) {
+		x, err := v.Interface().(driver.Valuer).Value()
+		if err != nil {
+			panic(err)
+		}
+		if x == nil {
+			return append(b, "NULL"...)
+		}
+		v = reflect.ValueOf(x)
+	}
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		return appendArrayElem(b, v.Elem())
+	}
+
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if sign, ok := isInfinity(x); ok && sign < 0 {
+			return append(b, "-infinity"...)
+		} else if ok {
+			return append(b, "infinity"...)
+		}
+		return appendArrayQuoted(b, formatTimestamp(x))
+	case []byte:
+		return appendArrayQuoted(b, `\x`+hex.EncodeToString(x))
+	}
+	if s, ok := uuidParam(v.Interface()); ok {
+		return appendArrayQuoted(b, s)
+	}
+	if s, ok := netParam(v.Interface()); ok {
+		if s == "" {
+			return append(b, "NULL"...)
+		}
+		return appendArrayQuoted(b, s)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return appendArrayQuoted(b, v.String())
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 't')
+		}
+		return append(b, 'f')
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(b, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(b, v.Uint(), 10)
+	case reflect.Float32:
+		return strconv.AppendFloat(b, v.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.AppendFloat(b, v.Float(), 'g', -1, 64)
+	case reflect.Slice, reflect.Array:
+		// A sub-array of a multidimensional array.
+		b = append(b, '{')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendArrayElem(b, v.Index(i))
+		}
+		return append(b, '}')
+	case reflect.Struct:
+		return appendArrayQuoted(b, string(appendComposite(nil, v)))
+	}
+	panic(errf("cannot convert array element of type %s", v.Type()))
+}
+
+// appendArrayQuoted appends s to b as a double-quoted array element.
+func appendArrayQuoted(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b = append(b, '\\')
+		}
+		b = append(b, s[i])
+	}
+	return append(b, '"')
+}
+
+// Scan implements sql.Scanner, replacing the slice A points to with the
+// elements of the array. NULL sets it to nil. A multidimensional array
+// needs nested slices, such as [][]int64 for an integer[][].
+func (a GenericArray) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	rv := reflect.ValueOf(a.A)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return errf("cannot scan into %T: an array needs a pointer to a slice", a.A)
+	}
+	dst := rv.Elem()
+
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errf("cannot scan %T into an array", src)
+	}
+
+	dims, elems := parseArrayDims(b, ',')
+	if len(dims) == 0 {
+		dst.Set(reflect.MakeSlice(dst.Type(), 0, 0))
+		return nil
+	}
+	if n := arrayDepth(dst.Type()); n != len(dims) {
+		return errf("cannot scan a %d-dimensional array into %s", len(dims), dst.Type())
+	}
+	dst.Set(makeArray(dst.Type(), dims, &elems))
+	return nil
+}
+
+// arrayDepth returns the number of dimensions of the nested slice type t,
+// whose innermost elements aren't slices themselves, or are []byte or
+// sql.Scanner slices.
+func arrayDepth(t reflect.Type) int {
+	n := 0
+	for t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 && !reflect.PtrTo(t).Implements(scannerType) {
+		n++
+		t = t.Elem()
+	}
+	return n
+}
+
+// makeArray returns a slice of type t holding the array of the dimensions
+// dims, taking its elements from the front of elems.
+func makeArray(t reflect.Type, dims []int, elems *[][]byte) reflect.Value {
+	s := reflect.MakeSlice(t, dims[0], dims[0])
+	for i := 0; i < dims[0]; i++ {
+		if len(dims) > 1 {
+			s.Index(i).Set(makeArray(t.Elem(), dims[1:], elems))
+			continue
+		}
+		scanArrayElem(s.Index(i), (*elems)[0])
+		*elems = (*elems)[1:]
+	}
+	return s
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// scanArrayElem sets v to the element b of an array, nil for NULL.
+func scanArrayElem(v reflect.Value, b []byte) {
+	if nt, ok := v.Addr().Interface().(*sql.NullTime); ok {
+		// sql.NullTime only scans a time.Time.
+		*nt = sql.NullTime{}
+		if b != nil {
+			nt.Time, nt.Valid = parseArrayTime(b), true
+		}
+		return
+	}
+	if v.Addr().Type().Implements(scannerType) {
+		var src interface{}
+		if b != nil {
+			src = append([]byte(nil), b...)
+		}
+		if err := v.Addr().Interface().(sql.Scanner).Scan(src); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if v.Kind() == reflect.Ptr {
+		if b != nil {
+			p := reflect.New(v.Type().Elem())
+			scanArrayElem(p.Elem(), b)
+			v.Set(p)
+		}
+		return
+	}
+	if b == nil {
+		if v.Kind() == reflect.Interface || v.Kind() == reflect.Slice {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		panic(errf("cannot scan a NULL array element into %s", v.Type()))
+	}
+
+	switch v.Interface().(type) {
+	case time.Time:
+		v.Set(reflect.ValueOf(parseArrayTime(b)))
+		return
+	case []byte:
+		v.SetBytes(decodeBytea(b))
+		return
+	case net.IP:
+		ip, ok := decodeInet(b).(net.IP)
+		if !ok {
+			panic(errf("cannot scan %s into a net.IP", b))
+		}
+		v.Set(reflect.ValueOf(ip))
+		return
+	case net.IPNet:
+		n, ok := decodeInet(b).(*net.IPNet)
+		if !ok {
+			panic(errf("cannot scan %s into a net.IPNet", b))
+		}
+		v.Set(reflect.ValueOf(*n))
+		return
+	case net.HardwareAddr:
+		v.Set(reflect.ValueOf(decodeMacaddr(b)))
+		return
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(string(b))
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(string(b)))
+	case reflect.Bool:
+		if len(b) != 1 || (b[0] != 't' && b[0] != 'f') {
+			panic(errf("invalid boolean array element %q", b))
+		}
+		v.SetBool(b[0] == 't')
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(string(b), 10, v.Type().Bits())
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(string(b), 10, v.Type().Bits())
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(string(b), v.Type().Bits())
+		v.SetFloat(f)
+	case reflect.Struct:
+		scanComposite(v, b)
+	case reflect.Slice:
+		// An array field of a composite.
+		if err := (GenericArray{v.Addr().Interface()}).Scan(b); err != nil {
+			panic(err)
+		}
+	case reflect.Array:
+		// The elements of a uuid[], into any [16]byte type.
+		u, ok := parseUUID(string(b))
+		if !ok || v.Len() != 16 || v.Type().Elem().Kind() != reflect.Uint8 {
+			panic(errf("cannot scan array element %q into %s", b, v.Type()))
+		}
+		reflect.Copy(v, reflect.ValueOf(u))
+	default:
+		panic(errf("cannot scan an array element into %s", v.Type()))
+	}
+	if err != nil {
+		panic(errf("invalid array element %q for %s", b, v.Type()))
+	}
+}
+
+// parseArrayTime parses an element of a timestamp, timestamptz or date
+// array.
+func parseArrayTime(b []byte) time.Time {
+	if v, ok := decodeInfinity(b); ok {
+		t, ok := v.(time.Time)
+		if !ok {
+			panic(errf("cannot scan %s into a time.Time; see EnableInfinityTs", b))
+		}
+		return t
+	}
+
+	var t time.Time
+	var err error
+	if bytes.IndexByte(b, ':') < 0 {
+		t, err = parseDate(string(b))
+	} else {
+		t, err = parseTimestamp(string(b))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// parseArray splits the text form of a one-dimensional array into its
+// elements, undoing their quoting. NULL elements are nil, while empty
+// strings are not. Unquoted elements share memory with b.
+func parseArray(b []byte, del byte) [][]byte {
+	p := arrayParser{b: b, del: del}
+	p.parse()
+	if p.ndim > 1 {
+		panic(errf("cannot use a %d-dimensional array as a one-dimensional one", p.ndim))
+	}
+	return p.elems
+}
+
+// parseArrayDims parses the text form of an array of any number of
+// dimensions, returning the length of each and the elements in row-major
+// order. The explicit bounds of arrays not starting at 1, as in
+// [0:1]={a,b}, are checked and dropped. An empty array has no dimensions.
+func parseArrayDims(b []byte, del byte) (dims []int, elems [][]byte) {
+	p := arrayParser{b: b, del: del}
+	p.parse()
+	return append([]int(nil), p.dims[:p.ndim]...), p.elems
+}
+
+type arrayParser struct {
+	b     []byte
+	i     int
+	del   byte
+	leaf  int // depth of the elements, once one has been seen
+	elems [][]byte
+
+	// dims holds the length of the ndim dimensions seen. The server
+	// allows at most 6.
+	dims [6]int
+	ndim int
+}
+
+func (p *arrayParser) parse() {
+	p.leaf = -1
+	bounds := p.bounds()
+	p.elems = make([][]byte, 0, bytes.Count(p.b, []byte{p.del})+1)
+	p.array(0)
+	p.space()
+	if p.i != len(p.b) {
+		p.fail("junk after the closing brace")
+	}
+
+	if len(p.elems) == 0 {
+		p.ndim = 0
+	}
+	if bounds != nil && !equalInts(bounds, p.dims[:p.ndim]) {
+		p.fail("dimensions don't match the bounds")
+	}
+}
+
+func (p *arrayParser) fail(why string) {
+	panic(errf("invalid array %q: %s", p.b, why))
+}
+
+func (p *arrayParser) space() {
+	for p.i < len(p.b) && isArraySpace(p.b[p.i]) {
+		p.i++
+	}
+}
+
+// bounds reads the optional dimension decoration, returning the length of
+// each dimension.
+func (p *arrayParser) bounds() []int {
+	if len(p.b) == 0 || p.b[0] != '[' {
+		return nil
+	}
+	var dims []int
+	for p.i < len(p.b) && p.b[p.i] == '[' {
+		p.i++
+		lo := p.int(':')
+		hi := p.int(']')
+		if hi < lo-1 {
+			p.fail("upper bound below lower bound")
+		}
+		dims = append(dims, hi-lo+1)
+	}
+	if p.i == len(p.b) || p.b[p.i] != '=' {
+		p.fail("expected = after the bounds")
+	}
+	p.i++
+	p.space()
+	return dims
+}
+
+// int reads a bound up to the byte end.
+func (p *arrayParser) int(end byte) int {
+	j := bytes.IndexByte(p.b[p.i:], end)
+	if j < 0 {
+		p.fail("unterminated bounds")
+	}
+	n, err := strconv.Atoi(string(p.b[p.i : p.i+j]))
+	if err != nil {
+		p.fail("invalid bounds")
+	}
+	p.i += j + 1
+	return n
+}
+
+// array reads a brace-delimited array at the given nesting depth.
+func (p *arrayParser) array(depth int) {
+	if p.i == len(p.b) || p.b[p.i] != '{' {
+		p.fail("expected {")
+	}
+	if depth == p.ndim {
+		if p.ndim == len(p.dims) {
+			p.fail("too many dimensions")
+		}
+		p.dims[depth] = -1
+		p.ndim++
+	}
+	p.i++
+	p.space()
+
+	n := 0
+	if p.i < len(p.b) && p.b[p.i] == '}' {
+		p.i++
+	} else {
+		for {
+			p.space()
+			if p.i < len(p.b) && p.b[p.i] == '{' {
+				p.array(depth + 1)
+			} else {
+				p.elem(depth)
+			}
+			n++
+
+			p.space()
+			if p.i == len(p.b) {
+				p.fail("missing closing brace")
+			}
+			if p.b[p.i] == '}' {
+				p.i++
+				break
+			}
+			if p.b[p.i] != p.del {
+				p.fail(fmt.Sprintf("expected %q at offset %d", p.del, p.i))
+			}
+			p.i++
+		}
+	}
+
+	if p.dims[depth] == -1 {
+		p.dims[depth] = n
+	} else if p.dims[depth] != n {
+		p.fail("sub-arrays must have matching dimensions")
+	}
+}
+
+// elem reads an element, quoted or not, at the given nesting depth.
+func (p *arrayParser) elem(depth int) {
+	if p.leaf == -1 {
+		p.leaf = depth
+	} else if p.leaf != depth {
+		p.fail("elements at different depths")
+	}
+
+	if p.i == len(p.b) {
+		p.fail("missing element")
+	}
+	if p.b[p.i] == '"' {
+		e := []byte{}
+		for p.i++; ; p.i++ {
+			if p.i == len(p.b) {
+				p.fail("unterminated string")
+			}
+			if p.b[p.i] == '"' {
+				p.i++
+				break
+			}
+			if p.b[p.i] == '\\' && p.i+1 < len(p.b) {
+				p.i++
+			}
+			e = append(e, p.b[p.i])
+		}
+		p.elems = append(p.elems, e)
+		return
+	}
+
+	start := p.i
+	for p.i < len(p.b) && p.b[p.i] != p.del && p.b[p.i] != '}' {
+		if c := p.b[p.i]; c == '"' || c == '\\' || c == '{' {
+			p.fail(fmt.Sprintf("unexpected %q", c))
+		}
+		p.i++
+	}
+	e := bytes.TrimRight(p.b[start:p.i], " \t\n\r")
+	if len(e) == 0 {
+		p.fail("missing element")
+	}
+	if len(e) == 4 && bytes.EqualFold(e, []byte("NULL")) {
+		e = nil
+	}
+	p.elems = append(p.elems, e)
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isArraySpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// arraySrc returns the text of an array being scanned, reporting false for
+// NULL.
+func arraySrc(src interface{}, into string) ([]byte, bool) {
+	switch v := src.(type) {
+	case nil:
+		return nil, false
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	}
+	panic(errf("cannot scan %T into a %s", src, into))
+}
+
+// notNull panics if the element e of an array is NULL, which a typed array
+// has no way to hold.
+func notNull(e []byte, into string) {
+	if e == nil {
+		panic(errf("cannot scan a NULL element into a %s", into))
+	}
+}
+
+// Int64Array is a bigint[], or any other array of integers.
+type Int64Array []int64
+
+// Scan implements sql.Scanner.
+func (a *Int64Array) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	b, ok := arraySrc(src, "Int64Array")
+	if !ok {
+		*a = nil
+		return nil
+	}
+	elems := parseArray(b, ',')
+	r := make(Int64Array, len(elems))
+	for i, e := range elems {
+		notNull(e, "Int64Array")
+		if r[i], err = strconv.ParseInt(string(e), 10, 64); err != nil {
+			return errf("invalid Int64Array element %q", e)
+		}
+	}
+	*a = r
+	return nil
+}
+
+// Value implements driver.Valuer. A nil array is NULL.
+func (a Int64Array) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	b := make([]byte, 1, 2+len(a)*4)
+	b[0] = '{'
+	for i, n := range a {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendInt(b, n, 10)
+	}
+	return string(append(b, '}')), nil
+}
+
+// Float64Array is a double precision[], or any other array of numbers.
+type Float64Array []float64
+
+// Scan implements sql.Scanner.
+func (a *Float64Array) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	b, ok := arraySrc(src, "Float64Array")
+	if !ok {
+		*a = nil
+		return nil
+	}
+	elems := parseArray(b, ',')
+	r := make(Float64Array, len(elems))
+	for i, e := range elems {
+		notNull(e, "Float64Array")
+		if r[i], err = strconv.ParseFloat(string(e), 64); err != nil {
+			return errf("invalid Float64Array element %q", e)
+		}
+	}
+	*a = r
+	return nil
+}
+
+// Value implements driver.Valuer. A nil array is NULL.
+func (a Float64Array) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	b := make([]byte, 1, 2+len(a)*8)
+	b[0] = '{'
+	for i, f := range a {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendFloat(b, f, 'g', -1, 64)
+	}
+	return string(append(b, '}')), nil
+}
+
+// BoolArray is a boolean[].
+type BoolArray []bool
+
+// Scan implements sql.Scanner.
+func (a *BoolArray) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	b, ok := arraySrc(src, "BoolArray")
+	if !ok {
+		*a = nil
+		return nil
+	}
+	elems := parseArray(b, ',')
+	r := make(BoolArray, len(elems))
+	for i, e := range elems {
+		notNull(e, "BoolArray")
+		if len(e) != 1 || (e[0] != 't' && e[0] != 'f') {
+			return errf("invalid BoolArray element %q", e)
+		}
+		r[i] = e[0] == 't'
+	}
+	*a = r
+	return nil
+}
+
+// Value implements driver.Valuer. A nil array is NULL.
+func (a BoolArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	b := make([]byte, 1, 1+len(a)*2)
+	b[0] = '{'
+	for i, v := range a {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if v {
+			b = append(b, 't')
+		} else {
+			b = append(b, 'f')
+		}
+	}
+	return string(append(b, '}')), nil
+}
+
+// StringArray is a text[], or any other array of strings.
+type StringArray []string
+
+// Scan implements sql.Scanner.
+func (a *StringArray) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	b, ok := arraySrc(src, "StringArray")
+	if !ok {
+		*a = nil
+		return nil
+	}
+	elems := parseArray(b, ',')
+	r := make(StringArray, len(elems))
+	for i, e := range elems {
+		notNull(e, "StringArray")
+		r[i] = string(e)
+	}
+	*a = r
+	return nil
+}
+
+// Value implements driver.Valuer. A nil array is NULL.
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	n := 2
+	for _, s := range a {
+		n += len(s) + 3
+	}
+	b := make([]byte, 1, n)
+	b[0] = '{'
+	for i, s := range a {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendArrayQuoted(b, s)
+	}
+	return string(append(b, '}')), nil
+}
+
+// ByteaArray is a bytea[].
+type ByteaArray [][]byte
+
+// Scan implements sql.Scanner.
+func (a *ByteaArray) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	b, ok := arraySrc(src, "ByteaArray")
+	if !ok {
+		*a = nil
+		return nil
+	}
+	elems := parseArray(b, ',')
+	r := make(ByteaArray, len(elems))
+	for i, e := range elems {
+		notNull(e, "ByteaArray")
+		r[i] = decodeBytea(e)
+	}
+	*a = r
+	return nil
+}
+
+// Value implements driver.Valuer. A nil array is NULL.
+func (a ByteaArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	n := 2
+	for _, v := range a {
+		n += 2*len(v) + 6
+	}
+	b := make([]byte, 1, n)
+	b[0] = '{'
+	for i, v := range a {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `"\\x`...)
+		l := len(b)
+		b = b[:l+hex.EncodedLen(len(v))]
+		hex.Encode(b[l:], v)
+		b = append(b, '"')
+	}
+	return string(append(b, '}')), nil
+}
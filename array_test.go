@@ -0,0 +1,396 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArrayValue(t *testing.T) {
+	s := "x"
+	tests := []struct {
+		in       interface{}
+		expected interface{}
+	}{
+		{[]int64(nil), nil},
+		{[]int64{}, "{}"},
+		{[]int{1, -2, 3}, "{1,-2,3}"},
+		{[]float64{1.5, -0.25}, "{1.5,-0.25}"},
+		{[]bool{true, false}, "{t,f}"},
+		{[]string{"a", "", `q"b\`, "NULL", "x,y"}, `{"a","","q\"b\\","NULL","x,y"}`},
+		{[][]byte{{0xde, 0xad}, nil}, `{"\\xdead","\\x"}`},
+		{[]*string{&s, nil}, `{"x",NULL}`},
+		{[]sql.NullInt64{{Int64: 7, Valid: true}, {}}, "{7,NULL}"},
+		{&[]int{4}, "{4}"},
+		{[2]int{5, 6}, "{5,6}"},
+	}
+	for _, tt := range tests {
+		v, err := Array(tt.in).Value()
+		if err != nil {
+			t.Errorf("%#v: %v", tt.in, err)
+			continue
+		}
+		if v != tt.expected {
+			t.Errorf("%#v: expected %#v, got %#v", tt.in, tt.expected, v)
+		}
+	}
+
+	if _, err := Array(1).Value(); err == nil {
+		t.Error("expected an error for a non-slice")
+	}
+	if _, err := Array([]complex128{1}).Value(); err == nil {
+		t.Error("expected an error for an unsupported element type")
+	}
+}
+
+func TestArrayScan(t *testing.T) {
+	var strs []string
+	if err := Array(&strs).Scan([]byte(`{a,"",  "q\"b\\" ,"NULL","x,y"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a", "", `q"b\`, "NULL", "x,y"}; !reflect.DeepEqual(strs, expected) {
+		t.Fatalf("expected %q, got %q", expected, strs)
+	}
+
+	var ints []int32
+	if err := Array(&ints).Scan("{1,-2,3}"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int32{1, -2, 3}; !reflect.DeepEqual(ints, expected) {
+		t.Fatalf("expected %v, got %v", expected, ints)
+	}
+	if err := Array(&ints).Scan("{}"); err != nil || ints == nil || len(ints) != 0 {
+		t.Fatalf("expected an empty slice, got %v, %v", ints, err)
+	}
+	if err := Array(&ints).Scan(nil); err != nil || ints != nil {
+		t.Fatalf("expected NULL to scan as nil, got %v, %v", ints, err)
+	}
+	if err := Array(&ints).Scan("{1,NULL}"); err == nil {
+		t.Fatal("expected an error scanning NULL into an int32")
+	}
+	if err := Array(&ints).Scan("{3000000000}"); err == nil {
+		t.Fatal("expected an error for an out of range element")
+	}
+
+	var ptrs []*float64
+	if err := Array(&ptrs).Scan("{1.5,NULL}"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ptrs) != 2 || *ptrs[0] != 1.5 || ptrs[1] != nil {
+		t.Fatalf("unexpected elements %v", ptrs)
+	}
+
+	var nulls []sql.NullString
+	if err := Array(&nulls).Scan(`{NULL,null,"b"}`); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []sql.NullString{{}, {}, {String: "b", Valid: true}}; !reflect.DeepEqual(nulls, expected) {
+		t.Fatalf("expected %v, got %v", expected, nulls)
+	}
+
+	var bools []bool
+	if err := Array(&bools).Scan("{t,f}"); err != nil || !reflect.DeepEqual(bools, []bool{true, false}) {
+		t.Fatalf("unexpected %v, %v", bools, err)
+	}
+
+	var byteas [][]byte
+	if err := Array(&byteas).Scan(`{"\\xdead","\\x"}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(byteas) != 2 || !bytes.Equal(byteas[0], []byte{0xde, 0xad}) || len(byteas[1]) != 0 {
+		t.Fatalf("unexpected %v", byteas)
+	}
+
+	for _, in := range []string{"", "{", "1,2", `{"a}`, "{a,}", "{,a}", `{a"b}`, `{"a"b}`, "{{1}}"} {
+		if err := Array(&strs).Scan(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+	if err := Array(ints).Scan("{}"); err == nil {
+		t.Error("expected an error scanning into a non-pointer")
+	}
+}
+
+func TestTypedArrays(t *testing.T) {
+	tests := []struct {
+		a        interface{ Value() (driver.Value, error) }
+		expected string
+	}{
+		{Int64Array{1, -2}, "{1,-2}"},
+		{Float64Array{1.5, 2}, "{1.5,2}"},
+		{BoolArray{true, false}, "{t,f}"},
+		{StringArray{"a", `"b\`, ""}, `{"a","\"b\\",""}`},
+		{ByteaArray{{0x01, 0xff}, {}}, `{"\\x01ff","\\x"}`},
+		{Int64Array{}, "{}"},
+	}
+	for _, tt := range tests {
+		v, err := tt.a.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tt.expected {
+			t.Errorf("%#v: expected %s, got %v", tt.a, tt.expected, v)
+		}
+	}
+	if v, _ := Int64Array(nil).Value(); v != nil {
+		t.Errorf("expected a nil array to be NULL, got %v", v)
+	}
+
+	var ints Int64Array
+	if err := ints.Scan([]byte("{1,-2,3}")); err != nil || !reflect.DeepEqual(ints, Int64Array{1, -2, 3}) {
+		t.Fatalf("unexpected %v, %v", ints, err)
+	}
+	if err := ints.Scan("{1,NULL}"); err == nil {
+		t.Fatal("expected an error for a NULL element")
+	}
+	if err := ints.Scan("{1.5}"); err == nil {
+		t.Fatal("expected an error for an invalid element")
+	}
+	if err := ints.Scan(nil); err != nil || ints != nil {
+		t.Fatalf("expected NULL to scan as nil, got %v, %v", ints, err)
+	}
+
+	var floats Float64Array
+	if err := floats.Scan("{1.5,-2,NaN}"); err != nil || len(floats) != 3 || floats[0] != 1.5 || floats[1] != -2 {
+		t.Fatalf("unexpected %v, %v", floats, err)
+	}
+
+	var bools BoolArray
+	if err := bools.Scan("{t,f}"); err != nil || !reflect.DeepEqual(bools, BoolArray{true, false}) {
+		t.Fatalf("unexpected %v, %v", bools, err)
+	}
+
+	var strs StringArray
+	if err := strs.Scan(`{a,"b c","NULL"}`); err != nil || !reflect.DeepEqual(strs, StringArray{"a", "b c", "NULL"}) {
+		t.Fatalf("unexpected %q, %v", strs, err)
+	}
+
+	var byteas ByteaArray
+	if err := byteas.Scan(`{"\\x01ff"}`); err != nil || len(byteas) != 1 || !bytes.Equal(byteas[0], []byte{1, 0xff}) {
+		t.Fatalf("unexpected %v, %v", byteas, err)
+	}
+
+	if err := strs.Scan(1); err == nil {
+		t.Fatal("expected an error scanning an int")
+	}
+}
+
+func TestArrayTyped(t *testing.T) {
+	var ints []int64
+	if _, ok := Array(&ints).(*Int64Array); !ok {
+		t.Fatalf("expected an *Int64Array, got %T", Array(&ints))
+	}
+	if err := Array(&ints).Scan("{4}"); err != nil || !reflect.DeepEqual(ints, []int64{4}) {
+		t.Fatalf("unexpected %v, %v", ints, err)
+	}
+	if v, err := Array([]string{"a"}).Value(); err != nil || v != `{"a"}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
+
+// TestTypedArrayAllocs keeps the typed arrays from regressing to
+// allocating per element, beyond the strings they return.
+func TestTypedArrayAllocs(t *testing.T) {
+	var ints Int64Array
+	if n := testing.AllocsPerRun(100, func() { ints.Scan([]byte("{1,2,3,4,5}")) }); n > 4 {
+		t.Errorf("Int64Array.Scan: %v allocs, expected at most 4", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { ints.Value() }); n > 2 {
+		t.Errorf("Int64Array.Value: %v allocs, expected at most 2", n)
+	}
+
+	var strs StringArray
+	if n := testing.AllocsPerRun(100, func() { strs.Scan([]byte("{a,b,c}")) }); n > 5 {
+		t.Errorf("StringArray.Scan: %v allocs, expected at most 5", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { strs.Value() }); n > 2 {
+		t.Errorf("StringArray.Value: %v allocs, expected at most 2", n)
+	}
+}
+
+func TestMultidimensionalArray(t *testing.T) {
+	v, err := Array([][]int{{1, 2}, {3, 4}}).Value()
+	if err != nil || v != "{{1,2},{3,4}}" {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	v, err = Array([][]string{{"a"}, {`"`}}).Value()
+	if err != nil || v != `{{"a"},{"\""}}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	var ints [][]int
+	if err := Array(&ints).Scan("{{1,2},{3,4},{5,6}}"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := [][]int{{1, 2}, {3, 4}, {5, 6}}; !reflect.DeepEqual(ints, expected) {
+		t.Fatalf("expected %v, got %v", expected, ints)
+	}
+	if err := Array(&ints).Scan("[0:1][-1:1]={{1,2,3},{4,5,6}}"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := [][]int{{1, 2, 3}, {4, 5, 6}}; !reflect.DeepEqual(ints, expected) {
+		t.Fatalf("expected %v, got %v", expected, ints)
+	}
+	if err := Array(&ints).Scan("{}"); err != nil || ints == nil || len(ints) != 0 {
+		t.Fatalf("expected an empty slice, got %v, %v", ints, err)
+	}
+
+	var strs [][][]*string
+	if err := Array(&strs).Scan(`{{{a,NULL}},{{"}",b}}}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(strs) != 2 || *strs[0][0][0] != "a" || strs[0][0][1] != nil || *strs[1][0][0] != "}" {
+		t.Fatalf("unexpected %v", strs)
+	}
+
+	var byteas [][][]byte
+	if err := Array(&byteas).Scan(`{{"\\x01"}}`); err != nil || len(byteas) != 1 || !bytes.Equal(byteas[0][0], []byte{1}) {
+		t.Fatalf("unexpected %v, %v", byteas, err)
+	}
+
+	var flat []int
+	for _, in := range []string{
+		"{{1,2},{3}}",
+		"{{1},2}",
+		"{1,{2}}",
+		"[1:3]={1,2}",
+		"[1:2]{1,2}",
+		"{{{{{{{1}}}}}}}",
+	} {
+		if err := Array(&ints).Scan(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+	if err := Array(&flat).Scan("{{1,2}}"); err == nil {
+		t.Error("expected an error scanning a two-dimensional array into []int")
+	}
+	if err := Array(&ints).Scan("{1,2}"); err == nil {
+		t.Error("expected an error scanning a one-dimensional array into [][]int")
+	}
+	var typed Int64Array
+	if err := typed.Scan("{{1}}"); err == nil {
+		t.Error("expected an error scanning a two-dimensional array into an Int64Array")
+	}
+	if err := typed.Scan("[0:1]={7,8}"); err != nil || !reflect.DeepEqual(typed, Int64Array{7, 8}) {
+		t.Errorf("unexpected %v, %v", typed, err)
+	}
+}
+
+func TestTimeArray(t *testing.T) {
+	t1 := time.Date(2012, 12, 21, 11, 30, 45, 123456000, time.UTC)
+	v, err := Array([]time.Time{t1}).Value()
+	if err != nil || v != `{"2012-12-21 11:30:45.123456+00"}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	v, err = Array([]sql.NullTime{{Time: t1, Valid: true}, {}}).Value()
+	if err != nil || v != `{"2012-12-21 11:30:45.123456+00",NULL}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	var times []time.Time
+	if err := Array(&times).Scan(`{"2012-12-21 11:30:45.123456+00","0044-03-15 12:00:00-02 BC"}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 2 || !times[0].Equal(t1) || times[1].Year() != -43 {
+		t.Fatalf("unexpected %v", times)
+	}
+	if err := Array(&times).Scan(`{2001-02-03,"0044-03-15 BC"}`); err != nil {
+		t.Fatal(err)
+	}
+	if !times[0].Equal(time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)) || times[1].Year() != -43 {
+		t.Fatalf("unexpected %v", times)
+	}
+	if err := Array(&times).Scan(`{2001-02-03,NULL}`); err == nil {
+		t.Fatal("expected an error scanning NULL into a time.Time")
+	}
+	if err := Array(&times).Scan(`{infinity}`); err == nil {
+		t.Fatal("expected an error scanning infinity without EnableInfinityTs")
+	}
+
+	var nulls []sql.NullTime
+	if err := Array(&nulls).Scan(`{NULL,2001-02-03}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(nulls) != 2 || nulls[0].Valid || !nulls[1].Valid || nulls[1].Time.Day() != 3 {
+		t.Fatalf("unexpected %v", nulls)
+	}
+
+	var ptrs []*time.Time
+	if err := Array(&ptrs).Scan(`{NULL,"2012-12-21 11:30:45.123456"}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(ptrs) != 2 || ptrs[0] != nil || !ptrs[1].Equal(t1) {
+		t.Fatalf("unexpected %v", ptrs)
+	}
+
+	neg, pos := time.Date(-1000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+	EnableInfinityTs(neg, pos)
+	defer DisableInfinityTs()
+	if err := Array(&times).Scan(`{-infinity,infinity}`); err != nil || !times[0].Equal(neg) || !times[1].Equal(pos) {
+		t.Fatalf("unexpected %v, %v", times, err)
+	}
+	v, err = Array([]time.Time{neg, pos}).Value()
+	if err != nil || v != "{-infinity,infinity}" {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
+
+// mood is an enum-like element type with its own text form.
+type mood int
+
+func (m mood) Value() (driver.Value, error) {
+	return [...]string{"sad", "ok", "happy"}[m], nil
+}
+
+func (m *mood) Scan(src interface{}) error {
+	switch string(src.([]byte)) {
+	case "sad":
+		*m = 0
+	case "ok":
+		*m = 1
+	case "happy":
+		*m = 2
+	default:
+		return fmt.Errorf("invalid mood %s", src)
+	}
+	return nil
+}
+
+func TestArrayCustomElements(t *testing.T) {
+	u := UUID{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}
+	in := `{a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11}`
+
+	for _, a := range []interface{}{[]UUID{u}, [][16]byte{u}} {
+		v, err := Array(a).Value()
+		if err != nil || v != `{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"}` {
+			t.Fatalf("%T: unexpected %v, %v", a, v, err)
+		}
+	}
+	var uuids []UUID
+	if err := Array(&uuids).Scan(in); err != nil || len(uuids) != 1 || uuids[0] != u {
+		t.Fatalf("unexpected %v, %v", uuids, err)
+	}
+	var raw [][16]byte
+	if err := Array(&raw).Scan(in); err != nil || len(raw) != 1 || raw[0] != u {
+		t.Fatalf("unexpected %v, %v", raw, err)
+	}
+	if err := Array(&raw).Scan("{x}"); err == nil {
+		t.Fatal("expected an error for an invalid uuid")
+	}
+
+	v, err := Array([]mood{2, 0}).Value()
+	if err != nil || v != `{"happy","sad"}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	var moods []mood
+	if err := Array(&moods).Scan("{ok,happy}"); err != nil || !reflect.DeepEqual(moods, []mood{1, 2}) {
+		t.Fatalf("unexpected %v, %v", moods, err)
+	}
+	if err := Array(&moods).Scan("{meh}"); err == nil {
+		t.Fatal("expected the element's Scan error")
+	}
+}
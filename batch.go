@@ -0,0 +1,225 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+)
+
+// Batch is a list of queries to be sent to the server in one go.
+type Batch struct {
+	queries []batchQuery
+}
+
+type batchQuery struct {
+	q    string
+	args []driver.Value
+}
+
+// Queue adds q with its arguments to the batch.
+func (b *Batch) Queue(q string, args ...driver.Value) {
+	b.queries = append(b.queries, batchQuery{q, args})
+}
+
+// Len returns the number of queries queued.
+func (b *Batch) Len() int {
+	return len(b.queries)
+}
+
+// BatchResult is the outcome of one query of a batch. Its rows are
+// decoded as Query decodes them.
+type BatchResult struct {
+	Columns    []string
+	Rows       [][]driver.Value
+	CommandTag string
+
+	// Err is the error the query failed with. Once a query fails the
+	// server skips the rest of the batch, and those queries report
+	// ErrBatchSkipped.
+	Err error
+}
+
+// ErrBatchSkipped is the error of the queries of a batch that followed a
+// failed one.
+var ErrBatchSkipped = errf("query skipped after an earlier query in the batch failed")
+
+// SendBatch runs every query of b in order, writing all of their Parse,
+// Bind and Execute messages followed by a single Sync in one network write,
+// then reading all of the results. On high-latency links this costs one
+// round trip for the whole batch rather than one per query.
+//
+// The queries run in an implicit transaction unless one is already open:
+// if one fails, the effects of the others are rolled back. Reach it from
+// database/sql through sql.Conn.Raw.
+func (cn *Conn) SendBatch(b *Batch) (results []*BatchResult, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	if len(b.queries) > 0 {
+		// A failed transaction runs the rest of the batch only if its
+		// first query ends it.
+		cn.checkTx(b.queries[0].q)
+	}
+
+	queries := make([]batchQuery, len(b.queries))
+	for i, bq := range b.queries {
+		args, err := checkParams(bq.args)
+		if err != nil {
+			return nil, err
+		}
+		queries[i] = batchQuery{bq.q, args}
+	}
+
+	cn.sendBatch(func() {
+		for _, bq := range queries {
+			oids, args := paramTypes(bq.args)
+			cn.parse("", bq.q, oids)
+			st := &stmt{Conn: cn, q: bq.q, paramTyps: oids}
+			st.bind(args)
+
+			cn.setHead('D')
+			cn.write(byte('P'))
+			cn.write("")
+			cn.sendMsg()
+
+			cn.setHead('E')
+			cn.write("")
+			cn.write(int32(0))
+			cn.sendMsg()
+		}
+	})
+
+	return cn.recvBatch(len(b.queries)), nil
+}
+
+// ExecBatch runs q once for each set of arguments in args, as SendBatch
+// does for a batch of queries but parsing q only once: bulk inserts that
+// can't use COPY then cost a single round trip. The types of TypedParams
+// are taken from the first set of arguments.
+//
+// There is a result for each set of arguments. Once one fails the rest
+// report ErrBatchSkipped, and as with SendBatch the effects of the others
+// are rolled back unless a transaction is open.
+func (cn *Conn) ExecBatch(q string, args [][]driver.Value) (results []*BatchResult, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	checked := make([][]driver.Value, len(args))
+	for i, v := range args {
+		if checked[i], err = checkParams(v); err != nil {
+			return nil, err
+		}
+	}
+
+	cn.sendBatch(func() {
+		var st *stmt
+		for _, v := range checked {
+			oids, v := paramTypes(v)
+			if st == nil {
+				cn.parse("", q, oids)
+				st = &stmt{Conn: cn, q: q, paramTyps: oids}
+			}
+			st.bind(v)
+
+			// Describe each portal, as SendBatch does, for the rows of
+			// an INSERT ... RETURNING.
+			cn.setHead('D')
+			cn.write(byte('P'))
+			cn.write("")
+			cn.sendMsg()
+
+			cn.setHead('E')
+			cn.write("")
+			cn.write(int32(0))
+			cn.sendMsg()
+		}
+	})
+
+	return cn.recvBatch(len(args)), nil
+}
+
+// sendBatch runs send, which writes the messages of a batch up to but
+// excluding the final Sync, and sends its messages and the Sync in one
+// network write.
+func (cn *Conn) sendBatch(send func()) {
+	buf := new(bytes.Buffer)
+	cn.wbuf = buf
+	func() {
+		defer func() {
+			cn.wbuf = nil
+		}()
+		send()
+		cn.sync()
+	}()
+
+	_, err := buf.WriteTo(cn.c)
+	if err != nil {
+		panic(err)
+	}
+	cn.sent = true
+}
+
+// recvBatch reads the results of the n statements of a batch, and the
+// ReadyForQuery that ends it.
+func (cn *Conn) recvBatch(n int) []*BatchResult {
+	results := make([]*BatchResult, n)
+	failed := false
+	for i := range results {
+		if failed {
+			results[i] = &BatchResult{Err: ErrBatchSkipped}
+			continue
+		}
+		results[i] = cn.recvBatchResult()
+		if err := results[i].Err; err != nil {
+			if _, ok := err.(*Error); !ok {
+				panic(err)
+			}
+			failed = true
+		}
+	}
+
+	if !failed {
+		cn.recvMsg()
+		if cn.T != 'Z' {
+			panic(cn.unexpected("batch"))
+		}
+		cn.read(&cn.status)
+	}
+
+	return results
+}
+
+// recvBatchResult reads the responses to one query of a batch. After an
+// error the connection has been read up to ReadyForQuery.
+func (cn *Conn) recvBatchResult() (res *BatchResult) {
+	res = new(BatchResult)
+	defer recoverErr(&res.Err)
+
+	var fields []fieldDesc
+	for {
+		cn.recvMsg()
+		switch cn.T {
+		case '1', '2', 'n':
+			// Throw away messages we don't care about
+		case 'T':
+			fields = cn.readRowDescription()
+			res.Columns = columnNames(fields)
+		case 'D':
+			row := make([]driver.Value, len(fields))
+			cn.readDataRow(row, fields)
+			res.Rows = append(res.Rows, row)
+		case 'C':
+			res.CommandTag = cn.readCString()
+			return res
+		case 'I':
+			return res
+		default:
+			panic(cn.unexpected("batch"))
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestSendBatch(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		// SELECT $1
+		testMsg{'1', nil}, testMsg{'2', nil},
+		testMsg{'T', []interface{}{int16(1), "a", int32(0), int16(0), int32(25), int16(-1), int32(-1), int16(0)}},
+		testMsg{'D', []interface{}{int16(1), int32(1), []byte("x")}},
+		testMsg{'C', []interface{}{"SELECT 1"}},
+		// INSERT
+		testMsg{'1', nil}, testMsg{'2', nil}, testMsg{'n', nil},
+		testMsg{'C', []interface{}{"INSERT 0 1"}},
+		// failing query
+		testMsg{'1', nil},
+		testMsg{'E', []interface{}{byte('M'), "boom", byte(0)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	b := new(Batch)
+	b.Queue("SELECT $1", "x")
+	b.Queue("INSERT INTO t VALUES (1)")
+	b.Queue("SELECT boom")
+	b.Queue("SELECT 2")
+
+	results, err := cn.SendBatch(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if types := sentTypes(t, conn.w.Bytes()); types != "PBDEPBDEPBDEPBDES" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if results[0].Err != nil || len(results[0].Rows) != 1 || results[0].Rows[0][0] != "x" {
+		t.Fatalf("unexpected first result %+v", results[0])
+	}
+	if results[1].Err != nil || results[1].CommandTag != "INSERT 0 1" {
+		t.Fatalf("unexpected second result %+v", results[1])
+	}
+	if _, ok := results[2].Err.(*Error); !ok {
+		t.Fatalf("expected a server error, got %v", results[2].Err)
+	}
+	if results[3].Err != ErrBatchSkipped {
+		t.Fatalf("expected the last query to be skipped, got %v", results[3].Err)
+	}
+	if cn.status != 'I' {
+		t.Fatalf("expected to have read up to ReadyForQuery")
+	}
+}
+
+func TestExecBatch(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'1', nil},
+		testMsg{'2', nil}, testMsg{'n', nil}, testMsg{'C', []interface{}{"INSERT 0 1"}},
+		testMsg{'2', nil}, testMsg{'E', []interface{}{byte('M'), "duplicate key", byte(0)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	results, err := cn.ExecBatch("INSERT INTO t VALUES ($1)", [][]driver.Value{{int64(1)}, {int64(1)}, {int64(2)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if types := sentTypes(t, conn.w.Bytes()); types != "PBDEBDEBDES" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Err != nil || results[0].CommandTag != "INSERT 0 1" {
+		t.Fatalf("unexpected first result %+v", results[0])
+	}
+	if _, ok := results[1].Err.(*Error); !ok {
+		t.Fatalf("expected a server error, got %v", results[1].Err)
+	}
+	if results[2].Err != ErrBatchSkipped {
+		t.Fatalf("expected the last insert to be skipped, got %v", results[2].Err)
+	}
+	if cn.status != 'I' {
+		t.Fatalf("expected to have read up to ReadyForQuery")
+	}
+}
+
+func TestExecBatchReturning(t *testing.T) {
+	rowDesc := testMsg{'T', []interface{}{int16(1), "id", int32(0), int16(0), int32(OidInt8), int16(8), int32(-1), int16(0)}}
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'1', nil},
+		testMsg{'2', nil}, rowDesc,
+		testMsg{'D', []interface{}{int16(1), int32(1), []byte("1")}},
+		testMsg{'C', []interface{}{"INSERT 0 1"}},
+		testMsg{'2', nil}, rowDesc,
+		testMsg{'D', []interface{}{int16(1), int32(1), []byte("2")}},
+		testMsg{'C', []interface{}{"INSERT 0 1"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	results, err := cn.ExecBatch("INSERT INTO t (a) VALUES ($1) RETURNING id", [][]driver.Value{{"x"}, {"y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for i, res := range results {
+		if res.Err != nil || len(res.Rows) != 1 || res.Rows[0][0] != int64(i+1) {
+			t.Fatalf("unexpected result %d %+v", i, res)
+		}
+		if len(res.Columns) != 1 || res.Columns[0] != "id" {
+			t.Fatalf("unexpected columns %v", res.Columns)
+		}
+	}
+}
+
+func TestBatchUnsupportedParam(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(nil)}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	b := new(Batch)
+	b.Queue("SELECT $1", "x")
+	b.Queue("SELECT $1, $2", 1, struct{}{})
+	_, err := cn.SendBatch(b)
+	e, ok := err.(*ErrUnsupportedParameterType)
+	if !ok || e.Index != 2 {
+		t.Fatalf("expected an ErrUnsupportedParameterType for $2, got %v", err)
+	}
+	if conn.w.Len() != 0 {
+		t.Fatalf("expected nothing to be sent, sent %q", conn.w.Bytes())
+	}
+	if cn.bad {
+		t.Fatal("expected the connection to stay usable")
+	}
+}
+
+func TestBatchInFailedTx(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(nil)}
+	cn := &Conn{c: conn, msg: newMsg(), status: byte(TxFailed)}
+
+	b := new(Batch)
+	b.Queue("INSERT INTO t VALUES (1)")
+	if _, err := cn.SendBatch(b); err != errTxAborted {
+		t.Fatalf("expected errTxAborted, got %v", err)
+	}
+	if _, err := cn.ExecBatch("INSERT INTO t VALUES ($1)", [][]driver.Value{{int64(1)}}); err != errTxAborted {
+		t.Fatalf("expected errTxAborted, got %v", err)
+	}
+	if conn.w.Len() != 0 {
+		t.Fatal("expected nothing to be sent")
+	}
+}
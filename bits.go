@@ -0,0 +1,71 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Bits is a bit string of any length, the value of a bit(n) or bit
+// varying. bit and bit varying columns decode to their text, such as
+// "1011", which scans into a string; scan them into a Bits to get the
+// bits instead.
+type Bits struct {
+	// Bytes holds the bits, the first in the most significant bit of
+	// the first byte. The bits past Len in the last byte are zero.
+	Bytes []byte
+	Len   int
+}
+
+// Bit returns bit i, counting from 0 at the left.
+func (b Bits) Bit(i int) bool {
+	if i < 0 || i >= b.Len {
+		panic(fmt.Sprintf("pq: bit index %d out of range [0:%d]", i, b.Len))
+	}
+	return b.Bytes[i/8]&(0x80>>uint(i%8)) != 0
+}
+
+// String returns the bits as 0s and 1s, the text form of a bit string.
+func (b Bits) String() string {
+	s := make([]byte, b.Len)
+	for i := range s {
+		s[i] = '0'
+		if b.Bit(i) {
+			s[i] = '1'
+		}
+	}
+	return string(s)
+}
+
+// Scan implements sql.Scanner, accepting the text form of a bit string.
+func (b *Bits) Scan(src interface{}) error {
+	var s []byte
+	switch v := src.(type) {
+	case []byte:
+		s = v
+	case string:
+		s = []byte(v)
+	default:
+		return fmt.Errorf("pq: cannot scan %T into a Bits", src)
+	}
+
+	buf := make([]byte, (len(s)+7)/8)
+	for i, c := range s {
+		switch c {
+		case '1':
+			buf[i/8] |= 0x80 >> uint(i%8)
+		case '0':
+		default:
+			return fmt.Errorf("pq: invalid bit string %q", s)
+		}
+	}
+	b.Bytes, b.Len = buf, len(s)
+	return nil
+}
+
+// Value implements driver.Valuer, sending b in its text form.
+func (b Bits) Value() (driver.Value, error) {
+	if b.Len < 0 || b.Len > 8*len(b.Bytes) {
+		return nil, fmt.Errorf("pq: Bits of length %d with %d bytes", b.Len, len(b.Bytes))
+	}
+	return b.String(), nil
+}
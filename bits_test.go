@@ -0,0 +1,52 @@
+package pq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBits(t *testing.T) {
+	var b Bits
+	if err := b.Scan([]byte("1011000011")); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Bits{Bytes: []byte{0xb0, 0xc0}, Len: 10}); !reflect.DeepEqual(b, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, b)
+	}
+	if !b.Bit(0) || b.Bit(1) || !b.Bit(9) {
+		t.Fatalf("unexpected bits of %s", b)
+	}
+	if v, err := b.Value(); err != nil || v != "1011000011" {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	if err := b.Scan(""); err != nil || b.Len != 0 || b.String() != "" {
+		t.Fatalf("expected an empty bit string, got %#v, %v", b, err)
+	}
+	if err := b.Scan("102"); err == nil {
+		t.Fatal("expected an error for an invalid bit string")
+	}
+	if err := b.Scan(nil); err == nil {
+		t.Fatal("expected an error scanning NULL")
+	}
+	if _, err := (Bits{Bytes: []byte{1}, Len: 9}).Value(); err == nil {
+		t.Fatal("expected an error for a length past the bytes")
+	}
+
+	if v := decodeText(OidVarbit, []byte("101")); v != "101" {
+		t.Fatalf("expected varbit to decode to its text, got %#v", v)
+	}
+}
+
+func TestBitsArray(t *testing.T) {
+	var bits []Bits
+	if err := Array(&bits).Scan("{101,0}"); err != nil {
+		t.Fatal(err)
+	}
+	if len(bits) != 2 || bits[0].String() != "101" || bits[1].String() != "0" {
+		t.Fatalf("unexpected %v", bits)
+	}
+	if v, err := Array(bits).Value(); err != nil || v != `{"101","0"}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
@@ -0,0 +1,50 @@
+package pq
+
+import (
+	"io"
+	"net"
+)
+
+// cancelRequestCode starts a CancelRequest in place of a protocol version.
+const cancelRequestCode = 80877102
+
+var errNoCancel = errf("no server to send a CancelRequest to")
+
+// dialServer returns a function connecting again to the server at the
+// other end of c, for a CancelRequest.
+func dialServer(c net.Conn) func() (net.Conn, error) {
+	addr := c.RemoteAddr()
+	return func() (net.Conn, error) {
+		var d net.Dialer
+		return d.Dial(addr.Network(), addr.String())
+	}
+}
+
+// cancel asks the server, over a connection of its own, to cancel the
+// command cn is running, identifying it with the key from BackendKeyData.
+// The command fails with a query_canceled error unless it ended first. The
+// request is waited on until the server closes that connection, so that
+// it can't hit a later command instead.
+func (cn *Conn) cancel() (err error) {
+	defer recoverErr(&err)
+
+	if cn.dialCancel == nil {
+		return errNoCancel
+	}
+	c, err := cn.dialCancel()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	m := newMsg()
+	m.setHead(0)
+	m.write(int32(cancelRequestCode), cn.pid, cn.key)
+	m.writeTo(c)
+
+	// The server replies nothing: it closes the connection once done.
+	if _, err := c.Read(make([]byte, 1)); err != io.EOF {
+		return errf("unexpected reply to a CancelRequest: %v", err)
+	}
+	return nil
+}
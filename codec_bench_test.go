@@ -0,0 +1,175 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// The codec benchmarks measure encode and decode throughput against fixed
+// datasets, and TestCodecAllocBudget turns their allocation counts into a
+// budget so codec changes that regress them fail the test suite instead
+// of going unnoticed. Lower a budget when a change improves on it.
+
+// goldenParams are the parameter values encoded per type.
+var goldenParams = []struct {
+	name   string
+	v      driver.Value
+	allocs float64
+}{
+	{"int64", int64(-1234567890123), 2},
+	{"float64", float64(3.14159265358979), 2},
+	{"bool", true, 2},
+	{"string", "the quick brown fox jumps over the lazy dog", 2},
+	{"bytea", bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 64), 2},
+	{"timestamptz", time.Date(2012, 12, 21, 11, 30, 45, 123456000, time.UTC), 2},
+	{"null", nil, 0},
+}
+
+// goldenRows are the row shapes decoded, as the type and the text of each
+// column.
+var goldenRows = []struct {
+	name   string
+	typs   []Oid
+	row    [][]byte
+	allocs float64
+}{
+	{"narrow", []Oid{OidInt8}, [][]byte{[]byte("42")}, 6},
+	{"mixed", []Oid{OidInt8, OidFloat8, OidBool, OidTimestamptz, OidText, OidText}, [][]byte{
+		[]byte("42"),
+		[]byte("3.14159"),
+		[]byte("t"),
+		[]byte("2012-12-21 11:30:45.123456+00"),
+		nil,
+		[]byte("the quick brown fox jumps over the lazy dog"),
+	}, 24},
+	{"wide", wideTypes(50), bytes.Split(bytes.Repeat([]byte("1234567890,"), 49), []byte(",")), 200},
+}
+
+// wideTypes returns the types of a wide row of n columns: int8 columns,
+// ending with an empty text one.
+func wideTypes(n int) []Oid {
+	typs := make([]Oid, n)
+	for i := range typs {
+		typs[i] = OidInt8
+	}
+	typs[n-1] = OidText
+	return typs
+}
+
+// goldenFields returns the descriptions of columns of the types typs, in
+// the text format.
+func goldenFields(typs []Oid) []fieldDesc {
+	fields := make([]fieldDesc, len(typs))
+	for i, typ := range typs {
+		fields[i] = fieldDesc{name: "c", typ: typ, format: formatText}
+	}
+	return fields
+}
+
+// decodeAll reads every row of stream, returning the number read.
+func decodeAll(stream []byte, typs []Oid) (int, error) {
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(stream)}, msg: newMsg(), state: stateExtendedQuery}
+	r := &rows{Conn: cn, fields: goldenFields(typs)}
+	dest := make([]driver.Value, len(typs))
+	n := 0
+	for {
+		err := r.Next(dest)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+func BenchmarkEncodeParam(b *testing.B) {
+	for _, p := range goldenParams {
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				encodeParam(p.v)
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeRows(b *testing.B) {
+	const n = 1000
+	for _, shape := range goldenRows {
+		stream := dataRowStream(shape.row, n)
+		b.Run(shape.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(stream)))
+			for i := 0; i < b.N; i++ {
+				if _, err := decodeAll(stream, shape.typs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeGoldenRows(t *testing.T) {
+	// The columns are decoded for their types, not left as bytes.
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(dataRowStream(goldenRows[1].row, 1))}, msg: newMsg(), state: stateExtendedQuery}
+	r := &rows{Conn: cn, fields: goldenFields(goldenRows[1].typs)}
+	dest := make([]driver.Value, len(goldenRows[1].typs))
+	if err := r.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{"int64", "float64", "bool", "time.Time", "<nil>", "string"} {
+		if got := fmt.Sprintf("%T", dest[i]); got != expected {
+			t.Errorf("column %d: expected %s, got %s", i, expected, got)
+		}
+	}
+
+	for _, shape := range goldenRows {
+		n, err := decodeAll(dataRowStream(shape.row, 3), shape.typs)
+		if err != nil {
+			t.Fatalf("%s: %v", shape.name, err)
+		}
+		if n != 3 {
+			t.Fatalf("%s: expected 3 rows, got %d", shape.name, n)
+		}
+	}
+}
+
+func TestCodecAllocBudget(t *testing.T) {
+	if testing.Short() || raceEnabled {
+		t.Skip("allocation counts are only meaningful in a full, non-race run")
+	}
+
+	for _, p := range goldenParams {
+		allocs := testing.AllocsPerRun(100, func() {
+			encodeParam(p.v)
+		})
+		if allocs > p.allocs {
+			t.Errorf("encoding %s: %v allocs, budget is %v", p.name, allocs, p.allocs)
+		}
+	}
+
+	for _, shape := range goldenRows {
+		stream := dataRowStream(shape.row, 1)
+		cn := &Conn{msg: newMsg(), state: stateExtendedQuery}
+		r := &rows{Conn: cn, fields: goldenFields(shape.typs)}
+		dest := make([]driver.Value, len(shape.row))
+		conn := &replayConn{r: bytes.NewReader(stream)}
+		cn.c = conn
+		allocs := testing.AllocsPerRun(100, func() {
+			conn.r.Reset(stream)
+			r.done = false
+			if err := r.Next(dest); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs > shape.allocs {
+			t.Errorf("decoding %s row: %v allocs, budget is %v", shape.name, allocs, shape.allocs)
+		}
+	}
+}
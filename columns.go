@@ -0,0 +1,190 @@
+package pq
+
+import (
+	"math"
+	"net"
+	"reflect"
+	"time"
+)
+
+// typeNames holds the names of the built-in types, as
+// ColumnTypeDatabaseTypeName reports them.
+var typeNames = map[Oid]string{
+	OidBool:           "BOOL",
+	OidBytea:          "BYTEA",
+	OidChar:           "CHAR",
+	OidName:           "NAME",
+	OidInt8:           "INT8",
+	OidInt2:           "INT2",
+	OidInt4:           "INT4",
+	OidRegproc:        "REGPROC",
+	OidText:           "TEXT",
+	OidOid:            "OID",
+	OidJSON:           "JSON",
+	OidPoint:          "POINT",
+	OidLseg:           "LSEG",
+	OidPath:           "PATH",
+	OidBox:            "BOX",
+	OidPolygon:        "POLYGON",
+	OidLine:           "LINE",
+	OidCidr:           "CIDR",
+	OidFloat4:         "FLOAT4",
+	OidFloat8:         "FLOAT8",
+	OidUnknown:        "UNKNOWN",
+	OidCircle:         "CIRCLE",
+	OidMacaddr8:       "MACADDR8",
+	OidMacaddr:        "MACADDR",
+	OidInet:           "INET",
+	OidBpchar:         "BPCHAR",
+	OidVarchar:        "VARCHAR",
+	OidDate:           "DATE",
+	OidTime:           "TIME",
+	OidTimestamp:      "TIMESTAMP",
+	OidTimestamptz:    "TIMESTAMPTZ",
+	OidInterval:       "INTERVAL",
+	OidTimetz:         "TIMETZ",
+	OidBit:            "BIT",
+	OidVarbit:         "VARBIT",
+	OidNumeric:        "NUMERIC",
+	OidRegprocedure:   "REGPROCEDURE",
+	OidRegoper:        "REGOPER",
+	OidRegoperator:    "REGOPERATOR",
+	OidRegclass:       "REGCLASS",
+	OidRegtype:        "REGTYPE",
+	OidRecord:         "RECORD",
+	OidUUID:           "UUID",
+	OidPgLSN:          "PG_LSN",
+	OidTSVector:       "TSVECTOR",
+	OidTSQuery:        "TSQUERY",
+	OidRegconfig:      "REGCONFIG",
+	OidRegdictionary:  "REGDICTIONARY",
+	OidJSONB:          "JSONB",
+	OidInt4Range:      "INT4RANGE",
+	OidNumRange:       "NUMRANGE",
+	OidTsRange:        "TSRANGE",
+	OidTstzRange:      "TSTZRANGE",
+	OidDateRange:      "DATERANGE",
+	OidInt8Range:      "INT8RANGE",
+	OidRegnamespace:   "REGNAMESPACE",
+	OidRegrole:        "REGROLE",
+	OidRegcollation:   "REGCOLLATION",
+	OidInt4Multirange: "INT4MULTIRANGE",
+	OidNumMultirange:  "NUMMULTIRANGE",
+	OidTsMultirange:   "TSMULTIRANGE",
+	OidTstzMultirange: "TSTZMULTIRANGE",
+	OidDateMultirange: "DATEMULTIRANGE",
+	OidInt8Multirange: "INT8MULTIRANGE",
+}
+
+// ColumnTypeDatabaseTypeName implements
+// driver.RowsColumnTypeDatabaseTypeName. It returns the upper-case name of
+// built-in types, such as INT4 or TIMESTAMPTZ, and "" for others, whose
+// oids vary between databases.
+func (r *rows) ColumnTypeDatabaseTypeName(i int) string {
+	return typeNames[r.fields[i].typ]
+}
+
+var (
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	stringType    = reflect.TypeOf("")
+	bytesType     = reflect.TypeOf([]byte(nil))
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// ColumnTypeScanType implements driver.RowsColumnTypeScanType, returning
+// the Go type values of the column decode to. Infinite timestamps and
+// dates are strings unless EnableInfinityTs is on.
+func (r *rows) ColumnTypeScanType(i int) reflect.Type {
+	typ := r.fields[i].typ
+	if _, ok := r.enums[typ]; ok {
+		return stringType
+	}
+	if t, ok := r.composites[typ]; ok {
+		return t
+	}
+	if _, ok := r.types[typ]; ok {
+		return interfaceType
+	}
+
+	switch typ {
+	case OidBool:
+		return reflect.TypeOf(false)
+	case OidInt2, OidInt4, OidInt8:
+		return reflect.TypeOf(int64(0))
+	case OidFloat4, OidFloat8:
+		return reflect.TypeOf(float64(0))
+	case OidNumeric:
+		if numericCodec.Decode != nil {
+			return interfaceType
+		}
+		return stringType
+	case OidDate, OidTime, OidTimetz, OidTimestamp, OidTimestamptz:
+		return timeType
+	case OidInet, OidCidr:
+		// A net.IP, or a *net.IPNet for networks.
+		return interfaceType
+	case OidMacaddr, OidMacaddr8:
+		return reflect.TypeOf(net.HardwareAddr(nil))
+	case OidOid:
+		return reflect.TypeOf(Oid(0))
+	case OidPgLSN:
+		return reflect.TypeOf(LSN(0))
+	case OidRecord:
+		return reflect.TypeOf(Record(nil))
+	case OidText, OidVarchar, OidBpchar, OidName, OidInterval, OidUUID, OidBit, OidVarbit,
+		OidInt4Range, OidInt8Range, OidNumRange, OidTsRange, OidTstzRange, OidDateRange,
+		OidInt4Multirange, OidInt8Multirange, OidNumMultirange, OidTsMultirange, OidTstzMultirange, OidDateMultirange,
+		OidPoint, OidLine, OidLseg, OidBox, OidPath, OidPolygon, OidCircle, OidTSVector, OidTSQuery,
+		OidRegproc, OidRegprocedure, OidRegoper, OidRegoperator, OidRegclass, OidRegtype,
+		OidRegconfig, OidRegdictionary, OidRegnamespace, OidRegrole, OidRegcollation:
+		return stringType
+	}
+	// The raw bytes of types decodeText doesn't know.
+	return bytesType
+}
+
+// ColumnTypeNullable implements driver.RowsColumnTypeNullable. The server
+// doesn't say whether result columns may be NULL, so ok is always false.
+func (r *rows) ColumnTypeNullable(i int) (nullable, ok bool) {
+	return false, false
+}
+
+// ColumnTypePrecisionScale implements
+// driver.RowsColumnTypePrecisionScale for numeric(p, s) columns. Numeric
+// columns declared without a precision have none to report.
+func (r *rows) ColumnTypePrecisionScale(i int) (precision, scale int64, ok bool) {
+	f := r.fields[i]
+	if f.typ != OidNumeric || f.typmod < 4 {
+		return 0, 0, false
+	}
+	// The modifier counts the 4 bytes of the varlena header, then holds
+	// the precision in its upper 16 bits and the scale in the lower 11,
+	// which since PostgreSQL 15 may be negative.
+	mod := f.typmod - 4
+	precision = int64(mod >> 16 & 0xffff)
+	scale = int64((mod&0x7ff)^1024) - 1024
+	return precision, scale, true
+}
+
+// ColumnTypeLength implements driver.RowsColumnTypeLength: the declared
+// length of varchar(n), char(n), bit(n) and varbit(n) columns, and
+// math.MaxInt64 for unbounded text and bytea ones.
+func (r *rows) ColumnTypeLength(i int) (length int64, ok bool) {
+	f := r.fields[i]
+	switch f.typ {
+	case OidText, OidBytea:
+		return math.MaxInt64, true
+	case OidVarchar, OidBpchar:
+		if f.typmod == -1 {
+			return math.MaxInt64, true
+		}
+		// The modifier counts the 4 bytes of the varlena header.
+		return int64(f.typmod) - 4, true
+	case OidBit, OidVarbit:
+		if f.typmod == -1 {
+			return math.MaxInt64, true
+		}
+		return int64(f.typmod), true
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var (
+	_ driver.RowsColumnTypeDatabaseTypeName = &rows{}
+	_ driver.RowsColumnTypeScanType         = &rows{}
+	_ driver.RowsColumnTypeNullable         = &rows{}
+	_ driver.RowsColumnTypeLength           = &rows{}
+	_ driver.RowsColumnTypePrecisionScale   = &rows{}
+)
+
+func TestColumnTypes(t *testing.T) {
+	r := &rows{Conn: &Conn{}, fields: []fieldDesc{
+		{name: "i", typ: OidInt4, typmod: -1},
+		{name: "v", typ: OidVarchar, typmod: 14},
+		{name: "t", typ: OidText, typmod: -1},
+		{name: "ts", typ: OidTimestamptz, typmod: -1},
+		{name: "b", typ: OidVarbit, typmod: 12},
+		{name: "j", typ: OidJSONB, typmod: -1},
+		{name: "x", typ: 16400, typmod: -1},
+		{name: "u", typ: OidUUID, typmod: -1},
+	}}
+
+	tests := []struct {
+		name     string
+		scanType reflect.Type
+		length   int64
+		hasLen   bool
+	}{
+		{"INT4", reflect.TypeOf(int64(0)), 0, false},
+		{"VARCHAR", reflect.TypeOf(""), 10, true},
+		{"TEXT", reflect.TypeOf(""), math.MaxInt64, true},
+		{"TIMESTAMPTZ", reflect.TypeOf(time.Time{}), 0, false},
+		{"VARBIT", reflect.TypeOf(""), 12, true},
+		{"JSONB", reflect.TypeOf([]byte(nil)), 0, false},
+		{"", reflect.TypeOf([]byte(nil)), 0, false},
+		{"UUID", reflect.TypeOf(""), 0, false},
+	}
+	for i, test := range tests {
+		if name := r.ColumnTypeDatabaseTypeName(i); name != test.name {
+			t.Errorf("%d: expected name %q, got %q", i, test.name, name)
+		}
+		if st := r.ColumnTypeScanType(i); st != test.scanType {
+			t.Errorf("%d: expected scan type %v, got %v", i, test.scanType, st)
+		}
+		if l, ok := r.ColumnTypeLength(i); l != test.length || ok != test.hasLen {
+			t.Errorf("%d: expected length %d, %v, got %d, %v", i, test.length, test.hasLen, l, ok)
+		}
+		if _, ok := r.ColumnTypeNullable(i); ok {
+			t.Errorf("%d: expected nullability to be unknown", i)
+		}
+	}
+
+	r.enums = map[Oid]bool{16400: false}
+	if st := r.ColumnTypeScanType(6); st != reflect.TypeOf("") {
+		t.Errorf("expected an enum to scan as a string, got %v", st)
+	}
+}
+
+func TestColumnTypePrecisionScale(t *testing.T) {
+	r := &rows{Conn: &Conn{}, fields: []fieldDesc{
+		{typ: OidNumeric, typmod: 10<<16 | 2 + 4},
+		{typ: OidNumeric, typmod: 3<<16 | 0x7fe + 4},
+		{typ: OidNumeric, typmod: -1},
+		{typ: OidInt4, typmod: -1},
+	}}
+
+	tests := []struct {
+		precision, scale int64
+		ok               bool
+	}{
+		{10, 2, true},
+		{3, -2, true},
+		{0, 0, false},
+		{0, 0, false},
+	}
+	for i, test := range tests {
+		p, s, ok := r.ColumnTypePrecisionScale(i)
+		if p != test.precision || s != test.scale || ok != test.ok {
+			t.Errorf("%d: expected %d, %d, %v, got %d, %d, %v", i, test.precision, test.scale, test.ok, p, s, ok)
+		}
+	}
+}
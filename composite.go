@@ -0,0 +1,251 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+)
+
+// Record is a composite value taken field by field, for composite types
+// no Go struct is registered for. Scanned fields are strings, or nil for
+// NULL; a Record sent as a parameter may hold any values an array can.
+// Anonymous composites, such as the result of SELECT (1, 'a'), decode to a
+// Record.
+type Record []interface{}
+
+// Scan implements sql.Scanner, accepting the text form of a composite.
+func (r *Record) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case Record:
+		*r = v
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errf("cannot scan %T into a Record", src)
+	}
+
+	*r = decodeRecord(b)
+	return nil
+}
+
+// Value implements driver.Valuer, formatting r as a composite literal. A
+// nil Record is NULL.
+func (r Record) Value() (v driver.Value, err error) {
+	defer recoverErr(&err)
+
+	if r == nil {
+		return nil, nil
+	}
+	b := []byte{'('}
+	for i := range r {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendCompositeField(b, reflect.ValueOf(r).Index(i))
+	}
+	return string(append(b, ')')), nil
+}
+
+// composites maps the Go struct types registered with RegisterComposite to
+// the names of their composite types.
+var composites = make(map[reflect.Type]string)
+
+// RegisterComposite maps the composite type name to the struct type of v,
+// whose exported fields are taken in order as the fields of the composite,
+// except those tagged pq:"-". Values of the struct type are then sent as
+// composites, and connections decode columns of the composite type into
+// them, so they scan into the struct:
+//
+//	type Point struct{ X, Y float64 }
+//
+//	pq.RegisterComposite("point3", Point{})
+//	...
+//	var p Point
+//	row.Scan(&p)
+//
+// Connections look up the types registered when they are opened, in one
+// extra query; a name that doesn't exist in the database is skipped. It
+// isn't safe to call concurrently with connecting: call it from an init
+// function.
+func RegisterComposite(name string, v interface{}) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(errf("RegisterComposite: %T is not a struct", v))
+	}
+	composites[t] = name
+}
+
+// loadComposites looks up the oids of the registered composite types.
+func (cn *Conn) loadComposites() (err error) {
+	defer recoverErr(&err)
+
+	var types []reflect.Type
+	var cols []string
+	for t, name := range composites {
+		types = append(types, t)
+		cols = append(cols, "to_regtype("+quoteLiteral(name)+")::oid")
+	}
+	r := cn.simpleQuery("SELECT " + strings.Join(cols, ", "))
+	defer r.Close()
+
+	row := make([]driver.Value, len(cols))
+	if err := r.Next(row); err != nil {
+		return err
+	}
+	cn.composites = make(map[Oid]reflect.Type)
+	for i, v := range row {
+		if v != nil {
+			cn.composites[v.(Oid)] = types[i]
+		}
+	}
+	return nil
+}
+
+// decodeRecord decodes the text of a composite into a Record.
+func decodeRecord(b []byte) Record {
+	fields := parseComposite(b)
+	r := make(Record, len(fields))
+	for i, f := range fields {
+		if f != nil {
+			r[i] = string(f)
+		}
+	}
+	return r
+}
+
+// decodeComposite decodes the text of a composite into a new value of the
+// struct type t.
+func decodeComposite(t reflect.Type, b []byte) driver.Value {
+	v := reflect.New(t).Elem()
+	scanComposite(v, b)
+	return v.Interface()
+}
+
+// compositeParam returns the composite literal for v if it is of a
+// registered struct type.
+func compositeParam(v interface{}) (string, bool) {
+	if _, ok := composites[reflect.TypeOf(v)]; !ok {
+		return "", false
+	}
+	return string(appendComposite(nil, reflect.ValueOf(v))), true
+}
+
+// compositeFields returns the indexes of the fields of the struct type t
+// that map to composite fields.
+func compositeFields(t reflect.Type) []int {
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("pq") == "-" {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+// appendComposite appends the struct v to b as a composite literal.
+func appendComposite(b []byte, v reflect.Value) []byte {
+	b = append(b, '(')
+	for i, f := range compositeFields(v.Type()) {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendCompositeField(b, v.Field(f))
+	}
+	return append(b, ')')
+}
+
+// appendCompositeField appends v to b as a field of a composite literal.
+// A field takes the form of an array element, but NULL, which a nil
+// slice is too, is an empty field, and anything not quoted is quoted,
+// which arrays need.
+func appendCompositeField(b []byte, v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return b
+	}
+	e := appendArrayElem(nil, v)
+	switch {
+	case string(e) == "NULL":
+		return b
+	case e[0] == '"':
+		return append(b, e...)
+	}
+	return appendArrayQuoted(b, string(e))
+}
+
+// scanComposite sets the fields of the struct v from the text of a
+// composite.
+func scanComposite(v reflect.Value, b []byte) {
+	fields := parseComposite(b)
+	idx := compositeFields(v.Type())
+	if len(fields) != len(idx) {
+		panic(errf("cannot scan a composite of %d fields into %s, which maps %d", len(fields), v.Type(), len(idx)))
+	}
+	for i, f := range fields {
+		scanArrayElem(v.Field(idx[i]), f)
+	}
+}
+
+// parseComposite splits the text form of a composite into its fields,
+// undoing their quoting. NULL fields, which are empty, are nil, while
+// empty strings, which are quoted, are not.
+func parseComposite(b []byte) [][]byte {
+	if len(b) < 2 || b[0] != '(' || b[len(b)-1] != ')' {
+		panic(errf("invalid composite %q", b))
+	}
+	return splitFields(b[1:len(b)-1], "composite")
+}
+
+// splitFields splits the comma-separated fields of a composite or range
+// between its delimiters.
+func splitFields(s []byte, what string) [][]byte {
+	var fields [][]byte
+	for i := 0; ; i++ {
+		var f []byte
+		for i < len(s) && s[i] != ',' {
+			if f == nil {
+				f = []byte{}
+			}
+			switch s[i] {
+			case '"':
+				for i++; ; i++ {
+					if i == len(s) {
+						panic(errf("invalid %s %q: unterminated string", what, s))
+					}
+					if s[i] == '"' {
+						if i+1 < len(s) && s[i+1] == '"' {
+							i++
+						} else {
+							break
+						}
+					} else if s[i] == '\\' && i+1 < len(s) {
+						i++
+					}
+					f = append(f, s[i])
+				}
+			case '\\':
+				if i+1 < len(s) {
+					i++
+				}
+				f = append(f, s[i])
+			default:
+				f = append(f, s[i])
+			}
+			i++
+		}
+		fields = append(fields, f)
+		if i >= len(s) {
+			return fields
+		}
+	}
+}
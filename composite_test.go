@@ -0,0 +1,204 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseComposite(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected [][]byte
+	}{
+		{`(1,abc)`, [][]byte{[]byte("1"), []byte("abc")}},
+		{`(,"")`, [][]byte{nil, {}}},
+		{`("a ""b"" c","x,y",\\)`, [][]byte{[]byte(`a "b" c`), []byte("x,y"), []byte(`\`)}},
+		{`("\\""")`, [][]byte{[]byte(`\"`)}},
+		{`("{1,2}",)`, [][]byte{[]byte("{1,2}"), nil}},
+		{`()`, [][]byte{nil}},
+	}
+	for _, tt := range tests {
+		f := parseComposite([]byte(tt.in))
+		if !reflect.DeepEqual(f, tt.expected) {
+			t.Errorf("%s: expected %q, got %q", tt.in, tt.expected, f)
+		}
+	}
+
+	for _, in := range []string{"", "(", "1,2", `("a)`} {
+		var err error
+		func() {
+			defer recoverErr(&err)
+			parseComposite([]byte(in))
+		}()
+		if err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	v, err := Record{int64(1), "a \"b\"", nil, []int{1, 2}, "", true}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `("1","a \"b\"",,"{1,2}","","t")`; v != expected {
+		t.Fatalf("expected %s, got %s", expected, v)
+	}
+
+	var r Record
+	if err := r.Scan([]byte(v.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Record{"1", `a "b"`, nil, "{1,2}", "", "t"}); !reflect.DeepEqual(r, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, r)
+	}
+	if err := r.Scan(nil); err != nil || r != nil {
+		t.Fatalf("expected NULL to scan as nil, got %v, %v", r, err)
+	}
+}
+
+func TestDecodeRecord(t *testing.T) {
+	v := decodeText(OidRecord, []byte(`(1,"a,b",,"(2,x)")`))
+	if expected := (Record{"1", "a,b", nil, "(2,x)"}); !reflect.DeepEqual(v, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, v)
+	}
+
+	var r Record
+	if err := r.Scan(v); err != nil || !reflect.DeepEqual(r, v) {
+		t.Fatalf("unexpected %#v, %v", r, err)
+	}
+}
+
+type testItem struct {
+	Name     string
+	Price    *float64
+	Tags     []string
+	internal int
+	Skipped  int `pq:"-"`
+	Added    time.Time
+}
+
+type testOrder struct {
+	ID    int64
+	Items []testItem
+}
+
+func TestRegisterComposite(t *testing.T) {
+	RegisterComposite("item", testItem{})
+	defer delete(composites, reflect.TypeOf(testItem{}))
+
+	price := 9.5
+	added := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := testItem{Name: `a "b"`, Price: &price, Tags: []string{"x", "y z"}, Added: added}
+	v, err := checkParam(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `("a \"b\"","9.5","{\"x\",\"y z\"}","2020-01-02 03:04:05.000000+00")`
+	if v != expected {
+		t.Fatalf("expected %s, got %v", expected, v)
+	}
+
+	cn := &Conn{composites: map[Oid]reflect.Type{16400: reflect.TypeOf(testItem{})}}
+	d := cn.decode(&fieldDesc{typ: 16400}, []byte(`("a ""b""",,"{x,""y z""}","2020-01-02 03:04:05+00")`))
+	got, ok := d.(testItem)
+	if !ok {
+		t.Fatalf("expected a testItem, got %#v", d)
+	}
+	if got.Name != `a "b"` || got.Price != nil || !reflect.DeepEqual(got.Tags, []string{"x", "y z"}) || !got.Added.Equal(added) {
+		t.Fatalf("unexpected %#v", got)
+	}
+
+	// Composites nest in arrays nested in composites, each level quoting
+	// the one inside.
+	orders := []testOrder{{ID: 1, Items: []testItem{item, {Name: "c"}}}, {ID: 2}}
+	v, err = Array(orders).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotOrders []testOrder
+	if err := Array(&gotOrders).Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if len(gotOrders) != 2 || len(gotOrders[0].Items) != 2 || gotOrders[0].Items[0].Name != item.Name ||
+		*gotOrders[0].Items[0].Price != price || gotOrders[0].Items[0].Tags[1] != "y z" || gotOrders[1].ID != 2 {
+		t.Fatalf("unexpected %#v from %s", gotOrders, v)
+	}
+
+	if _, err := checkParam(struct{ A int }{1}); err == nil {
+		t.Fatal("expected an error for an unregistered struct")
+	}
+}
+
+func TestCompositeArray(t *testing.T) {
+	items := []testItem{{Name: "a"}, {Name: "b,c"}}
+	v, err := Array(items).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"(\"a\",,,\"0001-01-01 00:00:00.000000+00\")","(\"b,c\",,,\"0001-01-01 00:00:00.000000+00\")"}`; v != expected {
+		t.Fatalf("expected %s, got %s", expected, v)
+	}
+
+	var got []testItem
+	if err := Array(&got).Scan(`{"(a,1.5,{t},)","(\"b,c\",,,)"}`); err == nil {
+		t.Fatal("expected an error scanning a NULL time")
+	}
+	type named struct {
+		Name  string
+		Price *float64
+	}
+	var pairs []named
+	if err := Array(&pairs).Scan(`{"(a,1.5)","(\"b,c\",)"}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 || pairs[0].Name != "a" || *pairs[0].Price != 1.5 || pairs[1].Name != "b,c" || pairs[1].Price != nil {
+		t.Fatalf("unexpected %#v", pairs)
+	}
+	if err := Array(&pairs).Scan(`{"(a)"}`); err == nil {
+		t.Fatal("expected an error for a field count mismatch")
+	}
+}
+
+func TestLoadComposites(t *testing.T) {
+	RegisterComposite("item", testItem{})
+	defer delete(composites, reflect.TypeOf(testItem{}))
+
+	var buf bytes.Buffer
+	m := newMsg()
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		{'T', []interface{}{int16(1), "to_regtype", int32(0), int16(0), int32(OidOid), int16(4), int32(-1), int16(0)}},
+		{'D', []interface{}{int16(1), int32(5), []byte("16400")}},
+		{'C', []interface{}{"SELECT 1"}},
+		{'Z', []interface{}{byte('I')}},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	conn := &replayConn{r: bytes.NewReader(buf.Bytes())}
+	cn := &Conn{c: conn, msg: newMsg()}
+	if err := cn.loadComposites(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(conn.w.String(), "to_regtype('item')") {
+		t.Fatalf("expected the query to look up item, sent %q", conn.w.String())
+	}
+	if cn.composites[16400] != reflect.TypeOf(testItem{}) {
+		t.Fatalf("unexpected composites %v", cn.composites)
+	}
+	if _, ok := cn.decode(&fieldDesc{typ: 16400}, []byte(`(a,,,2020-01-02)`)).(testItem); !ok {
+		t.Fatal("expected the column to decode into a testItem")
+	}
+}
+
+var _ driver.Valuer = Record(nil)
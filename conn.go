@@ -2,18 +2,24 @@ package pq
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"crypto/tls"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/url"
+	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,8 +27,6 @@ var (
 	ErrSSLNotSupported = errors.New("SSL is not enabled on the server")
 )
 
-const timeFormat = "2006-01-02 15:04:05.000000-07"
-
 type h struct {
 	T int8
 	L int32
@@ -31,6 +35,9 @@ type h struct {
 type msg struct {
 	*h
 	b *bytes.Buffer
+
+	// discardRows makes readFrom skip the bodies of DataRow messages.
+	discardRows bool
 }
 
 func newMsg() *msg {
@@ -103,11 +110,23 @@ func (m *msg) writeTo(w io.Writer) {
 }
 
 func (m *msg) readFrom(r io.Reader) {
+	m.b.Reset()
+
 	err := binary.Read(r, binary.BigEndian, m.h)
 	if err != nil {
 		panic(err)
 	}
 
+	if (m.T == 'A' && m.L-4 > maxNotificationLen) || (m.T == 'D' && m.discardRows) {
+		// Skip the body of a DataRow being discarded, or of an
+		// oversized notification, which readNotification drops.
+		_, err = io.CopyN(ioutil.Discard, r, int64(m.L-4))
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	_, err = io.CopyN(m.b, r, int64(m.L-4))
 	if err != nil {
 		panic(err)
@@ -131,23 +150,114 @@ func (*pgdriver) Open(name string) (driver.Conn, error) {
 	return Open(name)
 }
 
+func (*pgdriver) OpenConnector(name string) (driver.Connector, error) {
+	return NewConnector(name)
+}
+
 func init() {
 	sql.Register("postgres", &pgdriver{})
 }
 
-type stateFn func(cn *Conn) stateFn
-
 type Conn struct {
 	c net.Conn
 	*msg
-	cid    int32
-	pid    int32
 	status byte
+
+	// pid is the process ID of the backend serving the connection, and
+	// key the secret key that goes with it, from BackendKeyData.
+	pid int32
+	key int32
+
+	// state is the stage of the exchange with the server.
+	state protoState
+
+	// connector is the Connector this connection was opened through, if
+	// any.
+	connector *Connector
+
+	// primary is set when the connection is to the first host listed,
+	// the one Drain is about.
+	primary bool
+
+	// dialCancel connects to the server for a CancelRequest, or is nil if
+	// the server can't be reached again.
+	dialCancel func() (net.Conn, error)
+
+	// fetchSize is the number of rows Query fetches per Execute, or 0 for
+	// all of them at once.
+	fetchSize int32
+
+	// binaryResults asks for the types decodeBinary knows in binary.
+	binaryResults bool
+
+	// sessionReset is the session_reset option: what ResetSession clears
+	// besides checking the connection is idle.
+	sessionReset string
+
+	// unsynced is set while a portal is being fetched from and the Sync
+	// ending the exchange has not been sent yet.
+	unsynced bool
+
+	// stmts caches named statements by query text for Query and Exec.
+	stmts *stmtCache
+
+	// nstmt numbers the named statements prepared on this connection.
+	nstmt int
+
+	// ncursor numbers the cursors opened with OpenCursor.
+	ncursor int
+
+	// trace times the query in progress when a tracer is installed.
+	trace *QueryTrace
+
+	// bad is set once an error has left the connection unusable.
+	bad bool
+
+	// inUse is set while a call is running on the connection.
+	inUse int32
+
+	// sent is set once the current call has written to the server.
+	sent bool
+
+	// params holds the run-time parameters reported by the server.
+	params map[string]string
+
+	// loc is the location of the session's TimeZone, if Go knows it.
+	loc *time.Location
+
+	// dateLoc is the location of the midnights dates are decoded to,
+	// UTC if nil.
+	dateLoc *time.Location
+
+	// composites maps the oids of the composite types registered with
+	// RegisterComposite to their Go types.
+	composites map[Oid]reflect.Type
+
+	// enums holds the enum types registered with the enum_types option,
+	// and the arrays of them, for which it is true.
+	enums map[Oid]bool
+
+	// types maps the oids of the types registered with RegisterType and
+	// RegisterTypeOid to their codecs.
+	types map[Oid]TypeCodec
+
+	// noticeHandler receives the notices the server sends, in place of
+	// the handler of the Connector.
+	noticeHandler func(*Error)
+
+	// notificationHandler receives the notifications the server sends,
+	// in place of the handler of the Connector.
+	notificationHandler func(*Notification)
+
+	// wbuf, when set, collects outgoing messages to be written at once.
+	wbuf *bytes.Buffer
+
+	// preferSimple sends every query through the simple protocol,
+	// interpolating any arguments client-side.
+	preferSimple bool
 }
 
 func Open(name string) (cn *Conn, err error) {
-	defer recoverErr(&err)
-
 	// TODO: less naive parsing.
 	// See: http://www.postgresql.org/docs/7.4/static/libpq.html#LIBPQ-CONNECT
 	o, err := parseConnString(name)
@@ -155,15 +265,82 @@ func Open(name string) (cn *Conn, err error) {
 		return nil, err
 	}
 
-	c, err := dial(o)
+	return open(context.Background(), o, nil)
+}
+
+func open(ctx context.Context, o Values, cr *Connector) (cn *Conn, err error) {
+	defer recoverErr(&err)
+
+	cn = &Conn{msg: newMsg(), connector: cr, params: make(map[string]string)}
+	cn.preferSimple = o.Get("prefer_simple_protocol") == "true"
+	cn.binaryResults = o.Get("binary_results") == "true"
+	if v := o.Get("statement_cache_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 0 {
+			return nil, errf("invalid statement_cache_size %q", v)
+		}
+		if size > 0 {
+			cn.stmts = newStmtCache(size)
+		}
+	}
+
+	if v := o.Get("fetch_size"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return nil, errf("invalid fetch_size %q", v)
+		}
+		cn.fetchSize = int32(n)
+	}
+
+	if v := o.Get("date_location"); v != "" {
+		cn.dateLoc, err = time.LoadLocation(v)
+		if err != nil {
+			return nil, errf("invalid date_location %q", v)
+		}
+	}
+
+	switch v := o.Get("session_reset"); v {
+	case "", "none":
+	case "unlisten", "discard":
+		cn.sessionReset = v
+	default:
+		return nil, errf(`unsupported session_reset %q; only "none" (default), "unlisten", and "discard" supported`, v)
+	}
+
+	cn.c, cn.primary, err = dial(ctx, o, cr != nil && cr.draining())
 	if err != nil {
 		return nil, err
 	}
+	cn.dialCancel = dialServer(cn.c)
 
-	cn = &Conn{c: c, msg: newMsg()}
 	cn.ssl(o)
 	cn.startup(o)
 
+	if len(composites) > 0 {
+		if err := cn.loadComposites(); err != nil {
+			cn.c.Close()
+			return nil, err
+		}
+	}
+	if len(registeredTypes) > 0 {
+		if err := cn.loadTypes(); err != nil {
+			cn.c.Close()
+			return nil, err
+		}
+	}
+	if v := o.Get("enum_types"); v != "" {
+		if err := cn.loadEnums(v); err != nil {
+			cn.c.Close()
+			return nil, err
+		}
+	}
+	if q := cn.listenQuery(); q != "" {
+		if _, err := cn.Exec(q, nil); err != nil {
+			cn.c.Close()
+			return nil, err
+		}
+	}
+
 	return
 }
 
@@ -197,11 +374,25 @@ func (cn *Conn) ssl(o Values) {
 	cn.c = tls.Client(cn.c, &tlsConf)
 }
 
+// sessionParams are the connection options passed on to the server in the
+// startup message, setting the run-time parameter of the same name for the
+// session.
+var sessionParams = []string{
+	"statement_timeout",
+	"lock_timeout",
+	"idle_in_transaction_session_timeout",
+}
+
 func (cn *Conn) startup(o Values) {
 	cn.setHead(0)
 	cn.write(int32(196608))
 	cn.write("user", o.Get("user"))
 	cn.write("database", o.Get("dbname"))
+	for _, k := range sessionParams {
+		if v := o.Get(k); v != "" {
+			cn.write(k, v)
+		}
+	}
 	cn.write("")
 	cn.sendMsg()
 
@@ -210,22 +401,16 @@ func (cn *Conn) startup(o Values) {
 		switch cn.T {
 		case 'R':
 			cn.auth(o)
-		case 'S':
-			// Ignore these for now
-			cn.readCString()
-			cn.readCString()
 		case 'K':
-			cn.read(&cn.cid)
 			cn.read(&cn.pid)
+			cn.read(&cn.key)
 		case 'Z':
 			cn.read(&cn.status)
 			return
 		default:
-			panic(errf("unknown response for startup: '%c'", cn.T))
+			panic(cn.unexpected("startup"))
 		}
 	}
-
-	return
 }
 
 func (cn *Conn) auth(o Values) {
@@ -245,7 +430,7 @@ func (cn *Conn) auth(o Values) {
 
 		cn.recvMsg()
 		if cn.T != 'R' {
-			panic(errf("unknown response for password message: '%c'", cn.T))
+			panic(cn.unexpected("password message"))
 		}
 
 		cn.read(&code)
@@ -254,7 +439,7 @@ func (cn *Conn) auth(o Values) {
 		}
 	}
 
-	panic(errf("unknown response for authentication: '%d'", code))
+	panic(errf("unsupported authentication request %d", code))
 }
 
 func md5s(s string) string {
@@ -267,233 +452,929 @@ func (cn *Conn) Close() error {
 	return cn.c.Close()
 }
 
-func (cn *Conn) Rollback() (err error) {
-	s, err := cn.Prepare("ROLLBACK")
-	if err != nil {
-		return err
+// IsValid implements driver.Validator, letting database/sql skip
+// connections already known to be broken, or to the primary host while
+// their Connector drains it, without a round trip.
+func (cn *Conn) IsValid() bool {
+	if cn.bad {
+		return false
 	}
-	defer s.Close()
+	return !cn.primary || cn.connector == nil || !cn.connector.draining()
+}
 
-	_, err = s.Query(nil)
-	if err != nil {
-		return err
+// Ping implements driver.Pinger with an empty query, a full round trip
+// through the server that touches no data. A connection that fails it,
+// or misses ctx's deadline, is reported as driver.ErrBadConn.
+func (cn *Conn) Ping(ctx context.Context) (err error) {
+	defer func() {
+		// An empty query is safe to send again.
+		if err != nil && cn.bad {
+			err = driver.ErrBadConn
+		}
+	}()
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+
+	if d, ok := ctx.Deadline(); ok {
+		cn.c.SetDeadline(d)
+		defer cn.c.SetDeadline(time.Time{})
 	}
-	return
-}
 
-func (cn *Conn) Commit() (err error) {
-	s, err := cn.Prepare("COMMIT")
-	if err != nil {
-		return err
+	cn.setHead('Q')
+	cn.write("")
+	cn.sendMsg()
+
+	cn.recvMsg()
+	if cn.T != 'I' {
+		panic(cn.unexpected("ping"))
 	}
-	defer s.Close()
 
-	_, err = s.Query(nil)
-	if err != nil {
-		return err
+	cn.recvMsg()
+	if cn.T != 'Z' {
+		panic(cn.unexpected("ping"))
 	}
-	return
+	cn.read(&cn.status)
+
+	return nil
 }
 
-func (cn *Conn) Begin() (tx driver.Tx, err error) {
-	// TODO: maybe cache stmt to avoid repreparing?
-	s, err := cn.Prepare("BEGIN")
-	if err != nil {
+// Rollback, Commit and Begin go through the simple query protocol, which
+// reads the result through to ReadyForQuery and leaves no statement behind.
+func (cn *Conn) Rollback() error {
+	_, err := cn.Exec("ROLLBACK", nil)
+	return err
+}
+
+func (cn *Conn) Commit() error {
+	_, err := cn.Exec("COMMIT", nil)
+	return err
+}
+
+func (cn *Conn) Begin() (driver.Tx, error) {
+	if _, err := cn.Exec("BEGIN", nil); err != nil {
 		return nil, err
 	}
-	defer s.Close()
+	return cn, nil
+}
 
-	_, err = s.Query(nil)
-	if err != nil {
-		return nil, err
+func (cn *Conn) Prepare(q string) (st driver.Stmt, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	if isCopy(q) {
+		if !isCopyFromStdin(q) {
+			return nil, errf("cannot prepare %q: only COPY FROM STDIN can be prepared; use CopyOut or CopyTo for COPY TO STDOUT, and Exec for other COPYs", q)
+		}
+		return cn.prepareCopyIn(q), nil
 	}
+	return cn.prepare(q), nil
+}
 
-	return cn, err
+func (cn *Conn) prepare(q string) *stmt {
+	cn.nstmt++
+	name := fmt.Sprintf("pq_%d", cn.nstmt)
+
+	cn.parse(name, q, nil)
+	cn.describe(name)
+
+	cn.setHead('S')
+	cn.sendMsg()
+
+	cn.recvParseComplete()
+	s := &stmt{Conn: cn, name: name, q: q}
+	s.recvDescribe()
+	s.formats = make([]int16, len(s.fields))
+	for i, f := range s.fields {
+		s.formats[i] = cn.resultFormat(f.typ)
+	}
+
+	cn.recvMsg()
+	if cn.T != 'Z' {
+		panic(cn.unexpected("parse"))
+	}
+	cn.read(&cn.status)
+
+	return s
 }
 
-func (cn *Conn) Prepare(q string) (st driver.Stmt, err error) {
-	defer recoverErr(&err)
+// cachedStmt returns the statement cached for q, preparing and caching it
+// if needed. It returns nil if the statement cache is disabled, and for
+// queries with parameter types: a cached statement keeps the types it was
+// first parsed with.
+func (cn *Conn) cachedStmt(q string, oids []Oid) *stmt {
+	if cn.stmts == nil || oids != nil {
+		return nil
+	}
 
-	cn.setHead('P')
-	cn.write("")
-	cn.write(q)
-	cn.write(int16(0))
+	if st := cn.stmts.get(q); st != nil {
+		return st
+	}
+
+	st := cn.prepare(q)
+	if old := cn.stmts.put(st); old != nil {
+		cn.closeStmt(old.name)
+	}
+	return st
+}
+
+// evictStale drops the statement cached for q when running it panicked
+// with an error saying it is stale: the result type of the query changed
+// with the schema ("cached plan must not change result type"), or the
+// statement was deallocated behind the driver's back. The next call
+// prepares it again. Deferred, it passes the panic on.
+func (cn *Conn) evictStale(q string) {
+	x := recover()
+	if x == nil {
+		return
+	}
+	if e, ok := x.(*Error); ok && !cn.bad {
+		switch e.Code {
+		case "0A000":
+			if st := cn.stmts.remove(q); st != nil {
+				// It still exists on the server.
+				cn.closeStmt(st.name)
+			}
+		case "26000":
+			cn.stmts.remove(q)
+		}
+	}
+	panic(x)
+}
+
+// closeStmt deallocates the named statement on the server.
+func (cn *Conn) closeStmt(name string) {
+	cn.setHead('C')
+	cn.write(byte('S'))
+	cn.write(name)
 	cn.sendMsg()
 
 	cn.setHead('S')
 	cn.sendMsg()
 
 	cn.recvMsg()
-	if cn.T != '1' {
-		panic(errf("unknown response from parse: '%c'", cn.T))
+	if cn.T != '3' {
+		panic(cn.unexpected("close"))
 	}
 
 	cn.recvMsg()
 	if cn.T != 'Z' {
-		panic(errf("unknown response from parse: '%c'", cn.T))
+		panic(cn.unexpected("close"))
 	}
 	cn.read(&cn.status)
+}
+
+// Query implements driver.Queryer. The query is parsed into the unnamed
+// statement and bound, described and executed in the same exchange, saving
+// the Prepare/Close round trips database/sql would otherwise make.
+//
+// Queries without arguments, and all queries when prefer_simple_protocol=true,
+// go through the simple query protocol instead: a single 'Q' message and no
+// Sync, which also keeps transaction-pooling proxies happy.
+func (cn *Conn) Query(q string, args []driver.Value) (r driver.Rows, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	var oids []Oid
+	oids, args = paramTypes(args)
+	cn.sampleParams(q, args)
+
+	if len(args) == 0 || cn.preferSimple {
+		if len(args) > 0 {
+			q, err = interpolate(q, args)
+			if err != nil {
+				return nil, err
+			}
+		}
+		cn.startTrace(q)
+		return cn.simpleQuery(q), nil
+	}
+
+	if st := cn.cachedStmt(q, oids); st != nil {
+		defer cn.evictStale(q)
+		cn.startTrace(q)
+		st.sendExec(args, cn.fetchSize)
+		return st.recvRows(), nil
+	}
+
+	cn.startTrace(q)
+	cn.parse("", q, oids)
+	cn.describe("")
 
-	return &stmt{Conn: cn}, nil
+	st := &stmt{Conn: cn, q: q, paramTyps: oids}
+	st.sendExec(args, cn.fetchSize)
+
+	cn.recvParseComplete()
+	st.recvDescribe()
+	return st.recvRows(), nil
+}
+
+// Exec implements driver.Execer. Without arguments the query goes through
+// the simple query protocol, so q may be a whole script of
+// semicolon-separated statements; the result is that of the last one.
+func (cn *Conn) Exec(q string, args []driver.Value) (res driver.Result, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	var oids []Oid
+	oids, args = paramTypes(args)
+	cn.sampleParams(q, args)
+
+	if len(args) == 0 || cn.preferSimple {
+		if len(args) > 0 {
+			q, err = interpolate(q, args)
+			if err != nil {
+				return nil, err
+			}
+		}
+		cn.startTrace(q)
+		cn.setHead('Q')
+		cn.write(q)
+		cn.sendMsg()
+		return cn.recvExec(), nil
+	}
+
+	if st := cn.cachedStmt(q, oids); st != nil {
+		defer cn.evictStale(q)
+		cn.startTrace(q)
+		st.sendExec(args, 0)
+		return cn.recvExec(), nil
+	}
+
+	cn.startTrace(q)
+	cn.parse("", q, oids)
+
+	st := &stmt{Conn: cn, q: q, paramTyps: oids}
+	st.sendExec(args, 0)
+
+	return cn.recvExec(), nil
+}
+
+// recvExec reads up to ReadyForQuery and returns the result of the last
+// command completed. The rows commands return are skipped on the wire.
+func (cn *Conn) recvExec() *Result {
+	cn.discardRows = true
+	defer func() {
+		cn.discardRows = false
+	}()
+
+	res := new(Result)
+	for {
+		cn.recvMsg()
+		switch cn.T {
+		case 'C':
+			res = parseCommandTag(cn.readCString())
+		case 'I':
+			// An empty query: nothing was done.
+			res = new(Result)
+		case 'Z':
+			cn.read(&cn.status)
+			return res
+		case 'G':
+			// A COPY FROM STDIN run directly: refuse it, which makes
+			// the server send the error that ends it.
+			cn.b.Reset()
+			cn.setHead('f')
+			cn.write("COPY FROM STDIN is only supported through CopyIn")
+			cn.sendMsg()
+		case '1', '2', 't', 'T', 'n', 'D', 'H', 'd', 'c':
+			// Throw away messages we don't care about, including the
+			// data of a COPY TO STDOUT run through Exec
+		default:
+			panic(cn.unexpected("exec"))
+		}
+	}
+}
+
+func (cn *Conn) simpleQuery(q string) *rows {
+	cn.setHead('Q')
+	cn.write(q)
+	cn.sendMsg()
+
+	// Start out past the end of an empty result set and advance to the
+	// first one that returns rows.
+	r := &rows{Conn: cn, eof: true}
+	r.recvResultEnd()
+	if r.next != nil {
+		r.fields, r.next, r.eof = r.next, nil, false
+	}
+	return r
+}
+
+func (cn *Conn) parse(name, q string, oids []Oid) {
+	cn.setHead('P')
+	cn.write(name)
+	cn.write(q)
+	cn.write(int16(len(oids)), oids)
+	cn.sendMsg()
+}
+
+// describe asks for the parameter and row descriptions of the named
+// statement.
+func (cn *Conn) describe(name string) {
+	cn.setHead('D')
+	cn.write(byte('S'))
+	cn.write(name)
+	cn.sendMsg()
+}
+
+func (cn *Conn) recvParseComplete() {
+	cn.recvMsg()
+	if cn.T != '1' {
+		panic(cn.unexpected("parse"))
+	}
+}
+
+// ParameterStatus returns the current value of a run-time parameter the
+// server reports to clients, such as server_version, TimeZone or
+// DateStyle, or "" if it hasn't reported it. The values follow SET and
+// DISCARD commands and configuration reloads.
+func (cn *Conn) ParameterStatus(name string) string {
+	return cn.params[name]
+}
+
+// recvParameterStatus records the ParameterStatus in the message buffer.
+func (cn *Conn) recvParameterStatus() {
+	k := cn.readCString()
+	v := cn.readCString()
+	if cn.params == nil {
+		cn.params = make(map[string]string)
+	}
+	cn.params[k] = v
+	if k == "TimeZone" {
+		// Zones Go doesn't know, such as POSIX specifications, leave
+		// timestamptz values at the offset the server sent.
+		cn.loc, _ = time.LoadLocation(v)
+	}
 }
 
 func (cn *Conn) sendMsg() {
+	cn.state = sentState(cn.state, cn.T)
+	if cn.wbuf != nil {
+		cn.writeTo(cn.wbuf)
+		return
+	}
 	cn.writeTo(cn.c)
+	cn.sent = true
 }
 
 func (cn *Conn) recvMsg() {
-	cn.readFrom(cn.c)
+	// Notifications, notices and parameter changes may arrive between any
+	// two messages.
+	for {
+		cn.readFrom(cn.c)
+		switch cn.T {
+		case 'A':
+			cn.recvNotification()
+			continue
+		case 'N':
+			cn.recvNotice()
+			continue
+		case 'S':
+			cn.recvParameterStatus()
+			continue
+		}
+		break
+	}
+
+	cn.checkState()
+	if cn.T == 'Z' {
+		cn.state = stateReady
+	}
+
 	if cn.T == 'E' {
-		panic(readError(cn))
+		err := readError(cn)
+		if cn.unsynced {
+			// The server skips everything up to the next Sync.
+			cn.sync()
+		}
+		if cn.trace != nil {
+			cn.trace.Err = err
+		}
+		if !isFatal(err) {
+			cn.recvReady()
+		}
+		panic(err)
 	}
+	cn.traceMsg()
+}
+
+// recvReady throws away everything up to ReadyForQuery, so that the
+// connection is back in a clean state after an error.
+func (cn *Conn) recvReady() {
+	cn.discardRows = true
+	defer func() {
+		cn.discardRows = false
+	}()
+
+	for {
+		cn.recvMsg()
+		if cn.T == 'Z' {
+			cn.read(&cn.status)
+			return
+		}
+	}
+}
+
+// isFatal reports whether err is a server error that ends the session,
+// after which the server closes the connection instead of sending
+// ReadyForQuery.
+func isFatal(err error) bool {
+	e, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	switch e.Severity {
+	case "FATAL", "PANIC":
+		return true
+	}
+	return false
 }
 
 type stmt struct {
 	*Conn
-	q string
+	name      string
+	q         string
+	paramTyps []Oid
+	fields    []fieldDesc
+
+	// formats holds the result format code requested for each field;
+	// nil requests text for all of them.
+	formats []int16
 }
 
-// Need to talk with bradfitz about this before implementing these.
-func (st *stmt) Close() error                                 { return nil }
-func (st *stmt) NumInput() int                                { return -1 }
-func (st *stmt) Exec(v []driver.Value) (driver.Result, error) { panic("todo") }
+// Close deallocates the statement on the server, so long-lived
+// connections don't accumulate prepared statements.
+func (st *stmt) Close() (err error) {
+	defer st.errRecover(&err)
+	st.checkBad()
+	st.enter()
+	defer st.leave()
+	st.checkReady()
+
+	if st.name != "" {
+		st.closeStmt(st.name)
+	}
+	return nil
+}
+
+// NumInput returns the number of parameters the server reported for the
+// statement, letting database/sql check argument counts before Bind.
+func (st *stmt) NumInput() int {
+	return len(st.paramTyps)
+}
+
+func (st *stmt) Exec(v []driver.Value) (res driver.Result, err error) {
+	defer st.errRecover(&err)
+	st.checkBad()
+	st.enter()
+	defer st.leave()
+	st.checkReady()
+	st.checkTx(st.q)
+
+	_, v = paramTypes(v)
+	st.sampleParams(st.q, v)
+	st.startTrace(st.q)
+
+	st.sendExec(v, 0)
+	return st.recvExec(), nil
+}
 
 func (st *stmt) Query(v []driver.Value) (r driver.Rows, err error) {
-	defer recoverErr(&err)
+	defer st.errRecover(&err)
+	st.checkBad()
+	st.enter()
+	defer st.leave()
+	st.checkReady()
+	st.checkTx(st.q)
+
+	_, v = paramTypes(v)
+	st.sampleParams(st.q, v)
+	st.startTrace(st.q)
+
+	st.sendExec(v, st.fetchSize)
+	return st.recvRows(), nil
+}
 
-	st.setHead('D')
-	st.write(byte('S'))
-	st.write("")
-	st.sendMsg()
+// sendExec binds v to the statement and executes the portal, fetching at
+// most maxRows rows at a time if maxRows is positive.
+func (st *stmt) sendExec(v []driver.Value, maxRows int32) {
+	st.bind(v)
+	st.execute(maxRows)
+}
 
+// bind binds v to the statement in the unnamed portal.
+func (st *stmt) bind(v []driver.Value) {
 	st.setHead('B')
 	st.write("")
-	st.write("")
-	st.write(int16(0))
-	st.write(int16(len(v)))
-	for _, v := range v {
-		l, s := encodeParam(v)
-		st.write(l, s)
+	st.write(st.name)
+	formats, values := st.paramFormats(v)
+	st.write(int16(len(formats)), formats)
+	st.write(int16(len(values)))
+	for _, b := range values {
+		if b == nil {
+			st.write(int32(-1))
+			continue
+		}
+		st.write(int32(len(b)), b)
+	}
+	st.write(int16(len(st.formats)))
+	for _, f := range st.formats {
+		st.write(f)
 	}
-	st.write(int16(0))
 	st.sendMsg()
+}
 
-	st.setHead('E')
-	st.write("")
-	st.write(int32(0))
-	st.sendMsg()
+// execute runs the unnamed portal. When fetching a limited number of rows
+// it sends Flush instead of Sync, which would end the implicit transaction
+// and destroy the portal; the Sync follows once the portal completes.
+func (cn *Conn) execute(maxRows int32) {
+	cn.setHead('E')
+	cn.write("")
+	cn.write(maxRows)
+	cn.sendMsg()
 
-	st.setHead('S')
-	st.sendMsg()
+	if maxRows > 0 {
+		cn.setHead('H')
+		cn.sendMsg()
+		cn.unsynced = true
+		return
+	}
 
-	st.recvParameterDescription()
-	col := st.recvRowDescription()
+	cn.sync()
+}
 
+func (cn *Conn) sync() {
+	cn.setHead('S')
+	cn.sendMsg()
+	cn.unsynced = false
+}
+
+func (st *stmt) recvRows() *rows {
 	st.recvMsg()
 	if st.T != '2' {
-		panic(errf("unknown response for bind: '%c'", st.T))
+		panic(st.unexpected("bind"))
 	}
 
-	return &rows{col: col, Conn: st.Conn}, nil
+	fields := st.fields
+	if st.formats != nil {
+		fields = make([]fieldDesc, len(st.fields))
+		for i, f := range st.fields {
+			f.format = st.formats[i]
+			fields[i] = f
+		}
+	}
+
+	return &rows{fields: fields, Conn: st.Conn}
 }
 
-func (st *stmt) recvParameterDescription() {
-	// Assert then ignore this message for now
+// recvDescribe reads the ParameterDescription and RowDescription (or
+// NoData) answering a statement Describe.
+func (st *stmt) recvDescribe() {
 	st.recvMsg()
 	if st.T != 't' {
 		panic(errf("expected parameter description, got: '%c'", st.T))
 	}
-	st.msg = newMsg()
-}
 
-func (st *stmt) recvRowDescription() []string {
+	var n int16
+	st.read(&n)
+	st.paramTyps = make([]Oid, n)
+	for i := range st.paramTyps {
+		st.read(&st.paramTyps[i])
+	}
+
 	st.recvMsg()
-	if st.T != 'T' {
+	switch st.T {
+	case 'T':
+		st.fields = st.readRowDescription()
+	case 'n':
+		// NoData: the statement returns no rows (an INSERT without
+		// RETURNING, DDL, ...). Query yields an empty result for it.
+		st.fields = nil
+	default:
 		panic(errf("expected row description, got: '%c'", st.T))
 	}
+}
 
-	var n int16
-	st.read(&n)
+// fieldDesc describes a result column, as sent in a RowDescription.
+type fieldDesc struct {
+	name   string
+	table  Oid
+	attnum int16
+	typ    Oid
+	typlen int16
+	typmod int32
+	format int16
+}
 
-	col := make([]string, n)
-	for i := 0; i < len(col); i++ {
-		col[i] = st.readCString()
-		st.msg.b.Next(18) // Throw away unwanted (for now) fields.
+func (cn *Conn) readRowDescription() []fieldDesc {
+	var n int16
+	cn.read(&n)
+
+	fields := make([]fieldDesc, n)
+	for i := range fields {
+		f := &fields[i]
+		f.name = cn.readCString()
+		cn.read(&f.table)
+		cn.read(&f.attnum)
+		cn.read(&f.typ)
+		cn.read(&f.typlen)
+		cn.read(&f.typmod)
+		cn.read(&f.format)
 	}
 
-	return col
+	return fields
+}
+
+func columnNames(fields []fieldDesc) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
 }
 
 type rows struct {
 	*Conn
-	col []string
-	done bool
+	fields []fieldDesc
+	done   bool
+
+	// eof is set once the current result set is exhausted; next then
+	// holds the fields of the following one, if any.
+	eof  bool
+	next []fieldDesc
+
+	// tag is the tag of the last command completed.
+	tag string
 }
 
 func (r *rows) Columns() []string {
-	return r.col
+	return columnNames(r.fields)
 }
 
-func (r *rows) Close() error {
+// Close discards what is left of the result up to ReadyForQuery. A portal
+// suspended by fetch_size is closed with Close and Sync, so the server
+// stops producing its rows. Otherwise Execute asked for every row and was
+// followed by Sync: the server has already sent them all, and closing the
+// portal could not stop that, so the rest of them are skipped on the wire
+// without being buffered or decoded. To bound what closing a huge result
+// early costs, set fetch_size.
+func (r *rows) Close() (err error) {
+	defer r.errRecover(&err)
+	r.enter()
+	defer r.leave()
+
+	if r.unsynced {
+		// Drop the suspended portal instead of fetching the rest of it.
+		r.setHead('C')
+		r.write(byte('P'))
+		r.write("")
+		r.sendMsg()
+		r.sync()
+	}
+
+	r.discardRows = true
 	defer func() {
-		r.msg = newMsg()
+		r.discardRows = false
 	}()
 
-	// TODO: Should I be doing this? Ask bradfitz.
-	//    NOTE: QueryRow doesn't work without this because it never reads until EOF
-	//    and so there is still a 'C' waiting in the pipe.
-	for {
-		err := r.Next(nil)
-		switch err {
-		case nil:
-		case io.EOF:
-			return nil
+	for !r.done {
+		r.recvMsg()
+		switch r.T {
+		case 'D', 'C', 's', '3', 'T', 'I':
+			// Throw away messages we don't care about
+		case 'Z':
+			r.read(&r.status)
+			r.done = true
 		default:
-			return err
+			panic(r.unexpected("execute"))
 		}
-
-		// Throw away messages we don't care about
 	}
 	return nil
 }
 
 func (r *rows) Next(dest []driver.Value) (err error) {
-	if r.done {
+	if r.done || r.eof {
 		return io.EOF
 	}
 
-	defer recoverErr(&err)
+	defer func() {
+		if err != nil && err != io.EOF {
+			// The connection has been brought back to ReadyForQuery
+			// (or is beyond repair); either way this result is over.
+			r.done = true
+		}
+	}()
+	defer r.errRecover(&err)
+	r.enter()
+	defer r.leave()
 
 	r.recvMsg()
-	switch {
-	case r.T == 'C':
+	for r.T == 's' {
+		// Portal suspended: fetch the next batch of rows.
+		r.execute(r.fetchSize)
 		r.recvMsg()
-		if r.T != 'Z' {
-			return errf("expected 'Z' but got: '%c'", r.T)
+	}
+
+	switch {
+	case r.T == 'C' || r.T == 'I':
+		if r.T == 'C' {
+			r.tag = r.readCString()
 		}
-		r.read(&r.status)
-		r.done = true
+		if r.unsynced {
+			r.sync()
+		}
+		r.eof = true
+		r.recvResultEnd()
 		return io.EOF
 	case r.T != 'D':
-		return errf("unknown response for execute: '%c'", r.T)
+		return r.unexpected("execute")
+	case r.discardRows:
+		return nil
 	}
 
+	r.readDataRow(dest, r.fields)
+	return nil
+}
+
+// recvResultEnd reads past the end of a result set, up to either the
+// RowDescription of the next one or ReadyForQuery. Results of commands
+// returning no rows are skipped.
+func (r *rows) recvResultEnd() {
+	for {
+		r.recvMsg()
+		switch r.T {
+		case 'T':
+			r.next = r.readRowDescription()
+			return
+		case 'C':
+			r.tag = r.readCString()
+		case 'I':
+			// An empty query.
+		case 'Z':
+			r.read(&r.status)
+			r.done = true
+			return
+		default:
+			panic(r.unexpected("execute"))
+		}
+	}
+}
+
+// HasNextResultSet implements driver.RowsNextResultSet. It only knows
+// about the next result set once the current one has been read through.
+func (r *rows) HasNextResultSet() bool {
+	return r.next != nil
+}
+
+// NextResultSet implements driver.RowsNextResultSet, skipping whatever is
+// left of the current result set.
+func (r *rows) NextResultSet() (err error) {
+	defer r.errRecover(&err)
+
+	r.discardRows = true
+	defer func() {
+		r.discardRows = false
+	}()
+
+	for !r.eof && !r.done {
+		if err := r.Next(nil); err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	if r.next == nil {
+		return io.EOF
+	}
+	r.fields, r.next, r.eof = r.next, nil, false
+	return nil
+}
+
+// readDataRow reads the DataRow in the message buffer into dest, decoding
+// the values according to fields. Without fields the raw text of every
+// value is returned.
+func (cn *Conn) readDataRow(dest []driver.Value, fields []fieldDesc) {
 	var n int16
 	var l int32
 
-	r.read(&n)
+	cn.read(&n)
 	for i := int16(0); i < n; i++ {
-		r.read(&l)
+		cn.read(&l)
 		if l < 0 { // nil
 			dest[i] = nil
 			continue
 		}
 		b := make([]byte, l)
-		r.read(b)
+		cn.read(b)
+		if fields != nil {
+			dest[i] = cn.decode(&fields[i], b)
+			continue
+		}
 		dest[i] = b
 	}
+}
 
-	return nil
+// errRecover is recoverErr for the methods of a connection. Errors that
+// leave the connection unusable mark it bad, which retires it from the
+// database/sql pool; I/O errors and fatal server errors hit before the
+// call sent anything are replaced by driver.ErrBadConn, so that
+// database/sql retries it elsewhere.
+func (cn *Conn) errRecover(err *error) {
+	x := recover()
+	if x == nil {
+		return
+	}
+
+	switch v := x.(type) {
+	case runtime.Error:
+		panic(x)
+	case error:
+		*err = v
+	default:
+		panic(x)
+	}
+
+	// Once something was sent, the statement may have run: retrying it
+	// could run it twice.
+	switch e := (*err).(type) {
+	case *Error:
+		if isFatal(e) {
+			cn.bad = true
+			if !cn.sent {
+				*err = driver.ErrBadConn
+			}
+		}
+	case net.Error:
+		cn.bad = true
+		if !cn.sent {
+			*err = driver.ErrBadConn
+		}
+	default:
+		if _, ok := e.(*ErrUnsupportedParameterType); ok {
+			// Arguments are encoded before they are sent.
+			break
+		}
+		if e == errRowsOpen || e == errTxAborted {
+			// Nothing was sent: the connection is as it was.
+			break
+		}
+		cn.bad = true
+		if (e == io.EOF || e == io.ErrUnexpectedEOF) && !cn.sent {
+			*err = driver.ErrBadConn
+		}
+	}
+}
+
+// errRowsOpen is the error of a command started while the rows of an
+// earlier one are still being read.
+var errRowsOpen = errf("previous result set not closed: read the rows of the last query through or close them before running another command")
+
+// checkReady refuses to start a command while the server is still
+// answering the last one, which would interleave the two exchanges.
+func (cn *Conn) checkReady() {
+	if cn.state != stateReady {
+		panic(errRowsOpen)
+	}
+}
+
+// errConnBusy is the error of a call made while another one is running on
+// the same connection.
+var errConnBusy = errf("connection busy: concurrent use detected")
+
+// enter marks the connection in use until the matching leave. A Conn
+// serves one call at a time: the messages of concurrent calls would
+// interleave on the wire and corrupt both, so a call that overlaps another
+// fails instead, and the connection is marked bad.
+func (cn *Conn) enter() {
+	if !atomic.CompareAndSwapInt32(&cn.inUse, 0, 1) {
+		panic(errConnBusy)
+	}
+	cn.sent = false
+}
+
+func (cn *Conn) leave() {
+	atomic.StoreInt32(&cn.inUse, 0)
+}
+
+// checkBad refuses to use a connection that has been marked bad.
+func (cn *Conn) checkBad() {
+	if cn.bad {
+		panic(driver.ErrBadConn)
+	}
 }
 
 func recoverErr(err *error) {
@@ -512,24 +1393,56 @@ func recoverErr(err *error) {
 	}
 }
 
-func dial(o Values) (net.Conn, error) {
+// dial connects to the first reachable server listed in the host option,
+// which may hold several comma-separated hosts with matching ports, and
+// reports whether it is the first, primary host. When avoidFirst is set
+// the first host is tried last.
+func dial(ctx context.Context, o Values, avoidFirst bool) (c net.Conn, primary bool, err error) {
 	// TODO: support possible network types
 	// See: http://www.postgresql.org/docs/7.4/static/libpq.html#LIBPQ-CONNECT
-	host := o.Get("host")
+	hosts := strings.Split(o.Get("host"), ",")
+	ports := strings.Split(o.Get("port"), ",")
+	if len(ports) != 1 && len(ports) != len(hosts) {
+		return nil, false, errf("%d port(s) given for %d host(s)", len(ports), len(hosts))
+	}
+
+	order := make([]int, len(hosts))
+	for i := range order {
+		order[i] = i
+	}
+	if avoidFirst && len(order) > 1 {
+		order = append(order[1:], order[0])
+	}
+
+	for _, i := range order {
+		port := ports[0]
+		if len(ports) > 1 {
+			port = ports[i]
+		}
+		c, err = dialHost(ctx, hosts[i], port)
+		if err == nil {
+			return c, i == 0, nil
+		}
+	}
+
+	return nil, false, err
+}
+
+func dialHost(ctx context.Context, host, port string) (net.Conn, error) {
+	var d net.Dialer
 	if strings.HasPrefix(host, "/") {
-		return net.Dial("unix", host)
+		return d.DialContext(ctx, "unix", host)
 	}
 
 	if host == "" {
 		host = "localhost"
 	}
 
-	port := o.Get("port")
 	if port == "" {
 		port = "5432"
 	}
 
-	return net.Dial("tcp", host+":"+port)
+	return d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
 }
 
 func parseConnString(cs string) (Values, error) {
@@ -587,21 +1500,169 @@ func errf(s string, args ...interface{}) error {
 	return fmt.Errorf("pq: "+s, args...)
 }
 
+// CheckNamedValue implements driver.NamedValueChecker. Values of the types
+// encodeParam handles are passed through as they are; driver.Valuer
+// results and other values database/sql knows how to convert are
+// converted first, as is the value of a TypedParam. Pointers are sent as
+// what they point to, and nil ones as NULL. Anything else is refused with
+// an error naming the argument before the query is sent.
+func (cn *Conn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := checkParam(nv.Value)
+	if err != nil {
+		return paramError(nv.Ordinal, err)
+	}
+	nv.Value = v
+	return nil
+}
+
+// checkParams converts v as CheckNamedValue does, for arguments that
+// don't come through database/sql.
+func checkParams(v []driver.Value) ([]driver.Value, error) {
+	out := make([]driver.Value, len(v))
+	for i, x := range v {
+		y, err := checkParam(x)
+		if err != nil {
+			return nil, paramError(i+1, err)
+		}
+		out[i] = y
+	}
+	return out, nil
+}
+
+// paramError returns err, the error of the argument $n, naming it.
+func paramError(n int, err error) error {
+	if e, ok := err.(*ErrUnsupportedParameterType); ok {
+		e.Index = n
+		return e
+	}
+	return errf("argument $%d: %v", n, err)
+}
+
+func checkParam(v interface{}) (interface{}, error) {
+	if tp, ok := v.(TypedParam); ok {
+		var err error
+		tp.Value, err = checkParam(tp.Value)
+		return tp, err
+	}
+
+	if vr, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(vr); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, nil
+		}
+		var err error
+		v, err = vr.Value()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		// A typed nil, of any pointer type.
+		return nil, nil
+	}
+
+	if d, ok := v.(time.Duration); ok {
+		// As an interval rather than the int64 it converts to.
+		return IntervalOf(d).String(), nil
+	}
+	if e, ok := v.(Enum); ok {
+		return e.EnumLabel(), nil
+	}
+
+	switch v.(type) {
+	case nil, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, string, []byte, bool, time.Time:
+		// Unsigned integers too large for an int64 are sent as their
+		// decimal text, which a numeric takes and the integer types
+		// reject as out of range, rather than wrapping around.
+		return v, nil
+	}
+
+	if s, ok := encodeNumeric(v); ok {
+		return s, nil
+	}
+	if s, ok := uuidParam(v); ok {
+		return s, nil
+	}
+	if s, ok := compositeParam(v); ok {
+		return s, nil
+	}
+	if s, ok := netParam(v); ok {
+		if s == "" {
+			return nil, nil
+		}
+		return s, nil
+	}
+	if s, ok := typeParam(v); ok {
+		return s, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+		// A pointer none of the above takes is sent as what it
+		// points to.
+		return checkParam(rv.Elem().Interface())
+	}
+
+	cv, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		if s, ok := v.(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+		return nil, &ErrUnsupportedParameterType{Type: reflect.TypeOf(v)}
+	}
+	return cv, nil
+}
+
 func encodeParam(param interface{}) (int32, []byte) {
+	if vr, ok := param.(driver.Valuer); ok {
+		// Values that haven't been through CheckNamedValue, such as
+		// those interpolated into a query.
+		if rv := reflect.ValueOf(vr); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return -1, []byte{}
+		}
+		v, err := vr.Value()
+		if err != nil {
+			panic(err)
+		}
+		param = v
+	}
+	if rv := reflect.ValueOf(param); rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return -1, []byte{}
+		}
+		return encodeParam(rv.Elem().Interface())
+	}
+
 	var s string
 	switch param.(type) {
 	default:
-		panic(fmt.Sprintf("unknown type for %T", param))
-	case int, uint8, uint16, uint32, uint64, int8, int16, int32, int64:
+		panic(&ErrUnsupportedParameterType{Type: reflect.TypeOf(param)})
+	case int, uint, uint8, uint16, uint32, uint64, int8, int16, int32, int64:
 		s = fmt.Sprintf("%d", param)
 	case float32, float64:
 		s = fmt.Sprintf("%f", param)
-	case string, []byte:
-		s = fmt.Sprintf("%s", param)
+	case string:
+		s = param.(string)
+	case []byte:
+		// The hex format of bytea: sent verbatim, arbitrary bytes
+		// would be taken for text, and mangled.
+		b := param.([]byte)
+		buf := make([]byte, 2+hex.EncodedLen(len(b)))
+		buf[0], buf[1] = '\\', 'x'
+		hex.Encode(buf[2:], b)
+		return int32(len(buf)), buf
 	case bool:
 		s = fmt.Sprintf("%t", param)
 	case time.Time:
-		s = param.(time.Time).Format(timeFormat)
+		t := param.(time.Time)
+		if sign, inf := isInfinity(t); inf {
+			s = "infinity"
+			if sign < 0 {
+				s = "-infinity"
+			}
+			break
+		}
+		s = formatTimestamp(t)
+	case time.Duration:
+		s = IntervalOf(param.(time.Duration)).String()
 	case nil:
 		return -1, []byte{}
 	}
@@ -609,31 +1670,21 @@ func encodeParam(param interface{}) (int32, []byte) {
 	return int32(len(s)), []byte(s)
 }
 
-type ErrorFields map[byte]string
+// ErrUnsupportedParameterType is the error of an argument of a Go type the
+// driver can't send.
+type ErrUnsupportedParameterType struct {
+	// Index is the position of the argument, counting from 1 as its
+	// placeholder does, or 0 if it isn't known.
+	Index int
 
-type ServerError struct {
-	Fields ErrorFields
+	// Type is the Go type of the argument.
+	Type reflect.Type
 }
 
-func (err *ServerError) Error() (s string) {
-	for k, v := range err.Fields {
-		s += fmt.Sprintf(` '%c':%s`, k, v)
+func (e *ErrUnsupportedParameterType) Error() string {
+	var arg string
+	if e.Index > 0 {
+		arg = fmt.Sprintf("argument $%d: ", e.Index)
 	}
-	return
-}
-
-func readError(cn *Conn) (err error) {
-	defer recoverErr(&err)
-
-	e := &ServerError{Fields: make(ErrorFields)}
-	var t byte
-	for {
-		cn.read(&t)
-		if t == 0 {
-			break
-		}
-		e.Fields[t] = cn.readCString()
-	}
-
-	return e
+	return fmt.Sprintf("pq: %sunsupported type %v; use an integer, float, bool, string, []byte, time.Time, a pointer to one, or a driver.Valuer", arg, e.Type)
 }
@@ -1,10 +1,18 @@
 package pq
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type readWriteLogger struct {
@@ -84,8 +92,8 @@ func TestError(t *testing.T) {
 		t.Fatal("expected error")
 	}
 
-	if _, ok := err.(*ServerError); !ok {
-		t.Fatal("expected *ServerError")
+	if _, ok := err.(*Error); !ok {
+		t.Fatal("expected *Error")
 	}
 }
 
@@ -121,7 +129,699 @@ func TestInvalidProtocolParseURL(t *testing.T) {
 	default:
 		msg := "invalid connection protocol: http"
 		if err.Error() != msg {
-			t.Fatal("Unexpected error message:\n+ %s\n- %s", err.Error(), msg)
+			t.Fatalf("Unexpected error message:\n+ %s\n- %s", err.Error(), msg)
 		}
 	}
 }
+
+func TestPreferSimpleProtocol(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost user=pqgotest password=foo sslmode=disable prefer_simple_protocol=true")
+	if err != nil {
+		t.Fatalf("unable to open database connection: %v", err)
+	}
+	defer db.Close()
+
+	var s string
+	err = db.QueryRow("SELECT $1::text", "it's").Scan(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s != "it's" {
+		t.Fatalf("expected %q, got %q", "it's", s)
+	}
+}
+
+func TestExecScript(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost user=pqgotest password=foo sslmode=disable")
+	if err != nil {
+		t.Fatalf("unable to open database connection: %v", err)
+	}
+	defer db.Close()
+
+	r, err := db.Exec(`
+		CREATE TEMP TABLE pqgotest_script (a int);
+		INSERT INTO pqgotest_script VALUES (1), (2);
+		SELECT * FROM pqgotest_script;
+		UPDATE pqgotest_script SET a = a + 1;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := r.RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Fatalf("expected 2 rows affected, got %d", n)
+	}
+}
+
+func TestParseCommandTag(t *testing.T) {
+	tests := map[string]int64{
+		"INSERT 0 5":   5,
+		"UPDATE 3":     3,
+		"SELECT 10":    10,
+		"CREATE TABLE": 0,
+	}
+
+	for tag, expected := range tests {
+		n, err := parseCommandTag(tag).RowsAffected()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != expected {
+			t.Errorf("%q: expected %d rows affected, got %d", tag, expected, n)
+		}
+	}
+}
+
+func TestNumInput(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost user=pqgotest password=foo sslmode=disable")
+	if err != nil {
+		t.Fatalf("unable to open database connection: %v", err)
+	}
+	defer db.Close()
+
+	s, err := db.Prepare("SELECT $1::int, $2::text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	_, err = s.Query(1)
+	if err == nil {
+		t.Fatal("expected an argument count error")
+	}
+	if _, ok := err.(*Error); ok {
+		t.Fatal("expected the argument count to be checked before reaching the server")
+	}
+}
+
+func TestPreparedStatementReuse(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost user=pqgotest password=foo sslmode=disable")
+	if err != nil {
+		t.Fatalf("unable to open database connection: %v", err)
+	}
+	defer db.Close()
+
+	s, err := db.Prepare("SELECT $1::int + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		var n int
+		if err := s.QueryRow(i).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != i+1 {
+			t.Fatalf("expected %d, got %d", i+1, n)
+		}
+	}
+}
+
+func TestSimpleQueryRaw(t *testing.T) {
+	cn, err := Open("host=localhost user=pqgotest password=foo sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cn.Close()
+
+	results, _, err := cn.SimpleQuery("SELECT 1, NULL; CREATE TEMP TABLE pqgotest_raw (a int)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	if string(results[0].Rows[0][0]) != "1" || results[0].Rows[0][1] != nil {
+		t.Fatalf("unexpected row %q", results[0].Rows[0])
+	}
+
+	if results[1].CommandTag != "CREATE TABLE" {
+		t.Fatalf("unexpected command tag %q", results[1].CommandTag)
+	}
+}
+
+func TestStmtCloseDeallocates(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost user=pqgotest password=foo sslmode=disable")
+	if err != nil {
+		t.Fatalf("unable to open database connection: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	s, err := db.Prepare("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	err = db.QueryRow("SELECT count(*) FROM pg_prepared_statements").Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 0 {
+		t.Fatalf("expected no prepared statements left, got %d", n)
+	}
+}
+
+func TestRowsCloseEarly(t *testing.T) {
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(dataRowStream([][]byte{[]byte("1")}, 100))}, msg: newMsg(), state: stateExtendedQuery}
+	r := &rows{Conn: cn, fields: []fieldDesc{{name: "a"}}}
+
+	dest := make([]driver.Value, 1)
+	if err := r.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cn.status != 'I' {
+		t.Fatalf("expected to have read up to ReadyForQuery, status is %q", cn.status)
+	}
+
+	if cn.discardRows {
+		t.Fatal("expected discardRows to be reset")
+	}
+
+	if w := cn.c.(*replayConn).w.Len(); w != 0 {
+		t.Fatalf("expected nothing to be sent for a portal already run to completion, sent %d bytes", w)
+	}
+}
+
+func TestFetchSize(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	for _, typ := range []int8{'D', 's', 'D', 'C', 'Z'} {
+		m.setHead(typ)
+		switch typ {
+		case 'D':
+			m.write(int16(1), int32(1), []byte("1"))
+		case 'C':
+			m.write("SELECT 2")
+		case 'Z':
+			m.write(byte('I'))
+		}
+		m.writeTo(&buf)
+	}
+
+	conn := &replayConn{r: bytes.NewReader(buf.Bytes())}
+	cn := &Conn{c: conn, msg: newMsg(), fetchSize: 1, unsynced: true, state: stateExtendedQuery}
+	r := &rows{Conn: cn, fields: []fieldDesc{{name: "a"}}}
+
+	n := 0
+	dest := make([]driver.Value, 1)
+	for r.Next(dest) == nil {
+		n++
+	}
+
+	if n != 2 {
+		t.Fatalf("expected 2 rows, got %d", n)
+	}
+
+	if types := sentTypes(t, conn.w.Bytes()); types != "EHS" {
+		t.Fatalf("expected Execute, Flush and Sync to be sent, got %q", types)
+	}
+}
+
+func TestRowsCloseSuspended(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	for _, typ := range []int8{'D', 's', '3', 'Z'} {
+		m.setHead(typ)
+		switch typ {
+		case 'D':
+			m.write(int16(1), int32(1), []byte("1"))
+		case 'Z':
+			m.write(byte('I'))
+		}
+		m.writeTo(&buf)
+	}
+
+	conn := &replayConn{r: bytes.NewReader(buf.Bytes())}
+	cn := &Conn{c: conn, msg: newMsg(), fetchSize: 1, unsynced: true}
+	r := &rows{Conn: cn, fields: []fieldDesc{{name: "a"}}}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if types := sentTypes(t, conn.w.Bytes()); types != "CS" {
+		t.Fatalf("expected Close and Sync to be sent, got %q", types)
+	}
+}
+
+func TestNextResultSet(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	rowDesc := []interface{}{int16(1), "a", int32(0), int16(0), int32(25), int16(-1), int32(-1), int16(0)}
+	dataRow := []interface{}{int16(1), int32(1), []byte("x")}
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		{'C', []interface{}{"CREATE TABLE"}},
+		{'T', rowDesc}, {'D', dataRow}, {'D', dataRow}, {'C', []interface{}{"SELECT 2"}},
+		{'C', []interface{}{"INSERT 0 1"}},
+		{'T', rowDesc}, {'D', dataRow}, {'C', []interface{}{"SELECT 1"}},
+		{'Z', []interface{}{byte('I')}},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg()}
+	r := cn.simpleQuery("...")
+
+	dest := make([]driver.Value, 1)
+	if err := r.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip the rest of the first result set.
+	if err := r.NextResultSet(); err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for r.Next(dest) == nil {
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 row in the second result set, got %d", n)
+	}
+
+	if r.HasNextResultSet() {
+		t.Fatal("expected no more result sets")
+	}
+	if err := r.NextResultSet(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost user=pqgotest password=foo sslmode=disable statement_timeout=1234 lock_timeout=5s")
+	if err != nil {
+		t.Fatalf("unable to open database connection: %v", err)
+	}
+	defer db.Close()
+
+	var timeout, lockTimeout string
+	err = db.QueryRow("SELECT current_setting('statement_timeout'), current_setting('lock_timeout')").Scan(&timeout, &lockTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if timeout != "1234ms" || lockTimeout != "5s" {
+		t.Fatalf("unexpected settings %q, %q", timeout, lockTimeout)
+	}
+}
+
+func TestEmptyQuery(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost user=pqgotest password=foo sslmode=disable")
+	if err != nil {
+		t.Fatalf("unable to open database connection: %v", err)
+	}
+	defer db.Close()
+
+	res, err := db.Exec("  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 0 {
+		t.Fatalf("expected 0 rows affected, got %d", n)
+	}
+
+	r, err := db.Query("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Next() {
+		t.Fatal("expected no rows")
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := db.Prepare("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.Exec(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParameterStatusMidSession(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	m.setHead('S')
+	m.write("TimeZone", "Europe/Paris")
+	m.writeTo(&buf)
+	m.setHead('C')
+	m.write("SET")
+	m.writeTo(&buf)
+	m.setHead('Z')
+	m.write(byte('I'))
+	m.writeTo(&buf)
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg()}
+	if _, err := cn.Exec("SET TimeZone = 'Europe/Paris'", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if tz := cn.ParameterStatus("TimeZone"); tz != "Europe/Paris" {
+		t.Fatalf("expected TimeZone to be tracked, got %q", tz)
+	}
+}
+
+func TestResyncAfterError(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		{'T', []interface{}{int16(1), "a", int32(0), int16(0), int32(25), int16(-1), int32(-1), int16(0)}},
+		{'D', []interface{}{int16(1), int32(1), []byte("x")}},
+		{'E', []interface{}{byte('S'), "ERROR", byte('M'), "division by zero", byte(0)}},
+		{'Z', []interface{}{byte('I')}},
+		// The next query
+		{'C', []interface{}{"SELECT 1"}},
+		{'Z', []interface{}{byte('I')}},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg()}
+	r := cn.simpleQuery("SELECT 1/(2-x) FROM ...")
+
+	dest := make([]driver.Value, 1)
+	if err := r.Next(dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Next(dest).(*Error); !ok {
+		t.Fatal("expected a server error")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cn.Exec("SELECT 1", nil); err != nil {
+		t.Fatalf("expected the connection to be usable after the error, got %v", err)
+	}
+}
+
+// deadConn is a connection the server has gone from: writes fail.
+type deadConn struct {
+	replayConn
+}
+
+func (c *deadConn) Write(p []byte) (int, error) {
+	return 0, &net.OpError{Op: "write", Net: "tcp", Err: errors.New("broken pipe")}
+}
+
+func TestErrBadConn(t *testing.T) {
+	conn := &deadConn{replayConn{r: bytes.NewReader(nil)}}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	if _, err := cn.Exec("SELECT 1", nil); err != driver.ErrBadConn {
+		t.Fatalf("expected driver.ErrBadConn, got %v", err)
+	}
+	if _, err := cn.Query("SELECT 1", nil); err != driver.ErrBadConn {
+		t.Fatalf("expected driver.ErrBadConn, got %v", err)
+	}
+}
+
+func TestErrAfterSend(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(nil)}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	// The INSERT went out: it may have run, so it must not be retried.
+	if _, err := cn.Exec("INSERT INTO t VALUES (1)", nil); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if !cn.bad {
+		t.Fatal("expected the connection to be marked bad")
+	}
+
+	conn.w.Reset()
+	if _, err := cn.Query("SELECT 1", nil); err != driver.ErrBadConn {
+		t.Fatalf("expected driver.ErrBadConn, got %v", err)
+	}
+	if conn.w.Len() != 0 {
+		t.Fatal("expected nothing to be sent on a bad connection")
+	}
+}
+
+func TestFatalErrorMarksBad(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	m.setHead('E')
+	m.write(byte('S'), "FATAL", byte('M'), "terminating connection due to administrator command", byte(0))
+	m.writeTo(&buf)
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg()}
+	_, err := cn.Exec("SELECT 1", nil)
+	if e, ok := err.(*Error); !ok || e.Severity != "FATAL" {
+		t.Fatalf("expected the FATAL error, got %v", err)
+	}
+	if !cn.bad {
+		t.Fatal("expected the connection to be marked bad")
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(nil)}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	// Another call is running.
+	cn.enter()
+
+	if _, err := cn.Exec("SELECT 1", nil); err != errConnBusy {
+		t.Fatalf("expected errConnBusy, got %v", err)
+	}
+	if conn.w.Len() != 0 {
+		t.Fatal("expected nothing to be sent by the overlapping call")
+	}
+	if !cn.bad {
+		t.Fatal("expected the connection to be marked bad")
+	}
+
+	cn.leave()
+	if _, err := cn.Exec("SELECT 1", nil); err != driver.ErrBadConn {
+		t.Fatalf("expected driver.ErrBadConn once the first call is over, got %v", err)
+	}
+}
+
+func TestRowsNotClosed(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(nil)}
+	// The rows of the last query are still arriving.
+	cn := &Conn{c: conn, msg: newMsg(), state: stateExtendedQuery}
+
+	if _, err := cn.Exec("SELECT 1", nil); err != errRowsOpen {
+		t.Fatalf("expected errRowsOpen, got %v", err)
+	}
+	if _, err := cn.Prepare("SELECT 1"); err != errRowsOpen {
+		t.Fatalf("expected errRowsOpen, got %v", err)
+	}
+	if conn.w.Len() != 0 {
+		t.Fatal("expected nothing to be sent")
+	}
+	if cn.bad {
+		t.Fatal("expected the connection to stay usable")
+	}
+}
+
+func TestPing(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	m.setHead('I')
+	m.writeTo(&buf)
+	m.setHead('Z')
+	m.write(byte('I'))
+	m.writeTo(&buf)
+
+	conn := &replayConn{r: bytes.NewReader(buf.Bytes())}
+	cn := &Conn{c: conn, msg: newMsg()}
+	if err := cn.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cn.Ping(context.Background()); err != driver.ErrBadConn {
+		t.Fatalf("expected driver.ErrBadConn from a dead connection, got %v", err)
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(nil)}, msg: newMsg()}
+	if !cn.IsValid() {
+		t.Fatal("expected a fresh connection to be valid")
+	}
+
+	if _, err := cn.Exec("SELECT 1", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if cn.IsValid() {
+		t.Fatal("expected a broken connection to be invalid")
+	}
+}
+
+type valuer struct{ v driver.Value }
+
+func (v *valuer) Value() (driver.Value, error) { return v.v, nil }
+
+func TestCheckNamedValue(t *testing.T) {
+	type myInt int
+	var nilValuer *valuer
+	var nilInt *int
+	var nilDuration *time.Duration
+	n, d, str := 5, time.Second, "w"
+	ptrToPtr := &str
+
+	tests := []struct {
+		in       interface{}
+		expected interface{}
+	}{
+		{int(1), int(1)},
+		{uint16(2), uint16(2)},
+		{"x", "x"},
+		{&valuer{"y"}, "y"},
+		{nilValuer, nil},
+		{sql.NullInt64{Int64: 3, Valid: true}, int64(3)},
+		{myInt(4), int64(4)},
+		{Typed(OidText, &valuer{"z"}), Typed(OidText, "z")},
+		{&n, int(5)},
+		{&d, "00:00:01"},
+		{&ptrToPtr, "w"},
+		{nilInt, nil},
+		{nilDuration, nil},
+		{Typed(OidInt4, nilInt), Typed(OidInt4, nil)},
+	}
+
+	cn := &Conn{}
+	for _, test := range tests {
+		nv := &driver.NamedValue{Ordinal: 1, Value: test.in}
+		if err := cn.CheckNamedValue(nv); err != nil {
+			t.Fatalf("%T: %v", test.in, err)
+		}
+		if nv.Value != test.expected {
+			t.Errorf("%T: expected %#v, got %#v", test.in, test.expected, nv.Value)
+		}
+	}
+
+	nv := &driver.NamedValue{Ordinal: 2, Value: struct{}{}}
+	err := cn.CheckNamedValue(nv)
+	if err == nil || !strings.Contains(err.Error(), "$2") {
+		t.Fatalf("expected an error naming the argument, got %v", err)
+	}
+	if e, ok := err.(*ErrUnsupportedParameterType); !ok || e.Index != 2 || e.Type != reflect.TypeOf(struct{}{}) {
+		t.Fatalf("expected an ErrUnsupportedParameterType, got %#v", err)
+	}
+}
+
+func TestTypedNullParam(t *testing.T) {
+	db, err := sql.Open("postgres", "sslmode=disable user=pqgotest password=foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var isNull bool
+	err = db.QueryRow("SELECT $1 IS NULL", Typed(OidText, nil)).Scan(&isNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isNull {
+		t.Fatal("expected the parameter to be NULL")
+	}
+}
+
+func TestQueryNoData(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		{'1', nil},
+		{'t', []interface{}{int16(1), int32(23)}},
+		{'n', nil},
+		{'2', nil},
+		{'C', []interface{}{"INSERT 0 1"}},
+		{'Z', []interface{}{byte('I')}},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg()}
+	r, err := cn.Query("INSERT INTO t VALUES ($1)", []driver.Value{int64(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Columns()) != 0 {
+		t.Fatalf("expected no columns, got %v", r.Columns())
+	}
+	if err := r.Next(nil); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if cn.status != 'I' {
+		t.Fatal("expected to have read up to ReadyForQuery")
+	}
+}
+
+func TestInsertWithoutReturning(t *testing.T) {
+	db, err := sql.Open("postgres", "host=localhost user=pqgotest password=foo sslmode=disable")
+	if err != nil {
+		t.Fatalf("unable to open database connection: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("CREATE TEMP TABLE pqgotest_nodata (a int)"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := tx.Query("INSERT INTO pqgotest_nodata VALUES ($1)", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	s, err := tx.Prepare("INSERT INTO pqgotest_nodata VALUES ($1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Exec(2); err != nil {
+		t.Fatal(err)
+	}
+}
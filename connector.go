@@ -0,0 +1,59 @@
+package pq
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync/atomic"
+)
+
+// Connector is a driver.Connector for a fixed connection string. Use it with
+// sql.OpenDB when connections need settings that cannot be expressed in the
+// connection string, or to control the pool from outside database/sql.
+type Connector struct {
+	o       Values
+	drain   int32
+	sampler atomic.Value // *ParamSampler
+	tracer  atomic.Value // func(*QueryTrace)
+	stats   stats
+
+	noticeHandler       atomic.Value // func(*Error)
+	notificationHandler atomic.Value // func(*Notification)
+	listenChannels      atomic.Value // []string
+}
+
+// NewConnector returns a Connector for the given connection string.
+func NewConnector(name string) (*Connector, error) {
+	o, err := parseConnString(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Connector{o: o}, nil
+}
+
+// Connect implements driver.Connector.
+func (cr *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return open(ctx, cr.o, cr)
+}
+
+// Driver implements driver.Connector.
+func (cr *Connector) Driver() driver.Driver {
+	return &pgdriver{}
+}
+
+// Drain prepares for planned maintenance of the primary (first listed)
+// host. New connections try the alternate hosts first, and the
+// connections to the primary opened through cr report themselves invalid
+// so database/sql discards them instead of handing them out again.
+// Connections to the alternate hosts stay in use.
+func (cr *Connector) Drain() {
+	atomic.StoreInt32(&cr.drain, 1)
+}
+
+// Undrain reverses Drain.
+func (cr *Connector) Undrain() {
+	atomic.StoreInt32(&cr.drain, 0)
+}
+
+func (cr *Connector) draining() bool {
+	return atomic.LoadInt32(&cr.drain) != 0
+}
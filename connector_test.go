@@ -0,0 +1,62 @@
+package pq
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestDialAvoidFirst(t *testing.T) {
+	var ports []string
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		_, port, _ := net.SplitHostPort(l.Addr().String())
+		ports = append(ports, port)
+	}
+
+	o := Values{"host": "127.0.0.1,127.0.0.1", "port": ports[0] + "," + ports[1]}
+	for i, avoidFirst := range []bool{false, true} {
+		c, primary, err := dial(context.Background(), o, avoidFirst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if primary != !avoidFirst {
+			t.Errorf("avoidFirst=%v: expected primary to be %v", avoidFirst, !avoidFirst)
+		}
+		_, port, _ := net.SplitHostPort(c.RemoteAddr().String())
+		c.Close()
+		if port != ports[i] {
+			t.Errorf("avoidFirst=%v: dialed port %s, expected %s", avoidFirst, port, ports[i])
+		}
+	}
+}
+
+func TestConnectorDrain(t *testing.T) {
+	cr, err := NewConnector("host=localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cn := &Conn{connector: cr, primary: true}
+	alternate := &Conn{connector: cr}
+	if !cn.IsValid() || !alternate.IsValid() {
+		t.Fatal("expected connections to be valid")
+	}
+
+	cr.Drain()
+	if cn.IsValid() {
+		t.Fatal("expected the connection to the primary to be invalid while draining")
+	}
+	if !alternate.IsValid() {
+		t.Fatal("expected the connection to an alternate host to stay valid while draining")
+	}
+
+	cr.Undrain()
+	if !cn.IsValid() {
+		t.Fatal("expected the connection to be valid again")
+	}
+}
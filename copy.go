@@ -0,0 +1,524 @@
+package pq
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"strings"
+)
+
+// CopyIn returns the query of a COPY FROM STDIN into the columns of table,
+// or all of them if none are given, which loads rows in bulk far faster
+// than INSERTs do. Prepare it in a
+// transaction, or on a sql.Conn, so that every Exec runs on the same
+// connection; Exec the statement once per row, then once without
+// arguments to end the COPY, and Close it:
+//
+//	stmt, err := tx.Prepare(pq.CopyIn("users", "name", "age"))
+//	...
+//	for _, u := range users {
+//		if _, err := stmt.Exec(u.Name, u.Age); err != nil {
+//			...
+//		}
+//	}
+//	res, err := stmt.Exec() // res.RowsAffected is the number of rows loaded
+//	...
+//	err = stmt.Close()
+//
+// Rows are sent as they fill a buffer, and the server checks them as they
+// come, but any error it finds is only reported by the final Exec.
+// Closing the statement without it ends the COPY all the same. A row that
+// can't be encoded, or an ExecContext whose context is done, aborts the
+// COPY instead, loading none of the rows.
+func CopyIn(table string, columns ...string) string {
+	return copyInQuery(QuoteIdentifier(table), columns)
+}
+
+// CopyInSchema is CopyIn for a table of the given schema, rather than the
+// first one of the search_path that has it.
+func CopyInSchema(schema, table string, columns ...string) string {
+	return copyInQuery(QuoteIdentifier(schema)+"."+QuoteIdentifier(table), columns)
+}
+
+func copyInQuery(target string, columns []string) string {
+	if len(columns) == 0 {
+		return "COPY " + target + " FROM STDIN"
+	}
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = QuoteIdentifier(c)
+	}
+	return "COPY " + target + " (" + strings.Join(quoted, ", ") + ") FROM STDIN"
+}
+
+// isCopy reports whether q is a COPY, which Prepare runs at once rather
+// than preparing.
+func isCopy(q string) bool {
+	q = strings.TrimLeft(q, " \t\r\n")
+	return len(q) >= 4 && strings.EqualFold(q[:4], "COPY")
+}
+
+// isCopyFromStdin reports whether q is a COPY FROM STDIN, the only COPY
+// Prepare accepts.
+func isCopyFromStdin(q string) bool {
+	_, _, rest, ok := splitCopy(q)
+	f := strings.Fields(strings.ToUpper(rest))
+	return ok && len(f) >= 2 && f[0] == "FROM" && strings.TrimRight(f[1], ";") == "STDIN"
+}
+
+// copyBufSize is the amount of row data copyIn collects before sending it.
+const copyBufSize = 64 * 1024
+
+// copyIn is the statement of a COPY FROM STDIN in progress. The COPY is
+// started when it is prepared, and the connection stays busy with it until
+// it ends.
+type copyIn struct {
+	cn   *Conn
+	buf  []byte
+	done bool
+
+	// fields holds the columns of a COPY in the binary format, nil for
+	// the text format.
+	fields []fieldDesc
+}
+
+var errCopyDone = errf("COPY has already ended")
+
+// prepareCopyIn starts the COPY FROM STDIN q.
+func (cn *Conn) prepareCopyIn(q string) *copyIn {
+	ci := &copyIn{cn: cn, buf: make([]byte, 0, copyBufSize)}
+	if isBinaryCopy(q) {
+		ci.fields = cn.copyColumns(q)
+		ci.buf = append(ci.buf, binaryCopyHeader...)
+	}
+
+	cn.setHead('Q')
+	cn.write(q)
+	cn.sendMsg()
+
+	cn.recvMsg()
+	if cn.T != 'G' {
+		panic(cn.unexpected("COPY FROM STDIN"))
+	}
+	// Skip the formats of the columns, which the query chose.
+	cn.b.Reset()
+	return ci
+}
+
+// NumInput returns -1: the columns of the rows aren't counted.
+func (ci *copyIn) NumInput() int {
+	return -1
+}
+
+// Exec adds the row v to the COPY, or ends it if v is empty, returning the
+// number of rows loaded. A row that can't be encoded aborts the COPY,
+// loading nothing, and leaves the connection ready for the next command.
+func (ci *copyIn) Exec(v []driver.Value) (driver.Result, error) {
+	return ci.exec(context.Background(), v)
+}
+
+// ExecContext is Exec, aborting the COPY instead if ctx is done.
+func (ci *copyIn) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	v := make([]driver.Value, len(args))
+	for i, a := range args {
+		v[i] = a.Value
+	}
+	return ci.exec(ctx, v)
+}
+
+func (ci *copyIn) exec(ctx context.Context, v []driver.Value) (res driver.Result, err error) {
+	cn := ci.cn
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+
+	if ci.done {
+		return nil, errCopyDone
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, ci.abort(err)
+	}
+	if len(v) == 0 {
+		return ci.end(), nil
+	}
+
+	if err := ci.add(v); err != nil {
+		return nil, ci.abort(err)
+	}
+	if len(ci.buf) >= copyBufSize {
+		ci.flush()
+	}
+	return driver.RowsAffected(0), nil
+}
+
+// add appends the row v to the buffer.
+func (ci *copyIn) add(v []driver.Value) (err error) {
+	defer recoverErr(&err)
+	n := len(ci.buf)
+	defer func() {
+		if err != nil {
+			ci.buf = ci.buf[:n]
+		}
+	}()
+
+	_, v = paramTypes(v)
+	if ci.fields != nil {
+		if len(v) != len(ci.fields) {
+			return errf("got %d values for the %d columns of the COPY", len(v), len(ci.fields))
+		}
+		ci.buf = ci.cn.appendCopyBinaryRow(ci.buf, ci.fields, v)
+	} else {
+		ci.buf = appendCopyRow(ci.buf, v)
+	}
+	return nil
+}
+
+// abort ends the COPY with CopyFail, so that the server throws away the
+// rows it has been sent, and returns err, the cause.
+func (ci *copyIn) abort(err error) error {
+	ci.done = true
+	ci.buf = ci.buf[:0]
+	ci.cn.failCopyIn(err.Error())
+	return err
+}
+
+// Query refuses to run: a COPY FROM STDIN returns no rows.
+func (ci *copyIn) Query(v []driver.Value) (driver.Rows, error) {
+	return nil, errf("cannot Query a COPY FROM STDIN; use Exec")
+}
+
+// Close ends the COPY if the final Exec hasn't.
+func (ci *copyIn) Close() (err error) {
+	cn := ci.cn
+	defer cn.errRecover(&err)
+	if ci.done {
+		return nil
+	}
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+
+	ci.end()
+	return nil
+}
+
+// flush sends the rows collected so far in a CopyData message.
+func (ci *copyIn) flush() {
+	if len(ci.buf) == 0 {
+		return
+	}
+	ci.cn.setHead('d')
+	ci.cn.write(ci.buf)
+	ci.cn.sendMsg()
+	ci.buf = ci.buf[:0]
+}
+
+// end sends the rest of the rows and CopyDone, and reads the result.
+func (ci *copyIn) end() *Result {
+	ci.done = true
+	if ci.fields != nil {
+		// The trailer of the binary format.
+		ci.buf = append(ci.buf, 0xff, 0xff)
+	}
+	ci.flush()
+	ci.cn.setHead('c')
+	ci.cn.sendMsg()
+	return ci.cn.recvExec()
+}
+
+// appendCopyRow appends the row v to b in COPY's text format.
+func appendCopyRow(b []byte, v []driver.Value) []byte {
+	for i, x := range v {
+		if i > 0 {
+			b = append(b, '\t')
+		}
+		l, s := encodeParam(x)
+		if l < 0 {
+			b = append(b, `\N`...)
+			continue
+		}
+		b = appendCopyText(b, s)
+	}
+	return append(b, '\n')
+}
+
+// appendCopyText appends s to b, escaping the characters COPY's text
+// format gives a meaning to.
+func appendCopyText(b, s []byte) []byte {
+	for _, c := range s {
+		switch c {
+		case '\\':
+			b = append(b, `\\`...)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		case '\t':
+			b = append(b, `\t`...)
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// CopyOut runs q, a COPY ... TO STDOUT, and returns the data it produces
+// as a stream, read as the server sends it rather than held in memory:
+// pipe it to a file, say, with io.Copy. In the text and CSV formats each
+// row ends with a newline, so a bufio.Scanner reads the rows one by one.
+//
+// The connection is busy with the COPY until the stream is read to EOF or
+// closed; closing it early still reads the rest of the data, to keep the
+// connection usable. Reach CopyOut from database/sql through
+// sql.Conn.Raw.
+func (cn *Conn) CopyOut(q string) (r io.ReadCloser, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	return cn.startCopyOut(q), nil
+}
+
+// startCopyOut runs the COPY TO STDOUT q.
+func (cn *Conn) startCopyOut(q string) *copyOut {
+	cn.setHead('Q')
+	cn.write(q)
+	cn.sendMsg()
+
+	cn.recvMsg()
+	if cn.T != 'H' {
+		panic(cn.unexpected("COPY TO STDOUT"))
+	}
+	// Skip the formats of the columns: the data is passed on as is.
+	cn.b.Reset()
+	return &copyOut{cn: cn}
+}
+
+// copyOut is the stream of a COPY TO STDOUT.
+type copyOut struct {
+	cn *Conn
+
+	// data is the unread part of the current CopyData message, which
+	// lives in the message buffer of the connection.
+	data []byte
+
+	// err is what reads return once the COPY is over: io.EOF, or the
+	// error it failed with.
+	err error
+
+	// rows is the number of rows the COPY produced, known at its end.
+	rows int64
+}
+
+func (co *copyOut) Read(p []byte) (n int, err error) {
+	if co.err != nil {
+		return 0, co.err
+	}
+	defer func() {
+		if err != nil {
+			co.err = err
+		}
+	}()
+	defer co.cn.errRecover(&err)
+	co.cn.enter()
+	defer co.cn.leave()
+
+	if n = co.read(p); n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// read is Read for callers already using the connection. It returns 0
+// once the COPY is over, and panics with the errors it fails with.
+func (co *copyOut) read(p []byte) int {
+	for len(co.data) == 0 {
+		if !co.recv() {
+			return 0
+		}
+	}
+	n := copy(p, co.data)
+	co.data = co.data[n:]
+	return n
+}
+
+// Close reads what is left of the COPY.
+func (co *copyOut) Close() (err error) {
+	if co.err != nil {
+		return nil
+	}
+	defer func() {
+		if err == nil {
+			co.err = io.EOF
+		}
+	}()
+	defer co.cn.errRecover(&err)
+	co.cn.enter()
+	defer co.cn.leave()
+
+	for co.recv() {
+	}
+	return nil
+}
+
+// recv reads the next message of the COPY into data, reporting false once
+// ReadyForQuery ends it.
+func (co *copyOut) recv() bool {
+	cn := co.cn
+	cn.recvMsg()
+	switch cn.T {
+	case 'd':
+		co.data = cn.b.Bytes()
+	case 'c':
+		// CopyDone.
+	case 'C':
+		co.rows = parseCommandTag(cn.readCString()).rowsAffected
+	case 'Z':
+		cn.read(&cn.status)
+		return false
+	default:
+		panic(cn.unexpected("COPY TO STDOUT"))
+	}
+	return true
+}
+
+// CopyFrom runs q, a COPY ... FROM STDIN, sending the data read from r as
+// is: it must already be in the format q names, such as the text format
+// or CSV, as a file written by COPY TO or psql's \copy is. It returns the
+// number of rows loaded.
+//
+// If reading r fails, the COPY is aborted, loading nothing, and CopyFrom
+// returns the error. Reach CopyFrom from database/sql through
+// sql.Conn.Raw.
+func (cn *Conn) CopyFrom(q string, r io.Reader) (int64, error) {
+	return cn.CopyFromContext(context.Background(), q, r)
+}
+
+// CopyFromContext is CopyFrom, aborting the COPY if ctx is done before r
+// is read to the end. The connection stays usable either way.
+func (cn *Conn) CopyFromContext(ctx context.Context, q string, r io.Reader) (rows int64, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	cn.setHead('Q')
+	cn.write(q)
+	cn.sendMsg()
+
+	cn.recvMsg()
+	if cn.T != 'G' {
+		panic(cn.unexpected("COPY FROM STDIN"))
+	}
+	cn.b.Reset()
+
+	buf := make([]byte, copyBufSize)
+	for {
+		if cerr := ctx.Err(); cerr != nil {
+			cn.failCopyIn(cerr.Error())
+			return 0, cerr
+		}
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			cn.setHead('d')
+			cn.write(buf[:n])
+			cn.sendMsg()
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			cn.failCopyIn(rerr.Error())
+			return 0, rerr
+		}
+	}
+
+	cn.setHead('c')
+	cn.sendMsg()
+	return cn.recvExec().rowsAffected, nil
+}
+
+// failCopyIn aborts the COPY FROM STDIN in progress with CopyFail, giving
+// reason as the cause, and reads the error the server ends it with.
+func (cn *Conn) failCopyIn(reason string) {
+	cn.setHead('f')
+	cn.write(reason)
+	cn.sendMsg()
+
+	defer func() {
+		x := recover()
+		if _, ok := x.(*Error); !ok && x != nil {
+			panic(x)
+		}
+	}()
+	cn.recvExec()
+}
+
+// CopyTo runs q, a COPY ... TO STDOUT, writing the data it produces to w
+// as is, and returns the number of rows written. If writing to w fails,
+// the rest of the data is read and thrown away, and CopyTo returns the
+// error. Reach CopyTo from database/sql through sql.Conn.Raw.
+func (cn *Conn) CopyTo(q string, w io.Writer) (int64, error) {
+	return cn.CopyToContext(context.Background(), q, w)
+}
+
+// CopyToContext is CopyTo, stopping if ctx is done: the COPY is then
+// cancelled with a CancelRequest, and what the server sent before it
+// stopped is thrown away, or the connection is closed if the request
+// can't be sent. CopyToContext returns ctx's error.
+func (cn *Conn) CopyToContext(ctx context.Context, q string, w io.Writer) (rows int64, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	co := cn.startCopyOut(q)
+	for co.recv() {
+		if len(co.data) == 0 {
+			continue
+		}
+		werr := ctx.Err()
+		if werr == nil {
+			_, werr = w.Write(co.data)
+		}
+		co.data = nil
+		if werr != nil {
+			if werr == ctx.Err() {
+				cn.cancelCopyOut(co)
+				return 0, werr
+			}
+			for co.recv() {
+			}
+			return 0, werr
+		}
+	}
+	return co.rows, nil
+}
+
+// cancelCopyOut stops the COPY TO STDOUT co with a CancelRequest and reads
+// up to the error ending it, or closes the connection if the request
+// fails, instead of receiving all the data.
+func (cn *Conn) cancelCopyOut(co *copyOut) {
+	if err := cn.cancel(); err != nil {
+		cn.bad = true
+		cn.c.Close()
+		return
+	}
+
+	defer func() {
+		x := recover()
+		if _, ok := x.(*Error); !ok && x != nil {
+			panic(x)
+		}
+	}()
+	for co.recv() {
+	}
+}
@@ -0,0 +1,624 @@
+package pq
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCopyIn(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'G', []interface{}{int8(0), int16(3), int16(0), int16(0), int16(0)}},
+		testMsg{'C', []interface{}{"COPY 2"}},
+		testMsg{'Z', []interface{}{byte('T')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	q := CopyIn("my table", "a", `b"c`, "d")
+	if q != `COPY "my table" ("a", "b""c", "d") FROM STDIN` {
+		t.Fatalf("unexpected query %q", q)
+	}
+	st, err := cn.Prepare(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.NumInput() != -1 {
+		t.Fatalf("expected NumInput -1, got %d", st.NumInput())
+	}
+	if _, err := st.Exec([]driver.Value{int64(1), "x\ty\\z\n", nil}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Exec([]driver.Value{int64(2), []byte{0xff}, true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cn.Exec("SELECT 1", nil); err != errRowsOpen {
+		t.Fatalf("expected the connection to be busy with the COPY, got %v", err)
+	}
+	res, err := st.Exec(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Fatalf("expected 2 rows, got %d", n)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Exec([]driver.Value{int64(3)}); err != errCopyDone {
+		t.Fatalf("expected errCopyDone, got %v", err)
+	}
+
+	if types := sentTypes(t, conn.w.Bytes()); types != "Qdc" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+	expected := "1\tx\\ty\\\\z\\n\t\\N\n2\t\\\\xff\ttrue\n"
+	if !strings.Contains(conn.w.String(), expected) {
+		t.Fatalf("expected the rows %q to be sent, sent %q", expected, conn.w.String())
+	}
+}
+
+func TestCopyInDirectExec(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'G', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'E', []interface{}{byte('S'), "ERROR", byte('C'), "57014", byte('M'), "COPY from stdin failed", byte(0)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	_, err := cn.Exec("COPY t FROM STDIN", nil)
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("expected the server's error, got %v", err)
+	}
+	if types := sentTypes(t, conn.w.Bytes()); types != "Qf" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+	if cn.bad || cn.state != stateReady {
+		t.Fatal("expected the connection to stay usable")
+	}
+}
+
+func TestCopyInSchema(t *testing.T) {
+	q := CopyInSchema("Sales 2024", "orders", "id", "total")
+	if q != `COPY "Sales 2024"."orders" ("id", "total") FROM STDIN` {
+		t.Fatalf("unexpected query %q", q)
+	}
+	if q := CopyInSchema(`a"b`, "t"); q != `COPY "a""b"."t" FROM STDIN` {
+		t.Fatalf("unexpected query %q", q)
+	}
+}
+
+func TestCopyOut(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'H', []interface{}{int8(0), int16(2), int16(0), int16(0)}},
+		testMsg{'d', []interface{}{[]byte("1\ta\n")}},
+		testMsg{'d', []interface{}{[]byte("2\t\\N\n")}},
+		testMsg{'c', nil},
+		testMsg{'C', []interface{}{"COPY 2"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	r, err := cn.CopyOut("COPY t TO STDOUT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	p := make([]byte, 3)
+	for {
+		n, err := r.Read(p)
+		out.Write(p[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.String() != "1\ta\n2\t\\N\n" {
+		t.Fatalf("unexpected data %q", out.String())
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if cn.state != stateReady || cn.status != 'I' {
+		t.Fatal("expected to have read up to ReadyForQuery")
+	}
+}
+
+func TestCopyOutCloseEarly(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'H', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'d', []interface{}{[]byte("1\n")}},
+		testMsg{'d', []interface{}{[]byte("2\n")}},
+		testMsg{'c', nil},
+		testMsg{'C', []interface{}{"COPY 2"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	r, err := cn.CopyOut("COPY t TO STDOUT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if cn.state != stateReady {
+		t.Fatal("expected the rest of the COPY to be read")
+	}
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected EOF after Close, got %v", err)
+	}
+}
+
+func TestCopyOutError(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'H', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'d', []interface{}{[]byte("1\n")}},
+		testMsg{'E', []interface{}{byte('S'), "ERROR", byte('M'), "division by zero", byte(0)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	r, err := cn.CopyOut("COPY (SELECT 1/x FROM t) TO STDOUT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ioutil.ReadAll(r)
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("expected the server's error, got %v", err)
+	}
+	if _, err2 := r.Read(make([]byte, 1)); err2 != err {
+		t.Fatalf("expected the error again, got %v", err2)
+	}
+	if cn.bad || cn.state != stateReady {
+		t.Fatal("expected the connection to stay usable")
+	}
+}
+
+func TestExecCopyOut(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'H', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'d', []interface{}{[]byte("1\n")}},
+		testMsg{'c', nil},
+		testMsg{'C', []interface{}{"COPY 1"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	res, err := cn.Exec("COPY t TO STDOUT", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("expected 1 row, got %d", n)
+	}
+}
+
+// describeMsgs returns the replies to the Describe of a statement
+// returning columns of the types typs.
+func describeMsgs(typs ...Oid) []testMsg {
+	fields := []interface{}{int16(len(typs))}
+	for i, typ := range typs {
+		fields = append(fields, string(rune('a'+i)), Oid(0), int16(0), typ, int16(-1), int32(-1), int16(0))
+	}
+	return []testMsg{
+		{'1', nil},
+		{'t', []interface{}{int16(0)}},
+		{'T', fields},
+		{'Z', []interface{}{byte('I')}},
+	}
+}
+
+func TestCopyInBinary(t *testing.T) {
+	msgs := append(describeMsgs(OidInt4, OidText, OidJSONB, OidBool),
+		testMsg{'G', []interface{}{int8(1), int16(4), int16(1), int16(1), int16(1), int16(1)}},
+		testMsg{'C', []interface{}{"COPY 1"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	)
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(msgs...))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	q := CopyInBinary("t", "a", "b", "c", "d")
+	if q != `COPY "t" ("a", "b", "c", "d") FROM STDIN WITH (FORMAT binary)` {
+		t.Fatalf("unexpected query %q", q)
+	}
+	st, err := cn.Prepare(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Exec([]driver.Value{int64(7), "x", `{}`, nil}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Exec(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if types := sentTypes(t, conn.w.Bytes()); types != "PDSQdc" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+	expected := string(binaryCopyHeader) +
+		"\x00\x04" +
+		"\x00\x00\x00\x04\x00\x00\x00\x07" +
+		"\x00\x00\x00\x01x" +
+		"\x00\x00\x00\x03\x01{}" +
+		"\xff\xff\xff\xff" +
+		"\xff\xff"
+	if !strings.Contains(conn.w.String(), expected) {
+		t.Fatalf("expected the data %q to be sent, sent %q", expected, conn.w.String())
+	}
+	if !strings.Contains(conn.w.String(), `SELECT "a", "b", "c", "d" FROM "t"`) {
+		t.Fatalf("expected the columns to be described, sent %q", conn.w.String())
+	}
+}
+
+func TestCopyInBinaryAbort(t *testing.T) {
+	for _, row := range [][]driver.Value{
+		{int64(1), "1.5"},
+		{int64(1)},
+	} {
+		msgs := append(describeMsgs(OidInt4, OidNumeric),
+			testMsg{'G', []interface{}{int8(1), int16(2), int16(1), int16(1)}},
+			testMsg{'E', []interface{}{byte('S'), "ERROR", byte('C'), "57014", byte('M'), "COPY from stdin failed", byte(0)}},
+			testMsg{'Z', []interface{}{byte('I')}},
+		)
+		conn := &replayConn{r: bytes.NewReader(serverMsgs(msgs...))}
+		cn := &Conn{c: conn, msg: newMsg()}
+
+		st, err := cn.Prepare(CopyInBinary("t", "i", "n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := st.Exec(row); err == nil {
+			t.Fatalf("%v: expected an error", row)
+		}
+		if cn.bad || cn.state != stateReady {
+			t.Fatalf("%v: expected the COPY to be aborted, leaving the connection usable", row)
+		}
+		if _, err := st.Exec([]driver.Value{int64(1), nil}); err != errCopyDone {
+			t.Fatalf("%v: expected errCopyDone, got %v", row, err)
+		}
+		if err := st.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if types := sentTypes(t, conn.w.Bytes()); types != "PDSQf" {
+			t.Fatalf("%v: unexpected messages sent: %q", row, types)
+		}
+	}
+}
+
+func TestCopyInCanceled(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'G', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'E', []interface{}{byte('S'), "ERROR", byte('C'), "57014", byte('M'), "COPY from stdin failed: context canceled", byte(0)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	st, err := cn.Prepare(CopyIn("t", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := st.(driver.StmtExecContext)
+	if _, err := ci.ExecContext(context.Background(), []driver.NamedValue{{Ordinal: 1, Value: int64(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ci.ExecContext(ctx, nil); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if cn.bad || cn.state != stateReady {
+		t.Fatal("expected the connection to stay usable")
+	}
+	if types := sentTypes(t, conn.w.Bytes()); types != "Qf" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+}
+
+func TestCopySelect(t *testing.T) {
+	for _, tt := range []struct {
+		q, sel string
+	}{
+		{`COPY t FROM STDIN`, `SELECT * FROM t`},
+		{`copy "my (t)" ("a", b) FROM STDIN WITH (FORMAT binary)`, `SELECT "a", b FROM "my (t)"`},
+		{`COPY s.t(a) TO STDOUT (FORMAT binary)`, `SELECT a FROM s.t`},
+		{`COPY (SELECT ')' AS x, f(1)) TO STDOUT WITH (FORMAT binary)`, `SELECT ')' AS x, f(1)`},
+	} {
+		sel, ok := copySelect(tt.q)
+		if !ok || sel != tt.sel {
+			t.Errorf("%q: expected %q, got %q, %v", tt.q, tt.sel, sel, ok)
+		}
+	}
+	if !isBinaryCopy(`COPY t TO STDOUT WITH (FORMAT BINARY)`) || isBinaryCopy(`COPY binary_t FROM STDIN`) {
+		t.Error("isBinaryCopy got the format wrong")
+	}
+}
+
+func TestPrepareCopyNotFromStdin(t *testing.T) {
+	for _, q := range []string{
+		`COPY t FROM STDIN`,
+		`copy "from" (a) from stdin;`,
+		`COPY t FROM STDIN WITH (FORMAT binary)`,
+	} {
+		if !isCopyFromStdin(q) {
+			t.Errorf("%q: expected a COPY FROM STDIN", q)
+		}
+	}
+
+	for _, q := range []string{
+		`COPY t TO STDOUT`,
+		`COPY (SELECT 'FROM STDIN') TO STDOUT`,
+		`COPY t FROM '/tmp/stdin'`,
+		`COPY t FROM PROGRAM 'cat stdin'`,
+	} {
+		cn := &Conn{c: &replayConn{r: bytes.NewReader(nil)}, msg: newMsg()}
+		if _, err := cn.Prepare(q); err == nil || !strings.Contains(err.Error(), "only COPY FROM STDIN") {
+			t.Errorf("%q: expected an error, got %v", q, err)
+		}
+		if cn.c.(*replayConn).w.Len() != 0 {
+			t.Errorf("%q: expected nothing to be sent", q)
+		}
+	}
+}
+
+func TestCopyOutBinary(t *testing.T) {
+	var data bytes.Buffer
+	data.Write(binaryCopyHeader)
+	data.Write([]byte("\x00\x03" +
+		"\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00\x00\x2a" +
+		"\x00\x00\x00\x02hi" +
+		"\xff\xff\xff\xff"))
+	data.Write([]byte("\x00\x03" +
+		"\x00\x00\x00\x08\xff\xff\xff\xff\xff\xff\xff\xff" +
+		"\xff\xff\xff\xff" +
+		"\x00\x00\x00\x01\x01"))
+	data.Write([]byte("\xff\xff"))
+	b := data.Bytes()
+
+	msgs := append(describeMsgs(OidInt8, OidText, OidBool),
+		testMsg{'H', []interface{}{int8(1), int16(3), int16(1), int16(1), int16(1)}},
+		// Split across messages, as the server may.
+		testMsg{'d', []interface{}{b[:10]}},
+		testMsg{'d', []interface{}{b[10:]}},
+		testMsg{'c', nil},
+		testMsg{'C', []interface{}{"COPY 2"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	)
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(msgs...))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	var got [][]driver.Value
+	err := cn.CopyOutBinary("COPY t TO STDOUT WITH (FORMAT binary)", func(row []driver.Value) error {
+		got = append(got, append([]driver.Value(nil), row...))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]driver.Value{{int64(42), "hi", nil}, {int64(-1), nil, true}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	if cn.state != stateReady {
+		t.Fatal("expected to have read up to ReadyForQuery")
+	}
+}
+
+func TestCopyOutBinaryStop(t *testing.T) {
+	var data bytes.Buffer
+	data.Write(binaryCopyHeader)
+	for i := 0; i < 2; i++ {
+		data.Write([]byte("\x00\x01\x00\x00\x00\x01\x01"))
+	}
+	data.Write([]byte("\xff\xff"))
+
+	msgs := append(describeMsgs(OidBool),
+		testMsg{'H', []interface{}{int8(1), int16(1), int16(1)}},
+		testMsg{'d', []interface{}{data.Bytes()}},
+		testMsg{'c', nil},
+		testMsg{'C', []interface{}{"COPY 2"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	)
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(msgs...))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	stop := errors.New("stop")
+	n := 0
+	err := cn.CopyOutBinary("COPY t TO STDOUT (FORMAT binary)", func(row []driver.Value) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Fatalf("expected to stop after a row, got %v after %d", err, n)
+	}
+	if cn.bad || cn.state != stateReady {
+		t.Fatal("expected the connection to stay usable")
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'G', []interface{}{int8(0), int16(2), int16(0), int16(0)}},
+		testMsg{'C', []interface{}{"COPY 2"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	data := "1,a\n2,\"b,c\"\n"
+	n, err := cn.CopyFrom("COPY t FROM STDIN (FORMAT csv)", strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 rows, got %d", n)
+	}
+	if types := sentTypes(t, conn.w.Bytes()); types != "Qdc" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+	if !strings.Contains(conn.w.String(), data) {
+		t.Fatalf("expected the data to be sent as is, sent %q", conn.w.String())
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestCopyFromReadError(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'G', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'E', []interface{}{byte('S'), "ERROR", byte('C'), "57014", byte('M'), "COPY from stdin failed: disk on fire", byte(0)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	fail := errors.New("disk on fire")
+	r := io.MultiReader(strings.NewReader("1\n"), errReader{fail})
+	if _, err := cn.CopyFrom("COPY t FROM STDIN", r); err != fail {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+	if types := sentTypes(t, conn.w.Bytes()); types != "Qdf" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+	if cn.bad || cn.state != stateReady {
+		t.Fatal("expected the connection to stay usable")
+	}
+}
+
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestCopyTo(t *testing.T) {
+	msgs := []testMsg{
+		{'H', []interface{}{int8(0), int16(1), int16(0)}},
+		{'d', []interface{}{[]byte("1\n")}},
+		{'d', []interface{}{[]byte("2\n")}},
+		{'c', nil},
+		{'C', []interface{}{"COPY 2"}},
+		{'Z', []interface{}{byte('I')}},
+	}
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(serverMsgs(msgs...))}, msg: newMsg()}
+
+	var out bytes.Buffer
+	n, err := cn.CopyTo("COPY t TO STDOUT", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || out.String() != "1\n2\n" {
+		t.Fatalf("unexpected result: %d rows, %q", n, out.String())
+	}
+
+	cn = &Conn{c: &replayConn{r: bytes.NewReader(serverMsgs(msgs...))}, msg: newMsg()}
+	fail := errors.New("disk full")
+	if _, err := cn.CopyTo("COPY t TO STDOUT", errWriter{fail}); err != fail {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+	if cn.bad || cn.state != stateReady {
+		t.Fatal("expected the rest of the COPY to be read")
+	}
+}
+
+func TestCopyFromCanceled(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'G', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'E', []interface{}{byte('S'), "ERROR", byte('C'), "57014", byte('M'), "COPY from stdin failed: context canceled", byte(0)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cn.CopyFromContext(ctx, "COPY t FROM STDIN", strings.NewReader("1\n")); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if types := sentTypes(t, conn.w.Bytes()); types != "Qf" {
+		t.Fatalf("unexpected messages sent: %q", types)
+	}
+	if cn.bad || cn.state != stateReady {
+		t.Fatal("expected the connection to stay usable")
+	}
+}
+
+// cancelServer returns a dialCancel for a Conn, and a channel receiving
+// the CancelRequests made through it.
+func cancelServer(t *testing.T) (func() (net.Conn, error), chan []byte) {
+	requests := make(chan []byte, 1)
+	return func() (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			b := make([]byte, 16)
+			if _, err := io.ReadFull(server, b); err != nil {
+				t.Error(err)
+			}
+			requests <- b
+		}()
+		return client, nil
+	}, requests
+}
+
+func TestCopyToCanceled(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'H', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'d', []interface{}{[]byte("1\n")}},
+		testMsg{'E', []interface{}{byte('S'), "ERROR", byte('C'), "57014", byte('M'), "canceling statement due to user request", byte(0)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	dialCancel, requests := cancelServer(t)
+	cn := &Conn{c: conn, msg: newMsg(), pid: 7, key: 42, dialCancel: dialCancel}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	if _, err := cn.CopyToContext(ctx, "COPY t TO STDOUT", &out); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected nothing to be written, got %q", out.String())
+	}
+	if cn.bad || cn.state != stateReady {
+		t.Fatal("expected the connection to stay usable")
+	}
+
+	expected := []byte{0, 0, 0, 16, 0x04, 0xd2, 0x16, 0x2e, 0, 0, 0, 7, 0, 0, 0, 42}
+	if b := <-requests; !bytes.Equal(b, expected) {
+		t.Fatalf("unexpected CancelRequest %v", b)
+	}
+}
+
+func TestCopyToCanceledNoCancel(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'H', []interface{}{int8(0), int16(1), int16(0)}},
+		testMsg{'d', []interface{}{[]byte("1\n")}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cn.CopyToContext(ctx, "COPY t TO STDOUT", ioutil.Discard); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !cn.bad {
+		t.Fatal("expected the connection to be closed")
+	}
+}
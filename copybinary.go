@@ -0,0 +1,310 @@
+package pq
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+// binaryCopyHeader starts the data of a COPY in the binary format: the
+// signature, no flags and no header extension.
+var binaryCopyHeader = []byte("PGCOPY\n\377\r\n\000\000\000\000\000\000\000\000\000")
+
+// CopyInBinary is CopyIn in COPY's binary format, which spares the server
+// parsing the text of every value. The values are encoded for the types
+// of the columns, which the statement looks up when it is prepared:
+// integers, floats, bools, bytea, uuid, date, time and timestamp columns
+// take the Go types the driver returns for them, text-like columns
+// (text, varchar, char(n), name, json, jsonb, enums) take strings. Other
+// types, numeric among them, have no binary encoding here and fail the
+// Exec; use CopyIn for them.
+func CopyInBinary(table string, columns ...string) string {
+	return CopyIn(table, columns...) + " WITH (FORMAT binary)"
+}
+
+// isBinaryCopy reports whether the COPY q uses the binary format, which
+// only appears in its options, after STDIN or STDOUT.
+func isBinaryCopy(q string) bool {
+	u := strings.ToUpper(q)
+	i := strings.LastIndex(u, "STDIN")
+	if j := strings.LastIndex(u, "STDOUT"); j > i {
+		i = j
+	}
+	return i >= 0 && strings.Contains(u[i:], "BINARY")
+}
+
+// copyColumns returns the columns of the COPY q, as the SELECT of the
+// same columns describes them.
+func (cn *Conn) copyColumns(q string) []fieldDesc {
+	sel, ok := copySelect(q)
+	if !ok {
+		panic(errf("cannot find the columns of %q", q))
+	}
+
+	cn.parse("", sel, nil)
+	cn.describe("")
+	cn.sync()
+
+	cn.recvParseComplete()
+	st := &stmt{Conn: cn}
+	st.recvDescribe()
+
+	cn.recvMsg()
+	if cn.T != 'Z' {
+		panic(cn.unexpected("parse"))
+	}
+	cn.read(&cn.status)
+	return st.fields
+}
+
+// copySelect returns a query with the columns of the COPY q: the query of
+// a COPY (query) TO, or else a SELECT of the columns of its table.
+func copySelect(q string) (string, bool) {
+	target, columns, _, ok := splitCopy(q)
+	if !ok {
+		return "", false
+	}
+	if strings.HasPrefix(target, "(") {
+		return target[1 : len(target)-1], true
+	}
+	if columns == "" {
+		columns = "*"
+	}
+	return "SELECT " + columns + " FROM " + target, true
+}
+
+// splitCopy splits the COPY q into its table or parenthesized query, the
+// list of columns if any, and the rest: FROM or TO, the source or
+// destination and the options.
+func splitCopy(q string) (target, columns, rest string, ok bool) {
+	q = strings.TrimSpace(q)
+	if !isCopy(q) {
+		return "", "", "", false
+	}
+	q = strings.TrimSpace(q[len("COPY"):])
+
+	if strings.HasPrefix(q, "(") {
+		end, ok := closingParen(q)
+		if !ok {
+			return "", "", "", false
+		}
+		return q[:end+1], "", q[end+1:], true
+	}
+
+	// The table, and the list of its columns if any, end at FROM or TO.
+	for i := 0; i < len(q); i++ {
+		switch c := q[i]; c {
+		case '"':
+			i = skipQuoted(q, i, '"', false) - 1
+		case '(':
+			end, ok := closingParen(q[i:])
+			if !ok {
+				return "", "", "", false
+			}
+			target, columns, rest = q[:i], q[i+1:i+end], q[i+end+1:]
+			i = len(q)
+		case ' ', '\t', '\r', '\n':
+			if strings.HasPrefix(strings.TrimLeft(q[i:], " \t\r\n"), "(") {
+				continue
+			}
+			target, rest = q[:i], q[i:]
+			i = len(q)
+		}
+	}
+	target = strings.TrimSpace(target)
+	return target, columns, rest, target != ""
+}
+
+// closingParen returns the index of the parenthesis closing the one q
+// starts with, skipping over quoted text.
+func closingParen(q string) (int, bool) {
+	depth := 0
+	for i := 0; i < len(q); i++ {
+		switch q[i] {
+		case '"', '\'':
+			i = skipQuoted(q, i, q[i], false) - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// appendCopyBinaryRow appends the row v, of the columns fields, to b in
+// COPY's binary format.
+func (cn *Conn) appendCopyBinaryRow(b []byte, fields []fieldDesc, v []driver.Value) []byte {
+	var n [4]byte
+	binary.BigEndian.PutUint16(n[:], uint16(len(v)))
+	b = append(b, n[:2]...)
+	for i, x := range v {
+		data, null := cn.encodeCopyBinary(fields[i].typ, x)
+		if null {
+			b = append(b, 0xff, 0xff, 0xff, 0xff)
+			continue
+		}
+		binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+		b = append(b, n[:]...)
+		b = append(b, data...)
+	}
+	return b
+}
+
+// encodeCopyBinary returns the binary format of v as a value of type typ,
+// or reports that v is NULL.
+func (cn *Conn) encodeCopyBinary(typ Oid, v driver.Value) ([]byte, bool) {
+	if vr, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = vr.Value(); err != nil {
+			panic(err)
+		}
+	}
+	if v == nil {
+		return nil, true
+	}
+	if b, ok := encodeBinary(typ, v); ok {
+		return b, false
+	}
+
+	// The binary format of text-like types is their text.
+	var text []byte
+	switch x := v.(type) {
+	case string:
+		text = []byte(x)
+	case []byte:
+		text = x
+	default:
+		var l int32
+		if l, text = encodeParam(v); l < 0 {
+			return nil, true
+		}
+	}
+	switch typ {
+	case OidText, OidVarchar, OidBpchar, OidName, OidJSON, OidUnknown:
+		return text, false
+	case OidJSONB:
+		// Preceded by the version of the format.
+		return append([]byte{1}, text...), false
+	}
+	if _, ok := cn.enums[typ]; ok {
+		return text, false
+	}
+	panic(errf("cannot send %T as a value of type %d in a binary COPY", v, typ))
+}
+
+// CopyOutBinary runs q, a COPY ... TO STDOUT WITH (FORMAT binary), and
+// calls fn with each row it produces, decoded as a query's would be. A row
+// is only valid until fn returns. If fn returns an error, the rest of the
+// data is read and thrown away, and CopyOutBinary returns the error.
+//
+// Values of types the driver only decodes from text, numeric among them,
+// are passed as the raw bytes of their binary format. Reach CopyOutBinary
+// from database/sql through sql.Conn.Raw.
+func (cn *Conn) CopyOutBinary(q string, fn func(row []driver.Value) error) (err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	fields := cn.copyColumns(q)
+	co := cn.startCopyOut(q)
+	r := bufio.NewReader(copyOutReader{co})
+
+	header := make([]byte, len(binaryCopyHeader))
+	if _, err := io.ReadFull(r, header); err != nil {
+		panic(errf("reading the header of a binary COPY: %v", err))
+	}
+	if !bytes.Equal(header[:11], binaryCopyHeader[:11]) {
+		panic(errf("invalid header of a binary COPY: %q", header))
+	}
+	if ext := binary.BigEndian.Uint32(header[15:]); ext > 0 {
+		if _, err := r.Discard(int(ext)); err != nil {
+			panic(errf("reading the header of a binary COPY: %v", err))
+		}
+	}
+
+	row := make([]driver.Value, len(fields))
+	for {
+		n := int16(readCopyUint(r, 2))
+		if n == -1 {
+			break
+		}
+		if int(n) != len(fields) {
+			panic(errf("got a row of %d columns in a COPY of %d", n, len(fields)))
+		}
+		for i := range row {
+			l := int32(readCopyUint(r, 4))
+			if l < 0 {
+				row[i] = nil
+				continue
+			}
+			b := make([]byte, l)
+			if _, err := io.ReadFull(r, b); err != nil {
+				panic(errf("reading a binary COPY: %v", err))
+			}
+			row[i] = cn.decodeCopyBinary(fields[i].typ, b)
+		}
+		if err := fn(row); err != nil {
+			for co.recv() {
+			}
+			return err
+		}
+	}
+	for co.recv() {
+	}
+	return nil
+}
+
+// copyOutReader reads a COPY TO STDOUT for a caller already using the
+// connection.
+type copyOutReader struct {
+	co *copyOut
+}
+
+func (r copyOutReader) Read(p []byte) (int, error) {
+	if n := r.co.read(p); n > 0 || len(p) == 0 {
+		return n, nil
+	}
+	return 0, io.EOF
+}
+
+// readCopyUint reads a big-endian integer of size bytes.
+func readCopyUint(r io.Reader, size int) uint32 {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:size]); err != nil {
+		panic(errf("reading a binary COPY: %v", err))
+	}
+	if size == 2 {
+		return uint32(binary.BigEndian.Uint16(b[:]))
+	}
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// decodeCopyBinary decodes the binary format of a value of type typ.
+func (cn *Conn) decodeCopyBinary(typ Oid, b []byte) driver.Value {
+	switch typ {
+	case OidText, OidVarchar, OidBpchar, OidName, OidJSON, OidUnknown:
+		return string(b)
+	case OidJSONB:
+		if len(b) > 0 && b[0] == 1 {
+			return string(b[1:])
+		}
+		return b
+	}
+	if _, ok := cn.composites[typ]; ok {
+		return b
+	}
+	if _, ok := cn.types[typ]; ok {
+		return b
+	}
+	return cn.decode(&fieldDesc{typ: typ, format: formatBinary}, b)
+}
@@ -0,0 +1,141 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Cursor iterates over the rows of a query through a server-side cursor,
+// fetching them a batch at a time, so that a result of any size can be
+// read in bounded memory. Make one with OpenCursor, and Close it when done.
+//
+// The Conn can't be used for anything else while the cursor is open.
+type Cursor struct {
+	cn        *Conn
+	name      string
+	fetchSize int
+
+	// began is set if the cursor opened the transaction it runs in.
+	began bool
+
+	rows    driver.Rows
+	columns []string
+	row     []driver.Value
+	fetched int // rows read from the current fetch
+
+	done, closed bool
+	err          error
+}
+
+// OpenCursor declares a cursor for q, whose rows are then fetched
+// fetchSize at a time. A cursor only lives in a transaction: if none is
+// open, OpenCursor begins one, which Close commits.
+//
+// The rows are fetched with the simple query protocol, in the text
+// format, and decoded as Query decodes them: integers come back as int64,
+// timestamps as time.Time, and so on.
+func (cn *Conn) OpenCursor(q string, fetchSize int, args ...driver.Value) (*Cursor, error) {
+	if fetchSize <= 0 {
+		return nil, errf("cursor fetch size must be positive, got %d", fetchSize)
+	}
+
+	c := &Cursor{cn: cn, fetchSize: fetchSize}
+	if cn.TxStatus() == TxIdle {
+		if _, err := cn.Exec("BEGIN", nil); err != nil {
+			return nil, err
+		}
+		c.began = true
+	}
+
+	cn.ncursor++
+	c.name = fmt.Sprintf("pq_cursor_%d", cn.ncursor)
+	if _, err := cn.Exec("DECLARE "+c.name+" NO SCROLL CURSOR FOR "+q, args); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Next advances to the next row, fetching another batch when needed. It
+// returns false once the rows are exhausted or on error; see Err.
+func (c *Cursor) Next() bool {
+	if c.done || c.closed || c.err != nil {
+		return false
+	}
+
+	for {
+		if c.rows == nil {
+			r, err := c.cn.Query("FETCH FORWARD "+strconv.Itoa(c.fetchSize)+" FROM "+c.name, nil)
+			if err != nil {
+				c.err = err
+				return false
+			}
+			c.rows, c.fetched = r, 0
+			c.columns = r.Columns()
+		}
+
+		row := make([]driver.Value, len(c.columns))
+		err := c.rows.Next(row)
+		if err == nil {
+			c.row = row
+			c.fetched++
+			return true
+		}
+
+		c.rows.Close()
+		c.rows = nil
+		c.row = nil
+		if err != io.EOF {
+			c.err = err
+			return false
+		}
+		if c.fetched < c.fetchSize {
+			c.done = true
+			return false
+		}
+	}
+}
+
+// Columns returns the names of the columns. It is nil before the first
+// call to Next.
+func (c *Cursor) Columns() []string {
+	return c.columns
+}
+
+// Values returns the current row. The slice isn't reused by later calls
+// to Next.
+func (c *Cursor) Values() []driver.Value {
+	return c.row
+}
+
+// Err returns the error that stopped Next, if any.
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Close closes the cursor. If OpenCursor began the transaction it is ended
+// too: committed, or rolled back if it failed.
+func (c *Cursor) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if c.rows != nil {
+		c.rows.Close()
+		c.rows = nil
+	}
+
+	var err error
+	switch {
+	case c.began && c.cn.TxStatus() == TxInTransaction:
+		_, err = c.cn.Exec("COMMIT", nil)
+	case c.began:
+		_, err = c.cn.Exec("ROLLBACK", nil)
+	case c.cn.TxStatus() == TxInTransaction:
+		_, err = c.cn.Exec("CLOSE "+c.name, nil)
+	}
+	return err
+}
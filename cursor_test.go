@@ -0,0 +1,47 @@
+package pq
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	cn, err := Open("host=localhost user=pqgotest password=foo sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cn.Close()
+
+	c, err := cn.OpenCursor("SELECT generate_series(1, 10)", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for c.Next() {
+		n++
+		if v := string(c.Values()[0].([]byte)); v != fmt.Sprint(n) {
+			t.Fatalf("expected row %d, got %s", n, v)
+		}
+	}
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Fatalf("expected 10 rows, got %d", n)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if cn.TxStatus() != TxIdle {
+		t.Fatalf("expected the cursor's transaction to be over, got status %q", cn.status)
+	}
+}
+
+func TestCursorFetchSize(t *testing.T) {
+	cn := &Conn{msg: newMsg(), status: 'I'}
+	if _, err := cn.OpenCursor("SELECT 1", 0); err == nil {
+		t.Fatal("expected an error for a zero fetch size")
+	}
+}
@@ -0,0 +1,256 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"strconv"
+	"time"
+)
+
+// infinityTs holds the times infinite timestamps and dates map to, once
+// enabled by EnableInfinityTs.
+var infinityTs struct {
+	enabled            bool
+	negative, positive time.Time
+}
+
+// EnableInfinityTs maps the timestamps and dates 'infinity' and
+// '-infinity' to positive and negative: results scan as those times, and
+// parameters equal to them are sent as infinite. It panics unless negative
+// is before positive. It isn't safe to call concurrently with queries:
+// call it from an init function.
+//
+// Otherwise infinite values decode as the strings "infinity" and
+// "-infinity", which fail to scan into a time.Time.
+func EnableInfinityTs(negative, positive time.Time) {
+	if !negative.Before(positive) {
+		panic(errf("EnableInfinityTs: negative time %v is not before positive time %v", negative, positive))
+	}
+	infinityTs.enabled = true
+	infinityTs.negative = negative
+	infinityTs.positive = positive
+}
+
+// DisableInfinityTs undoes EnableInfinityTs.
+func DisableInfinityTs() {
+	infinityTs.enabled = false
+}
+
+// infinity returns the value of an infinite timestamp or date, negative if
+// sign is.
+func infinity(sign int) driver.Value {
+	switch {
+	case infinityTs.enabled && sign < 0:
+		return infinityTs.negative
+	case infinityTs.enabled:
+		return infinityTs.positive
+	case sign < 0:
+		return "-infinity"
+	}
+	return "infinity"
+}
+
+// decodeInfinity returns the value of the text of a timestamp or date if
+// it is infinite.
+func decodeInfinity(b []byte) (driver.Value, bool) {
+	switch string(b) {
+	case "infinity":
+		return infinity(1), true
+	case "-infinity":
+		return infinity(-1), true
+	}
+	return nil, false
+}
+
+// isInfinity reports whether t is one of the times infinite values are
+// mapped to, returning the sign of the infinity.
+func isInfinity(t time.Time) (sign int, ok bool) {
+	switch {
+	case !infinityTs.enabled:
+		return 0, false
+	case t.Equal(infinityTs.positive):
+		return 1, true
+	case t.Equal(infinityTs.negative):
+		return -1, true
+	}
+	return 0, false
+}
+
+// parseTimestamp parses the text format of a timestamp, or of a timestamptz
+// when it ends with a UTC offset, in the ISO DateStyle: a date, a time
+// with up to nine fractional digits, an offset of hours, minutes and
+// seconds as in +05, +05:30 or -00:19:32, and a BC suffix for years before
+// 1 AD, which Go numbers from 0 down. Times with an offset are
+// returned in a zone of that fixed offset, those without in UTC.
+func parseTimestamp(s string) (time.Time, error) {
+	p := &dateParser{s: s}
+	year := p.num(4, 9, '-')
+	month := p.num(2, 2, '-')
+	day := p.num(2, 2, ' ')
+	hour, min, sec, nsec, zone := p.clock()
+	if p.i < len(s) && s[p.i:] == " BC" {
+		year = 1 - year
+		p.i = len(s)
+	}
+
+	if p.err || p.i != len(s) {
+		return time.Time{}, errf("invalid timestamp %q", s)
+	}
+	return time.Date(year, time.Month(month), day, hour, min, sec, nsec, zone), nil
+}
+
+// parseTime parses the text format of a time, or of a timetz when it ends
+// with a UTC offset, as the time of day of January 1 of year 0. As with
+// parseTimestamp, times with an offset are in a zone of that fixed offset,
+// those without in UTC.
+func parseTime(s string) (time.Time, error) {
+	p := &dateParser{s: s}
+	hour, min, sec, nsec, zone := p.clock()
+
+	if p.err || p.i != len(s) {
+		return time.Time{}, errf("invalid time %q", s)
+	}
+	return time.Date(0, 1, 1, hour, min, sec, nsec, zone), nil
+}
+
+// formatTimestamp formats t as a timestamptz literal, in full microseconds
+// and with the offset written as the server writes it: in hours, with the
+// minutes and seconds of zones that have them. The year is written out in
+// full, with the BC suffix the server uses for the years Go numbers 0 and
+// below.
+func formatTimestamp(t time.Time) string {
+	layout := "-01-02 15:04:05.000000" + offsetLayout(t)
+	year, bc := t.Year(), false
+	if year >= 1 && year <= 9999 {
+		return t.Format("2006" + layout)
+	}
+	if year <= 0 {
+		year, bc = 1-year, true
+	}
+	s := strconv.Itoa(year)
+	if len(s) < 4 {
+		s = "0000"[len(s):] + s
+	}
+	s += t.Format(layout)
+	if bc {
+		s += " BC"
+	}
+	return s
+}
+
+// offsetLayout returns the layout of the offset of t's zone.
+func offsetLayout(t time.Time) string {
+	_, off := t.Zone()
+	switch {
+	case off%60 != 0:
+		return "-07:00:00"
+	case off%3600 != 0:
+		return "-07:00"
+	}
+	return "-07"
+}
+
+// parseDate parses the text format of a date in the ISO DateStyle, such as
+// 2001-02-03 or 0044-03-15 BC, into midnight UTC of that day. Go numbers
+// the years before 1 AD from 0 down, so 1 BC is year 0.
+func parseDate(s string) (time.Time, error) {
+	p := &dateParser{s: s}
+	year := p.num(4, 9, '-')
+	month := p.num(2, 2, '-')
+	day := p.num(2, 2, 0)
+	if p.i < len(s) && s[p.i:] == " BC" {
+		year = 1 - year
+		p.i = len(s)
+	}
+
+	if p.err || p.i != len(s) {
+		return time.Time{}, errf("invalid date %q", s)
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// dateParser reads the fields of date and time values. A malformed value
+// sets err rather than stopping the parse, and is reported once at the end.
+type dateParser struct {
+	s   string
+	i   int
+	err bool
+}
+
+// num reads a number of min to max digits followed by sep, unless sep is
+// zero.
+func (p *dateParser) num(min, max int, sep byte) int {
+	n, digits := 0, 0
+	for ; p.i < len(p.s) && isDigit(p.s[p.i]) && digits < max; p.i++ {
+		n = n*10 + int(p.s[p.i]-'0')
+		digits++
+	}
+	if digits < min {
+		p.fail()
+	}
+	if sep != 0 {
+		if !p.peek(sep) {
+			p.fail()
+			return n
+		}
+		p.i++
+	}
+	return n
+}
+
+// clock reads a time of day with an optional fraction of a second and UTC
+// offset.
+func (p *dateParser) clock() (hour, min, sec, nsec int, zone *time.Location) {
+	hour = p.num(2, 2, ':')
+	min = p.num(2, 2, ':')
+	sec = p.num(2, 2, 0)
+
+	if p.peek('.') {
+		p.i++
+		digits := 0
+		for ; p.i < len(p.s) && isDigit(p.s[p.i]); p.i++ {
+			if digits < 9 {
+				nsec = nsec*10 + int(p.s[p.i]-'0')
+				digits++
+			}
+		}
+		if digits == 0 {
+			p.fail()
+		}
+		for ; digits < 9; digits++ {
+			nsec *= 10
+		}
+	}
+
+	zone = time.UTC
+	if p.peek('+') || p.peek('-') {
+		sign := 1
+		if p.s[p.i] == '-' {
+			sign = -1
+		}
+		p.i++
+		off := p.num(2, 2, 0) * 3600
+		if p.peek(':') {
+			p.i++
+			off += p.num(2, 2, 0) * 60
+			if p.peek(':') {
+				p.i++
+				off += p.num(2, 2, 0)
+			}
+		}
+		zone = time.FixedZone("", sign*off)
+	}
+	return
+}
+
+func (p *dateParser) peek(c byte) bool {
+	return p.i < len(p.s) && p.s[p.i] == c
+}
+
+func (p *dateParser) fail() {
+	p.err = true
+	p.i = len(p.s)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,225 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Time
+	}{
+		{"2001-02-03 04:05:06", time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)},
+		{"2001-02-03 04:05:06.5", time.Date(2001, 2, 3, 4, 5, 6, 5e8, time.UTC)},
+		{"2001-02-03 04:05:06.123456", time.Date(2001, 2, 3, 4, 5, 6, 123456000, time.UTC)},
+		{"2001-02-03 04:05:06+00", time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)},
+		{"2001-02-03 04:05:06.25-07", time.Date(2001, 2, 3, 11, 5, 6, 25e7, time.UTC)},
+		{"2001-02-03 04:05:06+05:30", time.Date(2001, 2, 2, 22, 35, 6, 0, time.UTC)},
+		{"1890-02-03 04:05:06-00:19:32", time.Date(1890, 2, 3, 4, 24, 38, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		got, err := parseTimestamp(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if !got.Equal(test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.expected, got)
+		}
+	}
+
+	got, _ := parseTimestamp("2001-02-03 04:05:06+05:30")
+	if _, off := got.Zone(); off != 5*3600+30*60 {
+		t.Errorf("expected the offset to be kept, got %d", off)
+	}
+
+	for _, in := range []string{"", "2001-02-03", "2001-02-03 04:05", "2001-02-03 04:05:06.", "2001-02-03 04:05:06+5", "2001-02-03 04:05:06 x"} {
+		if _, err := parseTimestamp(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestDecodeSessionTimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	cn := &Conn{loc: loc}
+	v := cn.decode(&fieldDesc{typ: OidTimestamptz}, []byte("2001-02-03 04:05:06-05"))
+	if tm := v.(time.Time); tm.Location() != loc || tm.Hour() != 4 {
+		t.Fatalf("expected the time in the session zone, got %v", tm)
+	}
+
+	v = cn.decode(&fieldDesc{typ: OidTimestamp}, []byte("2001-02-03 04:05:06"))
+	if tm := v.(time.Time); tm.Location() != time.UTC {
+		t.Fatalf("expected a timestamp without time zone in UTC, got %v", tm)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Time
+	}{
+		{"2001-02-03", time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{"0001-01-01", time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0001-12-31 BC", time.Date(0, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"0044-03-15 BC", time.Date(-43, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"10000-01-01", time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		got, err := parseDate(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if !got.Equal(test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.expected, got)
+		}
+	}
+
+	for _, in := range []string{"", "2001-02", "2001-02-03 AD", "01-02-03"} {
+		if _, err := parseDate(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestDateLocation(t *testing.T) {
+	loc := time.FixedZone("", -8*3600)
+	cn := &Conn{dateLoc: loc}
+
+	v := cn.decode(&fieldDesc{typ: OidDate}, []byte("2001-02-03"))
+	if tm := v.(time.Time); !tm.Equal(time.Date(2001, 2, 3, 0, 0, 0, 0, loc)) {
+		t.Fatalf("expected midnight in the date location, got %v", tm)
+	}
+
+	v = cn.decode(&fieldDesc{typ: OidDate, format: formatBinary}, []byte{0, 0, 0, 1})
+	if tm := v.(time.Time); !tm.Equal(time.Date(2000, 1, 2, 0, 0, 0, 0, loc)) {
+		t.Fatalf("expected midnight in the date location, got %v", tm)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Time
+	}{
+		{"04:05:06", time.Date(0, 1, 1, 4, 5, 6, 0, time.UTC)},
+		{"23:59:59.999999", time.Date(0, 1, 1, 23, 59, 59, 999999000, time.UTC)},
+		{"04:05:06.5+05:30", time.Date(0, 1, 1, 4, 5, 6, 5e8, time.FixedZone("", 5*3600+30*60))},
+		{"04:05:06-08", time.Date(0, 1, 1, 4, 5, 6, 0, time.FixedZone("", -8*3600))},
+	}
+
+	for _, test := range tests {
+		got, err := parseTime(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if !got.Equal(test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.in, test.expected, got)
+		}
+	}
+
+	if _, err := parseTime("04:05"); err == nil {
+		t.Error("expected an error for a time without seconds")
+	}
+}
+
+func TestTimeBinaryRoundTrip(t *testing.T) {
+	in := time.Date(0, 1, 1, 4, 5, 6, 123456000, time.FixedZone("", -(3*3600+30*60)))
+	for _, typ := range []Oid{OidTime, OidTimetz} {
+		b, ok := encodeBinary(typ, in)
+		if !ok {
+			t.Fatalf("type %d: not encoded", typ)
+		}
+		got := decodeBinary(typ, b).(time.Time)
+		if got.Hour() != 4 || got.Minute() != 5 || got.Second() != 6 || got.Nanosecond() != 123456000 {
+			t.Errorf("type %d: expected the wall clock of %v, got %v", typ, in, got)
+		}
+		if _, off := got.Zone(); typ == OidTimetz && off != -(3*3600+30*60) {
+			t.Errorf("expected the offset to round-trip, got %d", off)
+		}
+	}
+}
+
+func TestInfinityTs(t *testing.T) {
+	for _, typ := range []Oid{OidDate, OidTimestamp, OidTimestamptz} {
+		if v := decodeText(typ, []byte("-infinity")); v != "-infinity" {
+			t.Errorf("type %d: expected the -infinity sentinel, got %#v", typ, v)
+		}
+	}
+
+	negative := time.Date(-4713, 1, 1, 0, 0, 0, 0, time.UTC)
+	positive := time.Date(294276, 12, 31, 0, 0, 0, 0, time.UTC)
+	EnableInfinityTs(negative, positive)
+	defer DisableInfinityTs()
+
+	cn := &Conn{dateLoc: time.FixedZone("", 3600)}
+	if v := cn.decode(&fieldDesc{typ: OidDate}, []byte("infinity")); v != positive {
+		t.Errorf("expected the positive time, got %#v", v)
+	}
+	if v := decodeBinary(OidTimestamptz, []byte{0x80, 0, 0, 0, 0, 0, 0, 0}); v != negative {
+		t.Errorf("expected the negative time, got %#v", v)
+	}
+
+	if _, b := encodeParam(positive); string(b) != "infinity" {
+		t.Errorf("expected infinity, got %q", b)
+	}
+	b, _ := encodeBinary(OidDate, negative)
+	if !bytes.Equal(b, []byte{0x80, 0, 0, 0}) {
+		t.Errorf("expected -infinity, got %x", b)
+	}
+	b, _ = encodeBinary(OidTimestamp, positive)
+	if !bytes.Equal(b, []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("expected infinity, got %x", b)
+	}
+}
+
+func TestEnableInfinityTsOrder(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	now := time.Now()
+	EnableInfinityTs(now, now)
+}
+
+func TestTimestampYears(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		text string
+	}{
+		{time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC), "2001-02-03 04:05:06.000000+00"},
+		{time.Date(12, 2, 3, 4, 5, 6, 0, time.UTC), "0012-02-03 04:05:06.000000+00"},
+		{time.Date(0, 2, 3, 4, 5, 6, 0, time.UTC), "0001-02-03 04:05:06.000000+00 BC"},
+		{time.Date(-43, 3, 15, 0, 0, 0, 0, time.UTC), "0044-03-15 00:00:00.000000+00 BC"},
+		{time.Date(12345, 6, 7, 8, 9, 10, 0, time.UTC), "12345-06-07 08:09:10.000000+00"},
+		{time.Date(2001, 2, 3, 4, 5, 6, 0, time.FixedZone("", 5*3600+30*60)), "2001-02-03 04:05:06.000000+05:30"},
+		{time.Date(2001, 2, 3, 4, 5, 6, 123456000, time.FixedZone("", -(9*3600+30*60))), "2001-02-03 04:05:06.123456-09:30"},
+		{time.Date(1880, 1, 1, 0, 0, 0, 0, time.FixedZone("", -(3600+15*60+21))), "1880-01-01 00:00:00.000000-01:15:21"},
+		{time.Date(0, 12, 31, 23, 59, 59, 999999000, time.FixedZone("", 45*60)), "0001-12-31 23:59:59.999999+00:45 BC"},
+	}
+
+	for _, test := range tests {
+		if s := formatTimestamp(test.t); s != test.text {
+			t.Errorf("%v: expected %q, got %q", test.t, test.text, s)
+		}
+		got, err := parseTimestamp(test.text)
+		if err != nil {
+			t.Errorf("%q: %v", test.text, err)
+			continue
+		}
+		if !got.Equal(test.t) {
+			t.Errorf("%q: expected %v, got %v", test.text, test.t, got)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"time"
+)
+
+// pgEpoch is the origin of the binary timestamp and date formats.
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// decode returns the Go value for the column value b described by f.
+func (cn *Conn) decode(f *fieldDesc, b []byte) driver.Value {
+	if _, ok := cn.enums[f.typ]; ok {
+		// An enum label, the same in either format; arrays of enums
+		// are left as text too.
+		return string(b)
+	}
+
+	if t, ok := cn.composites[f.typ]; ok {
+		return decodeComposite(t, b)
+	}
+	if c, ok := cn.types[f.typ]; ok {
+		return decodeType(c, f.typ, b)
+	}
+
+	var v driver.Value
+	if f.format == formatBinary {
+		v = decodeBinary(f.typ, b)
+	} else {
+		v = decodeText(f.typ, b)
+	}
+	t, ok := v.(time.Time)
+	if ok {
+		if _, inf := isInfinity(t); inf {
+			return v
+		}
+	}
+	switch {
+	case !ok:
+	case f.typ == OidTimestamptz && cn.loc != nil:
+		// Show the instant as the session would.
+		v = t.In(cn.loc)
+	case f.typ == OidDate && cn.dateLoc != nil:
+		v = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, cn.dateLoc)
+	}
+	return v
+}
+
+// decodeText decodes the text format of a value of type typ into the Go
+// type database/sql scans it from most naturally. Types it doesn't know
+// are returned as the raw bytes.
+func decodeText(typ Oid, b []byte) driver.Value {
+	switch typ {
+	case OidBool:
+		return len(b) == 1 && b[0] == 't'
+	case OidInt2, OidInt4, OidInt8:
+		n, err := strconv.ParseInt(string(b), 10, 64)
+		if err != nil {
+			panic(errf("invalid value %q for type %d", b, typ))
+		}
+		return n
+	case OidFloat4, OidFloat8:
+		bits := 64
+		if typ == OidFloat4 {
+			bits = 32
+		}
+		f, err := strconv.ParseFloat(string(b), bits)
+		if err != nil {
+			panic(errf("invalid value %q for type %d", b, typ))
+		}
+		return f
+	case OidText, OidVarchar, OidBpchar, OidName, OidInterval, OidUUID, OidBit, OidVarbit,
+		OidInt4Range, OidInt8Range, OidNumRange, OidTsRange, OidTstzRange, OidDateRange,
+		OidInt4Multirange, OidInt8Multirange, OidNumMultirange, OidTsMultirange, OidTstzMultirange, OidDateMultirange,
+		OidPoint, OidLine, OidLseg, OidBox, OidPath, OidPolygon, OidCircle, OidTSVector, OidTSQuery,
+		// The reg* types are oids, but their text is the object's name.
+		OidRegproc, OidRegprocedure, OidRegoper, OidRegoperator, OidRegclass, OidRegtype,
+		OidRegconfig, OidRegdictionary, OidRegnamespace, OidRegrole, OidRegcollation:
+		return string(b)
+	case OidRecord:
+		return decodeRecord(b)
+	case OidOid:
+		n, err := strconv.ParseUint(string(b), 10, 32)
+		if err != nil {
+			panic(errf("invalid value %q for type %d", b, typ))
+		}
+		return Oid(n)
+	case OidBytea:
+		return decodeBytea(b)
+	case OidNumeric:
+		return decodeNumeric(b)
+	case OidInet, OidCidr:
+		return decodeInet(b)
+	case OidPgLSN:
+		lsn, err := ParseLSN(string(b))
+		if err != nil {
+			panic(err)
+		}
+		return lsn
+	case OidMacaddr, OidMacaddr8:
+		return decodeMacaddr(b)
+	case OidDate:
+		if v, ok := decodeInfinity(b); ok {
+			return v
+		}
+		t, err := parseDate(string(b))
+		if err != nil {
+			panic(err)
+		}
+		return t
+	case OidTime, OidTimetz:
+		t, err := parseTime(string(b))
+		if err != nil {
+			panic(err)
+		}
+		return t
+	case OidTimestamp, OidTimestamptz:
+		if v, ok := decodeInfinity(b); ok {
+			return v
+		}
+		t, err := parseTimestamp(string(b))
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	return b
+}
+
+// decodeBytea decodes the text format of a bytea: the hex format servers
+// use by default since 9.0, or the escape format of older servers and of
+// bytea_output=escape. The escape format doubles backslashes, so only the
+// hex format can start with \x.
+func decodeBytea(b []byte) []byte {
+	if len(b) >= 2 && b[0] == '\\' && b[1] == 'x' {
+		out := make([]byte, hex.DecodedLen(len(b)-2))
+		if _, err := hex.Decode(out, b[2:]); err != nil {
+			panic(errf("invalid bytea: %v", err))
+		}
+		return out
+	}
+
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] != '\\' {
+			out = append(out, b[i])
+			continue
+		}
+		switch {
+		case i+1 < len(b) && b[i+1] == '\\':
+			out = append(out, '\\')
+			i++
+		case i+3 < len(b) && isOctal(b[i+1]) && isOctal(b[i+2]) && isOctal(b[i+3]):
+			out = append(out, (b[i+1]-'0')<<6|(b[i+2]-'0')<<3|(b[i+3]-'0'))
+			i += 3
+		default:
+			panic(errf("invalid bytea escape at offset %d", i))
+		}
+	}
+	return out
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// decodeBinary decodes the binary format of a value of type typ. Types it
+// doesn't know are returned as the raw bytes.
+func decodeBinary(typ Oid, b []byte) driver.Value {
+	switch typ {
+	case OidBool:
+		checkLen(typ, b, 1)
+		return b[0] != 0
+	case OidInt2:
+		checkLen(typ, b, 2)
+		return int64(int16(binary.BigEndian.Uint16(b)))
+	case OidInt4:
+		checkLen(typ, b, 4)
+		return int64(int32(binary.BigEndian.Uint32(b)))
+	case OidInt8:
+		checkLen(typ, b, 8)
+		return int64(binary.BigEndian.Uint64(b))
+	case OidFloat4:
+		checkLen(typ, b, 4)
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case OidFloat8:
+		checkLen(typ, b, 8)
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	case OidUUID:
+		checkLen(typ, b, 16)
+		return formatUUID(b)
+	case OidOid:
+		checkLen(typ, b, 4)
+		return Oid(binary.BigEndian.Uint32(b))
+	case OidPgLSN:
+		checkLen(typ, b, 8)
+		return LSN(binary.BigEndian.Uint64(b))
+	case OidTimestamp, OidTimestamptz:
+		checkLen(typ, b, 8)
+		us := int64(binary.BigEndian.Uint64(b))
+		switch us {
+		case math.MaxInt64:
+			return infinity(1)
+		case math.MinInt64:
+			return infinity(-1)
+		}
+		// In seconds and microseconds: a time.Duration only spans
+		// about 292 years either side of the epoch.
+		sec, usec := us/1e6, us%1e6
+		if usec < 0 {
+			sec, usec = sec-1, usec+1e6
+		}
+		return time.Unix(pgEpoch.Unix()+sec, usec*1e3).UTC()
+	case OidTime:
+		checkLen(typ, b, 8)
+		us := int64(binary.BigEndian.Uint64(b))
+		return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(us) * time.Microsecond)
+	case OidTimetz:
+		checkLen(typ, b, 12)
+		us := int64(binary.BigEndian.Uint64(b))
+		// The zone is stored in seconds west of UTC.
+		zone := time.FixedZone("", -int(int32(binary.BigEndian.Uint32(b[8:]))))
+		return time.Date(0, 1, 1, 0, 0, 0, 0, zone).Add(time.Duration(us) * time.Microsecond)
+	case OidDate:
+		checkLen(typ, b, 4)
+		days := int32(binary.BigEndian.Uint32(b))
+		switch days {
+		case math.MaxInt32:
+			return infinity(1)
+		case math.MinInt32:
+			return infinity(-1)
+		}
+		return pgEpoch.AddDate(0, 0, int(days))
+	}
+	return b
+}
+
+func checkLen(typ Oid, b []byte, n int) {
+	if len(b) != n {
+		panic(errf("invalid binary value of %d bytes for type %d", len(b), typ))
+	}
+}
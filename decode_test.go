@@ -0,0 +1,158 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestDecodeBinary(t *testing.T) {
+	tests := []struct {
+		typ      Oid
+		in       []byte
+		expected driver.Value
+	}{
+		{OidBool, []byte{1}, true},
+		{OidInt2, []byte{0xff, 0xfe}, int64(-2)},
+		{OidInt4, []byte{0, 0, 1, 0}, int64(256)},
+		{OidInt8, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, int64(-1)},
+		{OidFloat4, []byte{0x3f, 0xc0, 0, 0}, float64(1.5)},
+		{OidFloat8, []byte{0xc0, 0x04, 0, 0, 0, 0, 0, 0}, float64(-2.5)},
+		{OidUUID, []byte{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{OidTimestamptz, []byte{0, 0, 0, 0, 0, 0, 0, 1}, time.Date(2000, 1, 1, 0, 0, 0, 1000, time.UTC)},
+		{OidTimestamp, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, time.Date(1999, 12, 31, 23, 59, 59, 999999000, time.UTC)},
+		{OidDate, []byte{0xff, 0xff, 0xff, 0xff}, time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{OidOid, []byte{0xff, 0xff, 0xff, 0xff}, Oid(4294967295)},
+	}
+
+	for _, test := range tests {
+		v := decodeBinary(test.typ, test.in)
+		if tm, ok := test.expected.(time.Time); ok {
+			if !tm.Equal(v.(time.Time)) {
+				t.Errorf("type %d: expected %v, got %v", test.typ, tm, v)
+			}
+			continue
+		}
+		if v != test.expected {
+			t.Errorf("type %d: expected %#v, got %#v", test.typ, test.expected, v)
+		}
+	}
+}
+
+func TestDecodeBinaryTimestampRange(t *testing.T) {
+	for _, tt := range []struct {
+		us       int64
+		expected time.Time
+	}{
+		{-12622780799500000, time.Date(1600, 1, 1, 0, 0, 0, 500000000, time.UTC)},
+		{-63082281600000000, time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{252455615999999999, time.Date(9999, 12, 31, 23, 59, 59, 999999000, time.UTC)},
+		// 15 March 44 BC, year -43 in astronomical numbering.
+		{-64464508800000000, time.Date(-43, 3, 15, 0, 0, 0, 0, time.UTC)},
+	} {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(tt.us))
+		for _, typ := range []Oid{OidTimestamp, OidTimestamptz} {
+			v, ok := decodeBinary(typ, b[:]).(time.Time)
+			if !ok || !v.Equal(tt.expected) {
+				t.Errorf("type %d, %d us: expected %v, got %v", typ, tt.us, tt.expected, v)
+			}
+		}
+	}
+}
+
+func TestDecodeBinaryBadLength(t *testing.T) {
+	var err error
+	func() {
+		defer recoverErr(&err)
+		decodeBinary(OidInt4, []byte{1, 2})
+	}()
+
+	if err == nil {
+		t.Fatal("expected an error for a truncated int4")
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		typ      Oid
+		in       string
+		expected driver.Value
+	}{
+		{OidBool, "t", true},
+		{OidBool, "f", false},
+		{OidInt2, "-2", int64(-2)},
+		{OidInt4, "256", int64(256)},
+		{OidInt8, "9223372036854775807", int64(9223372036854775807)},
+		{OidFloat4, "1.5", float64(1.5)},
+		{OidFloat8, "-2.25", float64(-2.25)},
+		{OidText, "x", "x"},
+		{OidVarchar, "", ""},
+		{OidTimestamptz, "2000-01-01 02:00:00.5+02", time.Date(2000, 1, 1, 0, 0, 0, 5e8, time.UTC)},
+		{OidTimestamp, "1999-12-31 23:59:59", time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{OidOid, "4294967295", Oid(4294967295)},
+		{OidRegclass, `public."My Table"`, `public."My Table"`},
+		{OidRegtype, "integer", "integer"},
+		{OidRegproc, "-", "-"},
+	}
+
+	for _, test := range tests {
+		v := decodeText(test.typ, []byte(test.in))
+		if tm, ok := test.expected.(time.Time); ok {
+			if !tm.Equal(v.(time.Time)) {
+				t.Errorf("%q: expected %v, got %v", test.in, tm, v)
+			}
+			continue
+		}
+		if v != test.expected {
+			t.Errorf("%q: expected %#v, got %#v", test.in, test.expected, v)
+		}
+	}
+
+	if v := decodeText(OidJSON, []byte("{}")); string(v.([]byte)) != "{}" {
+		t.Errorf("expected the raw bytes of an unknown type, got %#v", v)
+	}
+}
+
+func TestDecodeTextInvalid(t *testing.T) {
+	var err error
+	func() {
+		defer recoverErr(&err)
+		decodeText(OidInt4, []byte("x"))
+	}()
+
+	if err == nil {
+		t.Fatal("expected an error for an invalid int4")
+	}
+}
+
+func TestDecodeBytea(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected []byte
+	}{
+		{`\x`, []byte{}},
+		{`\x00ff5c`, []byte{0, 0xff, '\\'}},
+		{`abc`, []byte("abc")},
+		{`a\\b`, []byte(`a\b`)},
+		{`\000\377\\x`, []byte{0, 0xff, '\\', 'x'}},
+	}
+
+	for _, test := range tests {
+		v := decodeText(OidBytea, []byte(test.in))
+		if !bytes.Equal(v.([]byte), test.expected) {
+			t.Errorf("%q: expected %x, got %x", test.in, test.expected, v)
+		}
+	}
+
+	var err error
+	func() {
+		defer recoverErr(&err)
+		decodeBytea([]byte(`\9`))
+	}()
+	if err == nil {
+		t.Fatal("expected an error for an invalid escape")
+	}
+}
@@ -0,0 +1,82 @@
+package pq
+
+// FieldDescription describes a result column of a statement.
+type FieldDescription struct {
+	Name string
+
+	// Table and Column identify the table column the field comes from,
+	// if it comes straight from one; they are zero otherwise.
+	Table  Oid
+	Column int16
+
+	Type Oid
+
+	// Size is the size of the type, negative for variable-length types.
+	Size int16
+
+	// Modifier is the type modifier, such as the length of a varchar(n),
+	// or -1.
+	Modifier int32
+
+	// Format is the format the field is requested in: 0 for text, 1 for
+	// binary.
+	Format int16
+}
+
+// StatementDescription describes the parameters and result of a prepared
+// statement, as reported by the server when it was prepared.
+type StatementDescription struct {
+	Params []Oid
+
+	// Fields is nil for statements returning no rows.
+	Fields []FieldDescription
+}
+
+// Describe returns the description of the statement, without executing
+// it. It is a method of the statements returned by Conn.Prepare; from
+// database/sql, reach them through sql.Conn.Raw:
+//
+//	st, err := driverConn.(driver.Conn).Prepare(q)
+//	...
+//	desc := st.(interface {
+//		Describe() *pq.StatementDescription
+//	}).Describe()
+func (st *stmt) Describe() *StatementDescription {
+	d := &StatementDescription{Params: append([]Oid(nil), st.paramTyps...)}
+	if st.fields != nil {
+		d.Fields = fieldDescriptions(st.fields)
+		for i := range st.formats {
+			d.Fields[i].Format = st.formats[i]
+		}
+	}
+	return d
+}
+
+// FieldDescriptions returns the descriptions of the columns of the current
+// result set, including the table column each comes from, to map them back
+// to the schema. From database/sql, reach the rows through sql.Conn.Raw:
+//
+//	rows, err := driverConn.(driver.Queryer).Query(q, nil)
+//	...
+//	fields := rows.(interface {
+//		FieldDescriptions() []pq.FieldDescription
+//	}).FieldDescriptions()
+func (r *rows) FieldDescriptions() []FieldDescription {
+	return fieldDescriptions(r.fields)
+}
+
+func fieldDescriptions(fields []fieldDesc) []FieldDescription {
+	d := make([]FieldDescription, len(fields))
+	for i, f := range fields {
+		d[i] = FieldDescription{
+			Name:     f.name,
+			Table:    f.table,
+			Column:   f.attnum,
+			Type:     f.typ,
+			Size:     f.typlen,
+			Modifier: f.typmod,
+			Format:   f.format,
+		}
+	}
+	return d
+}
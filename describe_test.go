@@ -0,0 +1,88 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		{'1', nil},
+		{'t', []interface{}{int16(1), int32(OidInt4)}},
+		{'T', []interface{}{int16(2),
+			"id", int32(16384), int16(1), int32(OidInt4), int16(4), int32(-1), int16(0),
+			"name", int32(16384), int16(2), int32(OidVarchar), int16(-1), int32(36), int16(0),
+		}},
+		{'Z', []interface{}{byte('I')}},
+		{'1', nil},
+		{'t', []interface{}{int16(0)}},
+		{'n', nil},
+		{'Z', []interface{}{byte('I')}},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg()}
+	st, err := cn.Prepare("SELECT id, name FROM t WHERE id = $1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := st.(interface {
+		Describe() *StatementDescription
+	}).Describe()
+	if len(d.Params) != 1 || d.Params[0] != OidInt4 {
+		t.Fatalf("unexpected parameters %v", d.Params)
+	}
+	expected := []FieldDescription{
+		{Name: "id", Table: 16384, Column: 1, Type: OidInt4, Size: 4, Modifier: -1},
+		{Name: "name", Table: 16384, Column: 2, Type: OidVarchar, Size: -1, Modifier: 36},
+	}
+	if len(d.Fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %d", len(expected), len(d.Fields))
+	}
+	for i := range expected {
+		if d.Fields[i] != expected[i] {
+			t.Errorf("field %d: expected %+v, got %+v", i, expected[i], d.Fields[i])
+		}
+	}
+
+	st, err = cn.Prepare("DELETE FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := st.(interface {
+		Describe() *StatementDescription
+	}).Describe(); d.Params != nil || d.Fields != nil {
+		t.Fatalf("expected an empty description, got %+v", d)
+	}
+}
+
+func TestRowsFieldDescriptions(t *testing.T) {
+	r := &rows{fields: []fieldDesc{
+		{name: "id", table: 16384, attnum: 1, typ: OidInt4, typlen: 4, typmod: -1, format: formatBinary},
+		{name: "?column?", typ: OidText, typlen: -1, typmod: -1},
+	}}
+
+	d := r.FieldDescriptions()
+	expected := []FieldDescription{
+		{Name: "id", Table: 16384, Column: 1, Type: OidInt4, Size: 4, Modifier: -1, Format: formatBinary},
+		{Name: "?column?", Type: OidText, Size: -1, Modifier: -1},
+	}
+	if len(d) != len(expected) {
+		t.Fatalf("expected %d fields, got %d", len(expected), len(d))
+	}
+	for i := range expected {
+		if d[i] != expected[i] {
+			t.Errorf("field %d: expected %+v, got %+v", i, expected[i], d[i])
+		}
+	}
+}
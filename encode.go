@@ -0,0 +1,247 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// paramFormats encodes the parameters v of st, returning the format code
+// and the value (nil for NULL) of each. A parameter goes in binary when the
+// statement has described its type and encodeBinary handles the pair;
+// otherwise, as for the unnamed statement of a one-off query whose types
+// aren't known yet when Bind is sent, it goes in text, where a []byte is
+// taken for a bytea unless the statement says otherwise.
+func (st *stmt) paramFormats(v []driver.Value) ([]int16, [][]byte) {
+	formats := make([]int16, len(v))
+	values := make([][]byte, len(v))
+	for i, x := range v {
+		if x == nil {
+			continue
+		}
+		if i < len(st.paramTyps) {
+			typ := st.paramTyps[i]
+			if b, ok := encodeBinary(typ, x); ok {
+				formats[i] = formatBinary
+				values[i] = b
+				continue
+			}
+			if b, ok := x.([]byte); ok && typ != 0 && typ != OidBytea {
+				// The text of a json or text parameter, say, rather
+				// than a bytea.
+				if b == nil {
+					b = []byte{}
+				}
+				values[i] = b
+				continue
+			}
+		}
+		_, values[i] = encodeParam(x)
+	}
+	return formats, values
+}
+
+// encodeBinary returns the binary format of v as a parameter of type typ.
+// It reports false for the pairs it doesn't handle, including values out of
+// range for the type, which are left to the text format so that the server
+// reports the error.
+func encodeBinary(typ Oid, v driver.Value) ([]byte, bool) {
+	switch typ {
+	case OidBool:
+		if b, ok := v.(bool); ok {
+			if b {
+				return []byte{1}, true
+			}
+			return []byte{0}, true
+		}
+	case OidInt2:
+		if n, ok := toInt64(v); ok && n >= math.MinInt16 && n <= math.MaxInt16 {
+			b := make([]byte, 2)
+			binary.BigEndian.PutUint16(b, uint16(n))
+			return b, true
+		}
+	case OidInt4:
+		if n, ok := toInt64(v); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, uint32(n))
+			return b, true
+		}
+	case OidInt8:
+		if n, ok := toInt64(v); ok {
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint64(b, uint64(n))
+			return b, true
+		}
+	case OidFloat4:
+		if f, ok := toFloat64(v); ok {
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, math.Float32bits(float32(f)))
+			return b, true
+		}
+	case OidFloat8:
+		if f, ok := toFloat64(v); ok {
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint64(b, math.Float64bits(f))
+			return b, true
+		}
+	case OidBytea:
+		if b, ok := v.([]byte); ok {
+			if b == nil {
+				// nil marks a NULL in paramFormats.
+				b = []byte{}
+			}
+			return b, true
+		}
+	case OidUUID:
+		switch u := v.(type) {
+		case string:
+			return parseUUID(u)
+		case []byte:
+			if len(u) == 16 {
+				return u, true
+			}
+			return parseUUID(string(u))
+		}
+	case OidDate:
+		if t, ok := v.(time.Time); ok {
+			if sign, inf := isInfinity(t); inf {
+				days := int32(math.MaxInt32)
+				if sign < 0 {
+					days = math.MinInt32
+				}
+				b := make([]byte, 4)
+				binary.BigEndian.PutUint32(b, uint32(days))
+				return b, true
+			}
+			// The day on the wall calendar, whatever the location.
+			day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, uint32((day.Unix()-pgEpoch.Unix())/86400))
+			return b, true
+		}
+	case OidTime, OidTimetz:
+		t, ok := v.(time.Time)
+		if !ok {
+			break
+		}
+		// The wall clock reading, and for a timetz its offset.
+		us := int64(t.Hour())*3600e6 + int64(t.Minute())*60e6 + int64(t.Second())*1e6 + int64(t.Nanosecond()/1e3)
+		if typ == OidTime {
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint64(b, uint64(us))
+			return b, true
+		}
+		_, off := t.Zone()
+		b := make([]byte, 12)
+		binary.BigEndian.PutUint64(b, uint64(us))
+		binary.BigEndian.PutUint32(b[8:], uint32(int32(-off)))
+		return b, true
+	case OidTimestamp, OidTimestamptz:
+		t, ok := v.(time.Time)
+		if !ok {
+			break
+		}
+		if sign, inf := isInfinity(t); inf {
+			us := int64(math.MaxInt64)
+			if sign < 0 {
+				us = math.MinInt64
+			}
+			b := make([]byte, 8)
+			binary.BigEndian.PutUint64(b, uint64(us))
+			return b, true
+		}
+		if typ == OidTimestamp {
+			// A timestamp without time zone is the wall clock reading,
+			// as the text format's offset is ignored for it.
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+		}
+		us := (t.Unix()-pgEpoch.Unix())*1e6 + int64(t.Nanosecond()/1e3)
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(us))
+		return b, true
+	}
+	return nil, false
+}
+
+func toInt64(v driver.Value) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint:
+		if uint64(n) <= math.MaxInt64 {
+			return int64(n), true
+		}
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+func toFloat64(v driver.Value) (float64, bool) {
+	switch f := v.(type) {
+	case float32:
+		return float64(f), true
+	case float64:
+		return f, true
+	}
+	if n, ok := toInt64(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// TypedParam is a query parameter together with the type the server should
+// give it. The server infers the types of the parameters of a query from
+// where they appear, which fails for some, such as a NULL compared with
+// another parameter or the argument of a polymorphic function, with "could
+// not determine data type of parameter". Make one with Typed.
+type TypedParam struct {
+	Oid   Oid
+	Value interface{}
+}
+
+// Typed returns v as a parameter of the type oid.
+//
+// The types are sent when the query is parsed, so they apply to queries
+// run directly on the connection, including in a batch; the parameters of
+// a prepared statement take the types it was prepared with.
+func Typed(oid Oid, v interface{}) TypedParam {
+	return TypedParam{Oid: oid, Value: v}
+}
+
+// paramTypes splits the TypedParams in v into the type of each parameter,
+// zero for those left to the server, and the bare values. It returns nil
+// types and v itself when no parameter is typed.
+func paramTypes(v []driver.Value) ([]Oid, []driver.Value) {
+	var oids []Oid
+	for i, x := range v {
+		tp, ok := x.(TypedParam)
+		if !ok {
+			continue
+		}
+		if oids == nil {
+			oids = make([]Oid, len(v))
+			v = append([]driver.Value(nil), v...)
+		}
+		oids[i] = tp.Oid
+		v[i] = tp.Value
+	}
+	return oids, v
+}
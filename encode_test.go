@@ -0,0 +1,207 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEncodeBinary(t *testing.T) {
+	tests := []struct {
+		typ      Oid
+		in       driver.Value
+		expected []byte
+	}{
+		{OidBool, true, []byte{1}},
+		{OidInt2, int64(-2), []byte{0xff, 0xfe}},
+		{OidInt4, int64(256), []byte{0, 0, 1, 0}},
+		{OidInt8, int(-1), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{OidFloat4, float64(1.5), []byte{0x3f, 0xc0, 0, 0}},
+		{OidFloat8, int64(3), []byte{0x40, 0x08, 0, 0, 0, 0, 0, 0}},
+		{OidBytea, []byte{0, 0xff}, []byte{0, 0xff}},
+		{OidUUID, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", []byte{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}},
+		{OidDate, time.Date(1999, 12, 31, 23, 0, 0, 0, time.FixedZone("", -3600)), []byte{0xff, 0xff, 0xff, 0xff}},
+		{OidDate, time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), []byte{0, 0, 0, 1}},
+		{OidTimestamptz, time.Date(2000, 1, 1, 1, 0, 0, 1000, time.FixedZone("", 3600)), []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+		{OidTimestamp, time.Date(1999, 12, 31, 23, 59, 59, 999999000, time.FixedZone("", -3600)), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, test := range tests {
+		b, ok := encodeBinary(test.typ, test.in)
+		if !ok {
+			t.Errorf("type %d: %#v not encoded", test.typ, test.in)
+			continue
+		}
+		if !bytes.Equal(b, test.expected) {
+			t.Errorf("type %d: expected %x, got %x", test.typ, test.expected, b)
+		}
+	}
+}
+
+func TestEncodeBinaryFallback(t *testing.T) {
+	tests := []struct {
+		typ Oid
+		in  driver.Value
+	}{
+		{OidInt2, int64(1 << 15)},
+		{OidInt4, int64(-1 << 40)},
+		{OidInt8, uint64(1 << 63)},
+		{OidInt8, ^uint(0)},
+		{OidInt4, "1"},
+		{OidInt4, float64(1.5)},
+		{OidBytea, "\\x00"},
+		{OidUUID, "not a uuid"},
+		{OidText, "x"},
+	}
+
+	for _, test := range tests {
+		if b, ok := encodeBinary(test.typ, test.in); ok {
+			t.Errorf("type %d: expected %#v to be left to text, got %x", test.typ, test.in, b)
+		}
+	}
+}
+
+func TestBindFormats(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(nil)}
+	cn := &Conn{c: conn, msg: newMsg()}
+	st := &stmt{Conn: cn, name: "pq_1", paramTyps: []Oid{OidInt4, OidText, OidBytea, OidBytea}}
+	st.bind([]driver.Value{int64(7), "x", []byte(nil), nil})
+
+	m := newMsg()
+	m.readFrom(&conn.w)
+	if m.T != 'B' {
+		t.Fatalf("expected Bind, got %q", byte(m.T))
+	}
+	m.readCString()
+	if name := m.readCString(); name != "pq_1" {
+		t.Fatalf("expected statement pq_1, got %q", name)
+	}
+
+	var n int16
+	m.read(&n)
+	formats := make([]int16, n)
+	m.read(formats)
+	expected := []int16{formatBinary, formatText, formatBinary, formatText}
+	for i := range expected {
+		if i >= len(formats) || formats[i] != expected[i] {
+			t.Fatalf("expected formats %v, got %v", expected, formats)
+		}
+	}
+
+	m.read(&n)
+	lens := make([]int32, n)
+	for i := range lens {
+		m.read(&lens[i])
+		if lens[i] > 0 {
+			m.read(make([]byte, lens[i]))
+		}
+	}
+	if lens[0] != 4 || lens[1] != 1 || lens[2] != 0 || lens[3] != -1 {
+		t.Fatalf("unexpected parameter lengths %v", lens)
+	}
+}
+
+func TestParamTypes(t *testing.T) {
+	v := []driver.Value{int64(1), Typed(OidText, nil)}
+	oids, args := paramTypes(v)
+	if len(oids) != 2 || oids[0] != 0 || oids[1] != OidText {
+		t.Fatalf("unexpected types %v", oids)
+	}
+	if args[0] != int64(1) || args[1] != nil {
+		t.Fatalf("unexpected values %v", args)
+	}
+	if _, ok := v[1].(TypedParam); !ok {
+		t.Fatal("the arguments were modified")
+	}
+
+	if oids, _ := paramTypes([]driver.Value{int64(1)}); oids != nil {
+		t.Fatalf("expected no types, got %v", oids)
+	}
+}
+
+func TestParseParamTypes(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(nil)}
+	cn := &Conn{c: conn, msg: newMsg()}
+	cn.parse("", "SELECT $1, $2", []Oid{0, OidText})
+
+	m := newMsg()
+	m.readFrom(&conn.w)
+	m.readCString()
+	m.readCString()
+
+	var n int16
+	m.read(&n)
+	oids := make([]Oid, n)
+	m.read(oids)
+	if len(oids) != 2 || oids[0] != 0 || oids[1] != OidText {
+		t.Fatalf("unexpected types %v", oids)
+	}
+}
+
+func TestEncodeParamBytea(t *testing.T) {
+	l, b := encodeParam([]byte{0, 0xff, '\''})
+	if string(b) != `\x00ff27` || l != int32(len(b)) {
+		t.Fatalf("unexpected encoding %q", b)
+	}
+
+	st := &stmt{paramTyps: []Oid{OidJSON, 0}}
+	formats, values := st.paramFormats([]driver.Value{[]byte("{}"), []byte("{}")})
+	if formats[0] != formatText || string(values[0]) != "{}" {
+		t.Fatalf("expected the json parameter as is, got %q", values[0])
+	}
+	if string(values[1]) != `\x7b7d` {
+		t.Fatalf("expected the untyped parameter as a bytea, got %q", values[1])
+	}
+}
+
+func TestEncodeParamValuer(t *testing.T) {
+	l, b := encodeParam(sql.NullString{String: "x", Valid: true})
+	if string(b) != "x" || l != 1 {
+		t.Fatalf("unexpected encoding %q", b)
+	}
+	if l, _ := encodeParam(sql.NullInt64{}); l != -1 {
+		t.Fatalf("expected NULL, got length %d", l)
+	}
+	n := 3
+	if _, b := encodeParam(&n); string(b) != "3" {
+		t.Fatalf("expected the pointer's target, got %q", b)
+	}
+	if l, _ := encodeParam((*int)(nil)); l != -1 {
+		t.Fatalf("expected a nil pointer to be NULL, got length %d", l)
+	}
+	if l, _ := encodeParam((*Ltree)(nil)); l != -1 {
+		t.Fatalf("expected a nil pointer to be NULL, got length %d", l)
+	}
+
+	s, err := interpolate("SELECT $1", []driver.Value{sql.NullInt64{Int64: 7, Valid: true}})
+	if err != nil || s != "SELECT '7'" {
+		t.Fatalf("unexpected %q, %v", s, err)
+	}
+}
+
+func TestEncodeParamUint(t *testing.T) {
+	if _, b := encodeParam(uint64(math.MaxUint64)); string(b) != "18446744073709551615" {
+		t.Fatalf("unexpected encoding %q", b)
+	}
+	if _, b := encodeParam(uint(7)); string(b) != "7" {
+		t.Fatalf("unexpected encoding %q", b)
+	}
+	if b, ok := encodeBinary(OidInt8, uint(7)); !ok || !bytes.Equal(b, []byte{0, 0, 0, 0, 0, 0, 0, 7}) {
+		t.Fatalf("unexpected binary encoding %x", b)
+	}
+
+	st := &stmt{paramTyps: []Oid{OidInt8, OidNumeric}}
+	formats, values := st.paramFormats([]driver.Value{uint64(math.MaxUint64), uint64(math.MaxUint64)})
+	for i := range values {
+		if formats[i] != formatText || string(values[i]) != "18446744073709551615" {
+			t.Errorf("parameter %d: expected the decimal text, got %q", i, values[i])
+		}
+	}
+
+	if v, err := checkParam(^uint(0)); err != nil || v != ^uint(0) {
+		t.Fatalf("unexpected %#v, %v", v, err)
+	}
+}
@@ -0,0 +1,55 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"io"
+	"strings"
+)
+
+// Enum is implemented by Go enum types to be sent as the label of a
+// PostgreSQL enum, however they are represented in Go:
+//
+//	type Mood int
+//
+//	func (m Mood) EnumLabel() string { return [...]string{"sad", "ok", "happy"}[m] }
+//
+// Other types with a String method that database/sql can't convert, such
+// as structs, are sent as that string too. Named integer types with one
+// still go as numbers, since time.Month is one of them.
+type Enum interface {
+	EnumLabel() string
+}
+
+// loadEnums looks up the enum types named in the enum_types option, a
+// comma-separated list, so their values and arrays decode as text in
+// either format. Values of enum types not registered decode as []byte,
+// which still scans into a string.
+func (cn *Conn) loadEnums(names string) (err error) {
+	defer recoverErr(&err)
+
+	var types []string
+	for _, name := range strings.Split(names, ",") {
+		types = append(types, quoteLiteral(strings.TrimSpace(name))+"::regtype")
+	}
+	r := cn.simpleQuery("SELECT oid, typarray FROM pg_type WHERE typtype = 'e' AND oid IN (" + strings.Join(types, ", ") + ")")
+
+	cn.enums = make(map[Oid]bool)
+	n := 0
+	row := make([]driver.Value, 2)
+	for {
+		err := r.Next(row)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		cn.enums[row[0].(Oid)] = false
+		cn.enums[row[1].(Oid)] = true
+		n++
+	}
+	if n != len(types) {
+		return errf("enum_types %q: not all are enum types", names)
+	}
+	return nil
+}
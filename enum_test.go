@@ -0,0 +1,112 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testMood int
+
+func (m testMood) EnumLabel() string {
+	return [...]string{"sad", "ok", "happy"}[m]
+}
+
+type testColor struct{ name string }
+
+func (c testColor) String() string { return c.name }
+
+func TestEnumParam(t *testing.T) {
+	tests := []struct {
+		in       interface{}
+		expected driver.Value
+	}{
+		{testMood(2), "happy"},
+		{testColor{"red"}, "red"},
+		// A Stringer database/sql can convert keeps its value.
+		{time.March, int64(3)},
+	}
+	for _, tt := range tests {
+		v, err := checkParam(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tt.expected {
+			t.Errorf("%#v: expected %#v, got %#v", tt.in, tt.expected, v)
+		}
+	}
+
+	v, err := Array([]testMood{0, 1}).Value()
+	if err != nil || v != `{"sad","ok"}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
+
+func TestLoadEnums(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		{'T', []interface{}{int16(2),
+			"oid", int32(0), int16(0), int32(OidOid), int16(4), int32(-1), int16(0),
+			"typarray", int32(0), int16(0), int32(OidOid), int16(4), int32(-1), int16(0)}},
+		{'D', []interface{}{int16(2), int32(5), []byte("16385"), int32(5), []byte("16384")}},
+		{'C', []interface{}{"SELECT 1"}},
+		{'Z', []interface{}{byte('I')}},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	conn := &replayConn{r: bytes.NewReader(buf.Bytes())}
+	cn := &Conn{c: conn, msg: newMsg(), binaryResults: true}
+	if err := cn.loadEnums("mood"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(conn.w.String(), "'mood'::regtype") {
+		t.Fatalf("expected the query to look up mood, sent %q", conn.w.String())
+	}
+
+	if f := cn.resultFormat(16385); f != formatBinary {
+		t.Errorf("expected enums in binary, got format %d", f)
+	}
+	if f := cn.resultFormat(16384); f != formatText {
+		t.Errorf("expected enum arrays in text, got format %d", f)
+	}
+	if v := cn.decode(&fieldDesc{typ: 16385, format: formatBinary}, []byte("happy")); v != "happy" {
+		t.Errorf("expected the label, got %#v", v)
+	}
+	if v := cn.decode(&fieldDesc{typ: 16384}, []byte("{sad,ok}")); v != "{sad,ok}" {
+		t.Errorf("expected the array text, got %#v", v)
+	}
+	if v := cn.decode(&fieldDesc{typ: 99999}, []byte("x")); string(v.([]byte)) != "x" {
+		t.Errorf("expected unregistered types as bytes, got %#v", v)
+	}
+}
+
+func TestLoadEnumsNotEnum(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	m.setHead('T')
+	m.write(int16(2),
+		"oid", int32(0), int16(0), int32(OidOid), int16(4), int32(-1), int16(0),
+		"typarray", int32(0), int16(0), int32(OidOid), int16(4), int32(-1), int16(0))
+	m.writeTo(&buf)
+	m.setHead('C')
+	m.write("SELECT 0")
+	m.writeTo(&buf)
+	m.setHead('Z')
+	m.write(byte('I'))
+	m.writeTo(&buf)
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg()}
+	if err := cn.loadEnums("int4"); err == nil {
+		t.Fatal("expected an error for a type that isn't an enum")
+	}
+}
@@ -0,0 +1,93 @@
+package pq
+
+// Error is an error, or a notice, the server reported. Its fields are ""
+// when the server left them out; see "Error and Notice Message Fields" in
+// the PostgreSQL documentation for what each holds.
+type Error struct {
+	// Severity is ERROR, FATAL or PANIC for an error, and WARNING,
+	// NOTICE, DEBUG, INFO or LOG for a notice. Servers that send it
+	// untranslated (9.6 and later) report it in English whatever their
+	// lc_messages.
+	Severity string
+
+	// Code is the SQLSTATE code of the error, such as "23505" for a
+	// unique_violation.
+	Code string
+
+	Message          string
+	Detail           string
+	Hint             string
+	Position         string
+	InternalPosition string
+	InternalQuery    string
+	Where            string
+	Schema           string
+	Table            string
+	Column           string
+	DataType         string
+	Constraint       string
+	File             string
+	Line             string
+	Routine          string
+}
+
+func (e *Error) Error() string {
+	return "pq: " + e.Message
+}
+
+func readError(cn *Conn) (err error) {
+	defer recoverErr(&err)
+
+	e := new(Error)
+	var t byte
+	for {
+		cn.read(&t)
+		if t == 0 {
+			break
+		}
+		v := cn.readCString()
+		switch t {
+		case 'S':
+			if e.Severity == "" {
+				e.Severity = v
+			}
+		case 'V':
+			// The untranslated severity.
+			e.Severity = v
+		case 'C':
+			e.Code = v
+		case 'M':
+			e.Message = v
+		case 'D':
+			e.Detail = v
+		case 'H':
+			e.Hint = v
+		case 'P':
+			e.Position = v
+		case 'p':
+			e.InternalPosition = v
+		case 'q':
+			e.InternalQuery = v
+		case 'W':
+			e.Where = v
+		case 's':
+			e.Schema = v
+		case 't':
+			e.Table = v
+		case 'c':
+			e.Column = v
+		case 'd':
+			e.DataType = v
+		case 'n':
+			e.Constraint = v
+		case 'F':
+			e.File = v
+		case 'L':
+			e.Line = v
+		case 'R':
+			e.Routine = v
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,66 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadError(t *testing.T) {
+	cn := &Conn{msg: newMsg()}
+	cn.b = bytes.NewBuffer(nil)
+	cn.write(
+		byte('S'), "ERREUR",
+		byte('V'), "ERROR",
+		byte('C'), "23505",
+		byte('M'), `duplicate key value violates unique constraint "users_pkey"`,
+		byte('D'), "Key (id)=(1) already exists.",
+		byte('s'), "public",
+		byte('t'), "users",
+		byte('n'), "users_pkey",
+		byte('F'), "nbtinsert.c",
+		byte('L'), "664",
+		byte('R'), "_bt_check_unique",
+		byte('Z'), "ignored",
+		byte(0),
+	)
+
+	err := readError(cn)
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+	expected := Error{
+		Severity:   "ERROR",
+		Code:       "23505",
+		Message:    `duplicate key value violates unique constraint "users_pkey"`,
+		Detail:     "Key (id)=(1) already exists.",
+		Schema:     "public",
+		Table:      "users",
+		Constraint: "users_pkey",
+		File:       "nbtinsert.c",
+		Line:       "664",
+		Routine:    "_bt_check_unique",
+	}
+	if *e != expected {
+		t.Fatalf("expected %+v, got %+v", expected, *e)
+	}
+	if e.Error() != `pq: duplicate key value violates unique constraint "users_pkey"` {
+		t.Fatalf("unexpected message %q", e.Error())
+	}
+}
+
+func TestIsFatal(t *testing.T) {
+	for _, tt := range []struct {
+		err   error
+		fatal bool
+	}{
+		{&Error{Severity: "FATAL"}, true},
+		{&Error{Severity: "PANIC"}, true},
+		{&Error{Severity: "ERROR"}, false},
+		{errf("not from the server"), false},
+	} {
+		if isFatal(tt.err) != tt.fatal {
+			t.Errorf("%+v: expected isFatal %v", tt.err, tt.fatal)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package pq
+
+// FunctionCall calls the function fn through the fastpath interface, without
+// parsing a query. The arguments and the result are in the binary format,
+// with nil for NULL. It is the interface of the large object functions,
+// such as lo_open and loread, and of some extensions.
+func (cn *Conn) FunctionCall(fn Oid, args ...[]byte) (result []byte, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+
+	cn.setHead('F')
+	cn.write(int32(fn))
+	cn.write(int16(1), formatBinary)
+	cn.write(int16(len(args)))
+	for _, b := range args {
+		if b == nil {
+			cn.write(int32(-1))
+			continue
+		}
+		cn.write(int32(len(b)), b)
+	}
+	cn.write(formatBinary)
+	cn.sendMsg()
+
+	cn.recvMsg()
+	if cn.T != 'V' {
+		panic(cn.unexpected("function call"))
+	}
+	var l int32
+	cn.read(&l)
+	if l >= 0 {
+		result = make([]byte, l)
+		cn.read(result)
+	}
+
+	cn.recvMsg()
+	if cn.T != 'Z' {
+		panic(cn.unexpected("function call"))
+	}
+	cn.read(&cn.status)
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func functionCallConn(result []byte) *replayConn {
+	var buf bytes.Buffer
+	m := newMsg()
+	m.setHead('V')
+	if result == nil {
+		m.write(int32(-1))
+	} else {
+		m.write(int32(len(result)), result)
+	}
+	m.writeTo(&buf)
+	m.setHead('Z')
+	m.write(byte('I'))
+	m.writeTo(&buf)
+	return &replayConn{r: bytes.NewReader(buf.Bytes())}
+}
+
+func TestFunctionCall(t *testing.T) {
+	conn := functionCallConn([]byte{0, 0, 0, 1})
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	res, err := cn.FunctionCall(952, []byte{0, 0, 0, 42}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res, []byte{0, 0, 0, 1}) {
+		t.Fatalf("unexpected result %x", res)
+	}
+
+	m := newMsg()
+	m.readFrom(&conn.w)
+	if m.T != 'F' {
+		t.Fatalf("expected FunctionCall, got %q", byte(m.T))
+	}
+	var fn int32
+	var n, format int16
+	m.read(&fn)
+	m.read(&n)
+	m.read(&format)
+	if fn != 952 || n != 1 || format != formatBinary {
+		t.Fatalf("unexpected function %d or argument formats", fn)
+	}
+	var l1, l2 int32
+	m.read(&n)
+	m.read(&l1)
+	m.read(make([]byte, l1))
+	m.read(&l2)
+	if n != 2 || l1 != 4 || l2 != -1 {
+		t.Fatalf("unexpected arguments: %d, lengths %d and %d", n, l1, l2)
+	}
+}
+
+func TestFunctionCallNull(t *testing.T) {
+	cn := &Conn{c: functionCallConn(nil), msg: newMsg()}
+
+	res, err := cn.FunctionCall(952)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != nil {
+		t.Fatalf("expected NULL, got %x", res)
+	}
+}
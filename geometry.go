@@ -0,0 +1,205 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"strconv"
+	"strings"
+)
+
+// The geometric types. Their columns decode to their text, which scans
+// into a string; scan them into these types to get the coordinates.
+
+// Point is a point.
+type Point struct {
+	X, Y float64
+}
+
+// Scan implements sql.Scanner.
+func (p *Point) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+	f := parseGeometry(src, "Point", 2)
+	*p = Point{f[0], f[1]}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (p Point) Value() (driver.Value, error) {
+	return string(appendPoint(nil, p)), nil
+}
+
+// Line is a line, the points where AX + BY + C = 0.
+type Line struct {
+	A, B, C float64
+}
+
+// Scan implements sql.Scanner.
+func (l *Line) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+	f := parseGeometry(src, "Line", 3)
+	*l = Line{f[0], f[1], f[2]}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (l Line) Value() (driver.Value, error) {
+	b := append([]byte{'{'}, formatFloat(l.A)...)
+	b = append(append(b, ','), formatFloat(l.B)...)
+	b = append(append(b, ','), formatFloat(l.C)...)
+	return string(append(b, '}')), nil
+}
+
+// LineSegment is an lseg, from its first point to its second.
+type LineSegment [2]Point
+
+// Scan implements sql.Scanner.
+func (l *LineSegment) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+	f := parseGeometry(src, "LineSegment", 4)
+	*l = LineSegment{{f[0], f[1]}, {f[2], f[3]}}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (l LineSegment) Value() (driver.Value, error) {
+	return "[" + string(appendPoints(nil, l[:])) + "]", nil
+}
+
+// Box is a box, by two opposite corners. The server stores the upper right
+// corner first.
+type Box [2]Point
+
+// Scan implements sql.Scanner.
+func (b *Box) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+	f := parseGeometry(src, "Box", 4)
+	*b = Box{{f[0], f[1]}, {f[2], f[3]}}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (b Box) Value() (driver.Value, error) {
+	return string(appendPoints(nil, b[:])), nil
+}
+
+// Path is a path, open or closed.
+type Path struct {
+	Points []Point
+	Closed bool
+}
+
+// Scan implements sql.Scanner.
+func (p *Path) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+	f := parseGeometry(src, "Path", -1)
+	*p = Path{Points: toPoints(f), Closed: geometryText(src)[0] == '('}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (p Path) Value() (driver.Value, error) {
+	if p.Closed {
+		return "(" + string(appendPoints(nil, p.Points)) + ")", nil
+	}
+	return "[" + string(appendPoints(nil, p.Points)) + "]", nil
+}
+
+// Polygon is a polygon, by its vertices.
+type Polygon []Point
+
+// Scan implements sql.Scanner.
+func (p *Polygon) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+	*p = toPoints(parseGeometry(src, "Polygon", -1))
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (p Polygon) Value() (driver.Value, error) {
+	return "(" + string(appendPoints(nil, p)) + ")", nil
+}
+
+// Circle is a circle.
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// Scan implements sql.Scanner.
+func (c *Circle) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+	f := parseGeometry(src, "Circle", 3)
+	*c = Circle{Point{f[0], f[1]}, f[2]}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (c Circle) Value() (driver.Value, error) {
+	b := appendPoint([]byte{'<'}, c.Center)
+	b = append(append(b, ','), formatFloat(c.Radius)...)
+	return string(append(b, '>')), nil
+}
+
+func geometryText(src interface{}) string {
+	switch v := src.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// parseGeometry returns the numbers in the text form of a geometric value,
+// checking there are n of them, or a nonzero even number of them, for the
+// points of a path or polygon, if n is -1. The punctuation around them
+// varies by type, and isn't checked beyond being the brackets and commas
+// the types use.
+func parseGeometry(src interface{}, into string, n int) []float64 {
+	s := geometryText(src)
+	if s == "" {
+		panic(errf("cannot scan %T into a %s", src, into))
+	}
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return strings.ContainsRune("()[]{}<>, ", r)
+	})
+	if (n >= 0 && len(fields) != n) || (n < 0 && (len(fields) == 0 || len(fields)%2 != 0)) {
+		panic(errf("invalid %s %q", into, s))
+	}
+	f := make([]float64, len(fields))
+	for i, field := range fields {
+		var err error
+		if f[i], err = strconv.ParseFloat(field, 64); err != nil {
+			panic(errf("invalid %s %q", into, s))
+		}
+	}
+	return f
+}
+
+func toPoints(f []float64) []Point {
+	p := make([]Point, len(f)/2)
+	for i := range p {
+		p[i] = Point{f[2*i], f[2*i+1]}
+	}
+	return p
+}
+
+func appendPoint(b []byte, p Point) []byte {
+	b = append(append(b, '('), formatFloat(p.X)...)
+	b = append(append(b, ','), formatFloat(p.Y)...)
+	return append(b, ')')
+}
+
+func appendPoints(b []byte, p []Point) []byte {
+	for i := range p {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendPoint(b, p[i])
+	}
+	return b
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
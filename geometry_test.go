@@ -0,0 +1,68 @@
+package pq
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestGeometry(t *testing.T) {
+	tests := []struct {
+		in   string
+		dest interface {
+			sql.Scanner
+			driver.Valuer
+		}
+		expected interface{}
+		out      string
+	}{
+		{"(1.5,-2)", new(Point), &Point{1.5, -2}, "(1.5,-2)"},
+		{"{1,-1,0}", new(Line), &Line{1, -1, 0}, "{1,-1,0}"},
+		{"[(0,0),(1,1)]", new(LineSegment), &LineSegment{{0, 0}, {1, 1}}, "[(0,0),(1,1)]"},
+		{"(2,2),(0,0)", new(Box), &Box{{2, 2}, {0, 0}}, "(2,2),(0,0)"},
+		{"[(0,0),(1,1),(2,0)]", new(Path), &Path{Points: []Point{{0, 0}, {1, 1}, {2, 0}}}, "[(0,0),(1,1),(2,0)]"},
+		{"((0,0),(1,1))", new(Path), &Path{Points: []Point{{0, 0}, {1, 1}}, Closed: true}, "((0,0),(1,1))"},
+		{"((0,0),(1,1),(1e+21,0))", new(Polygon), &Polygon{{0, 0}, {1, 1}, {1e21, 0}}, "((0,0),(1,1),(1e+21,0))"},
+		{"<(1,2),3>", new(Circle), &Circle{Point{1, 2}, 3}, "<(1,2),3>"},
+	}
+	for _, tt := range tests {
+		if err := tt.dest.Scan([]byte(tt.in)); err != nil {
+			t.Fatalf("%s: %v", tt.in, err)
+		}
+		if !reflect.DeepEqual(tt.dest, tt.expected) {
+			t.Errorf("%s: expected %+v, got %+v", tt.in, tt.expected, tt.dest)
+		}
+		if v, err := tt.dest.Value(); err != nil || v != tt.out {
+			t.Errorf("%s: expected %s, got %v, %v", tt.in, tt.out, v, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		in   string
+		dest sql.Scanner
+	}{
+		{"(1)", new(Point)},
+		{"(1,x)", new(Point)},
+		{"{1,2}", new(Line)},
+		{"((0,0),(1))", new(Polygon)},
+		{"", new(Path)},
+	} {
+		if err := tt.dest.Scan(tt.in); err == nil {
+			t.Errorf("%q into %T: expected an error", tt.in, tt.dest)
+		}
+	}
+	if err := new(Point).Scan(nil); err == nil {
+		t.Error("expected an error scanning NULL")
+	}
+}
+
+func TestPointArray(t *testing.T) {
+	var points []Point
+	if err := Array(&points).Scan(`{"(1,2)","(3,4)"}`); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(points, []Point{{1, 2}, {3, 4}}) {
+		t.Fatalf("unexpected %v", points)
+	}
+}
@@ -0,0 +1,166 @@
+package pq
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Hstore is a value of the hstore extension type: a map of strings to
+// strings that may be NULL. A nil Hstore is NULL.
+type Hstore map[string]sql.NullString
+
+// Scan implements sql.Scanner, parsing the text format of an hstore.
+func (h *Hstore) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("pq: cannot scan %T into an Hstore", src)
+	}
+
+	m, err := parseHstore(s)
+	if err != nil {
+		return err
+	}
+	*h = m
+	return nil
+}
+
+// Value implements driver.Valuer, formatting h as an hstore literal.
+func (h Hstore) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		writeHstoreString(&b, k)
+		b.WriteString("=>")
+		if v := h[k]; v.Valid {
+			writeHstoreString(&b, v.String)
+		} else {
+			b.WriteString("NULL")
+		}
+	}
+	return b.String(), nil
+}
+
+func writeHstoreString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+}
+
+// parseHstore parses the text format of an hstore: "key"=>"value" pairs
+// separated by commas, with NULL for NULL values. The server quotes
+// everything, but unquoted words are accepted as on input.
+func parseHstore(s string) (Hstore, error) {
+	p := &hstoreParser{s: s}
+	m := make(Hstore)
+	for {
+		p.skipSpace()
+		if p.i == len(s) {
+			return m, nil
+		}
+
+		key, quoted, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		if !quoted && strings.EqualFold(key, "NULL") {
+			return nil, fmt.Errorf("pq: NULL key in hstore %q", s)
+		}
+
+		p.skipSpace()
+		if !strings.HasPrefix(s[p.i:], "=>") {
+			return nil, fmt.Errorf("pq: expected => at offset %d of hstore %q", p.i, s)
+		}
+		p.i += 2
+		p.skipSpace()
+
+		val, quoted, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		if !quoted && strings.EqualFold(val, "NULL") {
+			m[key] = sql.NullString{}
+		} else {
+			m[key] = sql.NullString{String: val, Valid: true}
+		}
+
+		p.skipSpace()
+		if p.i < len(s) {
+			if s[p.i] != ',' {
+				return nil, fmt.Errorf("pq: expected , at offset %d of hstore %q", p.i, s)
+			}
+			p.i++
+		}
+	}
+}
+
+type hstoreParser struct {
+	s string
+	i int
+}
+
+func (p *hstoreParser) skipSpace() {
+	for p.i < len(p.s) && (p.s[p.i] == ' ' || p.s[p.i] == '\t' || p.s[p.i] == '\n' || p.s[p.i] == '\r') {
+		p.i++
+	}
+}
+
+// word reads a double-quoted string, undoing its backslash escapes, or an
+// unquoted word.
+func (p *hstoreParser) word() (w string, quoted bool, err error) {
+	if p.i < len(p.s) && p.s[p.i] == '"' {
+		var b strings.Builder
+		for p.i++; p.i < len(p.s); p.i++ {
+			switch c := p.s[p.i]; c {
+			case '"':
+				p.i++
+				return b.String(), true, nil
+			case '\\':
+				p.i++
+				if p.i == len(p.s) {
+					break
+				}
+				b.WriteByte(p.s[p.i])
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", false, fmt.Errorf("pq: unterminated string in hstore %q", p.s)
+	}
+
+	start := p.i
+	for p.i < len(p.s) && !strings.ContainsRune(" \t\n\r,=", rune(p.s[p.i])) {
+		p.i++
+	}
+	if p.i == start {
+		return "", false, fmt.Errorf("pq: expected a string at offset %d of hstore %q", p.i, p.s)
+	}
+	return p.s[start:p.i], false, nil
+}
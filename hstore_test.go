@@ -0,0 +1,61 @@
+package pq
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestHstoreRoundTrip(t *testing.T) {
+	h := Hstore{
+		"a":         {String: "1", Valid: true},
+		"quote\"d":  {String: `back\slash`, Valid: true},
+		"empty":     {String: "", Valid: true},
+		"null":      {},
+		"comma, =>": {String: "NULL", Valid: true},
+	}
+
+	v, err := h.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `"a"=>"1", "comma, =>"=>"NULL", "empty"=>"", "null"=>NULL, "quote\"d"=>"back\\slash"`
+	if v != expected {
+		t.Fatalf("expected %s, got %s", expected, v)
+	}
+
+	var got Hstore
+	if err := got.Scan([]byte(v.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(h) {
+		t.Fatalf("expected %d pairs, got %v", len(h), got)
+	}
+	for k, v := range h {
+		if got[k] != v {
+			t.Errorf("%q: expected %+v, got %+v", k, v, got[k])
+		}
+	}
+}
+
+func TestHstoreScan(t *testing.T) {
+	var h Hstore
+	if err := h.Scan(`a=>b , c => NULL`); err != nil {
+		t.Fatal(err)
+	}
+	if h["a"] != (sql.NullString{String: "b", Valid: true}) || h["c"].Valid {
+		t.Fatalf("unexpected hstore %v", h)
+	}
+
+	if err := h.Scan(""); err != nil || h == nil || len(h) != 0 {
+		t.Fatalf("expected an empty hstore, got %v, %v", h, err)
+	}
+	if err := h.Scan(nil); err != nil || h != nil {
+		t.Fatalf("expected NULL to scan as nil, got %v, %v", h, err)
+	}
+
+	for _, in := range []string{`"a"`, `"a"=>`, `"a"=>"b" "c"=>"d"`, `"a=>"b"`, `NULL=>"b"`} {
+		if err := h.Scan(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
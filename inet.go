@@ -0,0 +1,69 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"net"
+)
+
+// decodeInet decodes the text format of an inet or cidr: a net.IP for an
+// address without a netmask, and a *net.IPNet otherwise. The IP of the
+// IPNet keeps the host bits an inet may have, as in 192.168.0.5/24.
+func decodeInet(b []byte) driver.Value {
+	s := string(b)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' {
+			ip, n, err := net.ParseCIDR(s)
+			if err != nil {
+				panic(errf("invalid inet %q", s))
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			n.IP = ip
+			return n
+		}
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		panic(errf("invalid inet %q", s))
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// decodeMacaddr decodes the text format of a macaddr or macaddr8.
+func decodeMacaddr(b []byte) driver.Value {
+	mac, err := net.ParseMAC(string(b))
+	if err != nil || (len(mac) != 6 && len(mac) != 8) {
+		panic(errf("invalid macaddr %q", b))
+	}
+	return mac
+}
+
+// netParam returns the text form of v if it is a net.IP, a net.IPNet or
+// a pointer to one, or a net.HardwareAddr, with "" for nil.
+func netParam(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case net.HardwareAddr:
+		if v == nil {
+			return "", true
+		}
+		return v.String(), true
+	case net.IP:
+		if v == nil {
+			return "", true
+		}
+		return v.String(), true
+	case *net.IPNet:
+		if v == nil {
+			return "", true
+		}
+		return v.String(), true
+	case net.IPNet:
+		return v.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,119 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeInet(t *testing.T) {
+	tests := []struct {
+		typ      Oid
+		in       string
+		expected driver.Value
+	}{
+		{OidInet, "192.168.0.1", net.IP{192, 168, 0, 1}},
+		{OidInet, "::1", net.ParseIP("::1")},
+		{OidInet, "192.168.0.5/24", &net.IPNet{IP: net.IP{192, 168, 0, 5}, Mask: net.CIDRMask(24, 32)}},
+		{OidCidr, "10.0.0.0/8", &net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}},
+		{OidCidr, "2001:db8::/32", &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)}},
+	}
+	for _, tt := range tests {
+		v := decodeText(tt.typ, []byte(tt.in))
+		if !reflect.DeepEqual(v, tt.expected) {
+			t.Errorf("%s: expected %#v, got %#v", tt.in, tt.expected, v)
+		}
+	}
+
+	for _, in := range []string{"", "192.168.0", "10.0.0.0/33"} {
+		var err error
+		func() {
+			defer recoverErr(&err)
+			decodeInet([]byte(in))
+		}()
+		if err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestNetParam(t *testing.T) {
+	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+	tests := []struct {
+		in       interface{}
+		expected driver.Value
+	}{
+		{net.IP{192, 168, 0, 1}, "192.168.0.1"},
+		{net.ParseIP("::1"), "::1"},
+		{n, "10.0.0.0/8"},
+		{*n, "10.0.0.0/8"},
+		{net.IP(nil), nil},
+		{(*net.IPNet)(nil), nil},
+	}
+	for _, tt := range tests {
+		v, err := checkParam(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tt.expected {
+			t.Errorf("%#v: expected %#v, got %#v", tt.in, tt.expected, v)
+		}
+	}
+}
+
+func TestInetArray(t *testing.T) {
+	v, err := Array([]net.IP{{10, 0, 0, 1}, nil}).Value()
+	if err != nil || v != `{"10.0.0.1",NULL}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	var ips []net.IP
+	if err := Array(&ips).Scan("{10.0.0.1,::1}"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 2 || !ips[0].Equal(net.IP{10, 0, 0, 1}) || !ips[1].Equal(net.ParseIP("::1")) {
+		t.Fatalf("unexpected %v", ips)
+	}
+
+	var nets []*net.IPNet
+	if err := Array(&nets).Scan("{10.0.0.0/8,NULL}"); err != nil {
+		t.Fatal(err)
+	}
+	if len(nets) != 2 || nets[0].String() != "10.0.0.0/8" || nets[1] != nil {
+		t.Fatalf("unexpected %v", nets)
+	}
+}
+
+func TestMacaddr(t *testing.T) {
+	mac := net.HardwareAddr{0x08, 0x00, 0x2b, 0x01, 0x02, 0x03}
+	if v := decodeText(OidMacaddr, []byte("08:00:2b:01:02:03")); !reflect.DeepEqual(v, mac) {
+		t.Errorf("expected %v, got %#v", mac, v)
+	}
+	mac8 := net.HardwareAddr{0x08, 0x00, 0x2b, 0x01, 0x02, 0x03, 0x04, 0x05}
+	if v := decodeText(OidMacaddr8, []byte("08:00:2b:01:02:03:04:05")); !reflect.DeepEqual(v, mac8) {
+		t.Errorf("expected %v, got %#v", mac8, v)
+	}
+
+	if v, err := checkParam(mac); err != nil || v != "08:00:2b:01:02:03" {
+		t.Errorf("unexpected %v, %v", v, err)
+	}
+	if v, err := checkParam(net.HardwareAddr(nil)); err != nil || v != nil {
+		t.Errorf("expected nil to be NULL, got %v, %v", v, err)
+	}
+
+	v, err := Array([]net.HardwareAddr{mac, nil}).Value()
+	if err != nil || v != `{"08:00:2b:01:02:03",NULL}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	var macs []net.HardwareAddr
+	if err := Array(&macs).Scan("{08:00:2b:01:02:03,08:00:2b:01:02:03:04:05}"); err != nil {
+		t.Fatal(err)
+	}
+	if len(macs) != 2 || !reflect.DeepEqual(macs[0], mac) || !reflect.DeepEqual(macs[1], mac8) {
+		t.Fatalf("unexpected %v", macs)
+	}
+	if err := Array(&macs).Scan("{08:00:2b}"); err == nil {
+		t.Fatal("expected an error for an invalid macaddr")
+	}
+}
@@ -0,0 +1,230 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval is a PostgreSQL interval. Like the server, it keeps months and
+// days apart from the time part: a month or a day has no fixed length until
+// it is applied to a date, so collapsing an interval into a time.Duration
+// is only exact when both are zero.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+// NewInterval returns the interval of the given calendar components plus
+// the time part d, truncated to microseconds.
+func NewInterval(years, months, days int, d time.Duration) Interval {
+	return Interval{
+		Months:       int32(years*12 + months),
+		Days:         int32(days),
+		Microseconds: int64(d / time.Microsecond),
+	}
+}
+
+// IntervalOf returns the interval holding exactly d, with no month or day
+// part.
+func IntervalOf(d time.Duration) Interval {
+	return Interval{Microseconds: int64(d / time.Microsecond)}
+}
+
+// Civil splits iv into its calendar components and its time part.
+func (iv Interval) Civil() (years, months, days int, d time.Duration) {
+	years = int(iv.Months / 12)
+	months = int(iv.Months % 12)
+	return years, months, int(iv.Days), iv.time()
+}
+
+// Duration returns iv as a time.Duration. ok is false if iv has a month or
+// day part, which has no fixed length.
+func (iv Interval) Duration() (d time.Duration, ok bool) {
+	return iv.time(), iv.Months == 0 && iv.Days == 0
+}
+
+// ApproxDuration returns iv as a time.Duration, counting a day as 24 hours
+// and a month as 30 days the way the server's justify_interval does.
+func (iv Interval) ApproxDuration() time.Duration {
+	return time.Duration(iv.Months)*30*24*time.Hour + time.Duration(iv.Days)*24*time.Hour + iv.time()
+}
+
+func (iv Interval) time() time.Duration {
+	return time.Duration(iv.Microseconds) * time.Microsecond
+}
+
+// AddTo returns t moved by iv as the server adds an interval to a
+// timestamp: months first, clamping the day to the last of the month they
+// land in, so that Jan 31 plus a month is Feb 29 (or 28) rather than
+// early March; then days, both in t's location; then the time part.
+func (iv Interval) AddTo(t time.Time) time.Time {
+	y, m, d := t.Date()
+	hh, mm, ss := t.Clock()
+
+	// The first of the target month, normalized by time.Date.
+	first := time.Date(y, m+time.Month(iv.Months), 1, 0, 0, 0, 0, t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+
+	t = time.Date(first.Year(), first.Month(), d, hh, mm, ss, t.Nanosecond(), t.Location())
+	return t.AddDate(0, 0, int(iv.Days)).Add(iv.time())
+}
+
+// Add returns the component-wise sum of iv and o.
+func (iv Interval) Add(o Interval) Interval {
+	return Interval{iv.Months + o.Months, iv.Days + o.Days, iv.Microseconds + o.Microseconds}
+}
+
+// Sub returns the component-wise difference of iv and o.
+func (iv Interval) Sub(o Interval) Interval {
+	return iv.Add(o.Neg())
+}
+
+// Neg returns -iv.
+func (iv Interval) Neg() Interval {
+	return Interval{-iv.Months, -iv.Days, -iv.Microseconds}
+}
+
+// Mul returns iv with every component multiplied by n.
+func (iv Interval) Mul(n int) Interval {
+	return Interval{iv.Months * int32(n), iv.Days * int32(n), iv.Microseconds * int64(n)}
+}
+
+// String returns iv in the server's "postgres" IntervalStyle, which it also
+// accepts as input.
+func (iv Interval) String() string {
+	var parts []string
+	years, months, days, _ := iv.Civil()
+	if years != 0 {
+		parts = append(parts, plural(years, "year", "years"))
+	}
+	if months != 0 {
+		parts = append(parts, plural(months, "mon", "mons"))
+	}
+	if days != 0 {
+		parts = append(parts, plural(days, "day", "days"))
+	}
+	if iv.Microseconds != 0 || len(parts) == 0 {
+		clock := formatClock(iv.Microseconds)
+		if iv.Microseconds > 0 && (iv.Months < 0 || iv.Days < 0) {
+			clock = "+" + clock
+		}
+		parts = append(parts, clock)
+	}
+	return strings.Join(parts, " ")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+func formatClock(us int64) string {
+	sign := ""
+	if us < 0 {
+		sign = "-"
+		us = -us
+	}
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, us/3600e6, us/60e6%60, us/1e6%60)
+	if frac := us % 1e6; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+	}
+	return s
+}
+
+// ParseInterval parses an interval in the server's default "postgres"
+// IntervalStyle, e.g. "1 year 2 mons -3 days 04:05:06.7".
+func ParseInterval(s string) (iv Interval, err error) {
+	f := strings.Fields(s)
+	for i := 0; i < len(f); i++ {
+		if strings.Contains(f[i], ":") {
+			us, err := parseClock(f[i])
+			if err != nil {
+				return Interval{}, err
+			}
+			iv.Microseconds += us
+			continue
+		}
+
+		if i+1 == len(f) {
+			return Interval{}, errf("invalid interval %q", s)
+		}
+		n, err := strconv.Atoi(f[i])
+		if err != nil {
+			return Interval{}, errf("invalid interval %q", s)
+		}
+		i++
+		switch f[i] {
+		case "year", "years":
+			iv.Months += int32(n * 12)
+		case "mon", "mons":
+			iv.Months += int32(n)
+		case "day", "days":
+			iv.Days += int32(n)
+		default:
+			return Interval{}, errf("invalid interval unit %q in %q", f[i], s)
+		}
+	}
+	return iv, nil
+}
+
+// Scan implements sql.Scanner, parsing an interval column.
+func (iv *Interval) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("pq: cannot scan %T into an Interval", src)
+	}
+
+	parsed, err := ParseInterval(s)
+	if err != nil {
+		return err
+	}
+	*iv = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, sending iv in the form String returns.
+func (iv Interval) Value() (driver.Value, error) {
+	return iv.String(), nil
+}
+
+// parseClock parses [-+]hh:mm:ss[.ffffff] into microseconds.
+func parseClock(s string) (int64, error) {
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	p := strings.Split(s, ":")
+	if len(p) != 3 {
+		return 0, errf("invalid interval time %q", s)
+	}
+	h, err1 := strconv.ParseInt(p[0], 10, 64)
+	m, err2 := strconv.ParseInt(p[1], 10, 64)
+	sec, err3 := strconv.ParseFloat(p[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, errf("invalid interval time %q", s)
+	}
+
+	us := h*3600e6 + m*60e6 + int64(sec*1e6+0.5)
+	if neg {
+		us = -us
+	}
+	return us, nil
+}
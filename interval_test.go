@@ -0,0 +1,123 @@
+package pq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Interval
+	}{
+		{"00:00:00", Interval{}},
+		{"1 year 2 mons 3 days 04:05:06.7", Interval{14, 3, 4*3600e6 + 5*60e6 + 6.7e6}},
+		{"-1 days +02:03:00", Interval{0, -1, 2*3600e6 + 3*60e6}},
+		{"-2 years -00:00:00.000001", Interval{-24, 0, -1}},
+		{"1 mon", Interval{1, 0, 0}},
+	}
+
+	for _, test := range tests {
+		iv, err := ParseInterval(test.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if iv != test.expected {
+			t.Errorf("ParseInterval(%q) = %+v, expected %+v", test.s, iv, test.expected)
+		}
+	}
+}
+
+func TestIntervalString(t *testing.T) {
+	for _, s := range []string{"00:00:00", "1 year 2 mons 3 days 04:05:06.7", "-1 days +02:03:00", "-2 years -00:00:00.000001"} {
+		iv, err := ParseInterval(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if iv.String() != s {
+			t.Errorf("round trip of %q gave %q", s, iv.String())
+		}
+	}
+}
+
+func TestIntervalCivil(t *testing.T) {
+	iv := NewInterval(1, 14, 3, 90*time.Minute)
+	years, months, days, d := iv.Civil()
+	if years != 2 || months != 2 || days != 3 || d != 90*time.Minute {
+		t.Fatalf("unexpected civil components %d %d %d %v", years, months, days, d)
+	}
+
+	if _, ok := iv.Duration(); ok {
+		t.Fatal("expected a month-based interval not to convert exactly")
+	}
+	if d, ok := IntervalOf(time.Hour).Duration(); !ok || d != time.Hour {
+		t.Fatalf("expected exact conversion of 1h, got %v %v", d, ok)
+	}
+}
+
+func TestIntervalAddTo(t *testing.T) {
+	start := time.Date(2012, 1, 31, 0, 0, 0, 0, time.UTC)
+	got := NewInterval(0, 1, 1, time.Hour).AddTo(start)
+	// Feb 29, clamped from Feb 31, then a day.
+	expected := time.Date(2012, 3, 1, 1, 0, 0, 0, time.UTC)
+	if !got.Equal(expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+
+	for _, tt := range []struct {
+		start    time.Time
+		iv       Interval
+		expected time.Time
+	}{
+		{time.Date(2013, 1, 31, 0, 0, 0, 0, time.UTC), NewInterval(0, 1, 0, 0), time.Date(2013, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2012, 3, 31, 0, 0, 0, 0, time.UTC), NewInterval(0, -1, 0, 0), time.Date(2012, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2012, 12, 31, 12, 0, 0, 0, time.UTC), NewInterval(1, 2, 0, 0), time.Date(2014, 2, 28, 12, 0, 0, 0, time.UTC)},
+		{time.Date(2012, 1, 15, 0, 0, 0, 0, time.UTC), NewInterval(0, 1, 20, 0), time.Date(2012, 3, 6, 0, 0, 0, 0, time.UTC)},
+	} {
+		if got := tt.iv.AddTo(tt.start); !got.Equal(tt.expected) {
+			t.Errorf("%v + %v: got %v, expected %v", tt.start, tt.iv, got, tt.expected)
+		}
+	}
+
+	iv := NewInterval(0, 1, 2, time.Second)
+	if iv.Add(iv).Sub(iv) != iv || iv.Mul(2) != iv.Add(iv) {
+		t.Fatal("interval arithmetic is inconsistent")
+	}
+}
+
+func TestIntervalScanValue(t *testing.T) {
+	var iv Interval
+	if err := iv.Scan([]byte("1 day 00:00:01")); err != nil {
+		t.Fatal(err)
+	}
+	if iv != NewInterval(0, 0, 1, time.Second) {
+		t.Fatalf("unexpected interval %+v", iv)
+	}
+
+	v, err := iv.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != iv.String() {
+		t.Fatalf("expected %q, got %#v", iv.String(), v)
+	}
+
+	if err := iv.Scan(nil); err == nil {
+		t.Fatal("expected an error scanning NULL")
+	}
+}
+
+func TestDurationParam(t *testing.T) {
+	v, err := checkParam(90 * time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != IntervalOf(90*time.Minute).String() {
+		t.Fatalf("expected an interval literal, got %#v", v)
+	}
+
+	iv, err := ParseInterval(v.(string))
+	if err != nil || iv != IntervalOf(90*time.Minute) {
+		t.Fatalf("expected the literal to parse back, got %+v, %v", iv, err)
+	}
+}
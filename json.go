@@ -0,0 +1,86 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONText is the text of a json or jsonb value. It scans from those
+// columns as is, and is sent as text so the server parses it as JSON: a
+// plain []byte parameter would be taken for a bytea. A nil JSONText is
+// NULL.
+type JSONText json.RawMessage
+
+// Value implements driver.Valuer. It refuses text that isn't valid JSON.
+func (j JSONText) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	if !json.Valid(j) {
+		return nil, fmt.Errorf("pq: invalid JSON %q", []byte(j))
+	}
+	return string(j), nil
+}
+
+// Scan implements sql.Scanner.
+func (j *JSONText) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = append((*j)[:0], v...)
+	default:
+		return fmt.Errorf("pq: cannot scan %T into a JSONText", src)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, so a JSONText embeds in other
+// documents as the JSON it holds.
+func (j JSONText) MarshalJSON() ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *JSONText) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
+
+// Unmarshal decodes j into v.
+func (j JSONText) Unmarshal(v interface{}) error {
+	return json.Unmarshal(j, v)
+}
+
+// JSONB returns v as a jsonb parameter, marshaled with encoding/json: a
+// map[string]interface{} or a struct with json tags, say, to store as a
+// document. A nil v is NULL, while a nil map or pointer is the JSON null.
+//
+// As with Typed, the jsonb type applies to queries run directly on the
+// connection; a prepared statement takes the text as its parameter's
+// type, which for a json or jsonb column is the same.
+func JSONB(v interface{}) TypedParam {
+	return Typed(OidJSONB, jsonParam{v})
+}
+
+type jsonParam struct {
+	v interface{}
+}
+
+// Value implements driver.Valuer.
+func (j jsonParam) Value() (driver.Value, error) {
+	if j.v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, fmt.Errorf("pq: cannot marshal %T as JSON: %v", j.v, err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,79 @@
+package pq
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONText(t *testing.T) {
+	var j JSONText
+	if err := j.Scan([]byte(`{"a": [1, 2]}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct{ A []int }
+	if err := j.Unmarshal(&doc); err != nil || len(doc.A) != 2 {
+		t.Fatalf("unexpected document %+v, %v", doc, err)
+	}
+
+	v, err := j.Value()
+	if err != nil || v != `{"a": [1, 2]}` {
+		t.Fatalf("expected the text as a string, got %#v, %v", v, err)
+	}
+
+	b, err := json.Marshal(struct{ J JSONText }{j})
+	if err != nil || string(b) != `{"J":{"a":[1,2]}}` {
+		t.Fatalf("unexpected embedding %s, %v", b, err)
+	}
+
+	if err := j.Scan(nil); err != nil || j != nil {
+		t.Fatalf("expected NULL to scan as nil, got %q, %v", j, err)
+	}
+	if v, err := j.Value(); v != nil || err != nil {
+		t.Fatalf("expected nil to be NULL, got %#v, %v", v, err)
+	}
+
+	if _, err := JSONText(`{`).Value(); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+}
+
+func TestJSONColumns(t *testing.T) {
+	for _, typ := range []Oid{OidJSON, OidJSONB} {
+		v := decodeText(typ, []byte(`{"a":1}`))
+		if b, ok := v.([]byte); !ok || string(b) != `{"a":1}` {
+			t.Errorf("type %d: expected the raw text, got %#v", typ, v)
+		}
+	}
+}
+
+func TestJSONB(t *testing.T) {
+	type doc struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags,omitempty"`
+	}
+
+	tests := []struct {
+		in       interface{}
+		expected interface{}
+	}{
+		{map[string]interface{}{"a": 1, "b": []int{2}}, `{"a":1,"b":[2]}`},
+		{doc{Name: "x"}, `{"name":"x"}`},
+		{(*doc)(nil), "null"},
+		{nil, nil},
+	}
+	for _, test := range tests {
+		v, err := checkParam(JSONB(test.in))
+		if err != nil {
+			t.Fatalf("%#v: %v", test.in, err)
+		}
+		if v != Typed(OidJSONB, test.expected) {
+			t.Errorf("%#v: expected %#v, got %#v", test.in, test.expected, v)
+		}
+	}
+
+	if _, err := checkParam(JSONB(func() {})); err == nil || !strings.Contains(err.Error(), "cannot marshal func()") {
+		t.Fatalf("expected a marshaling error, got %v", err)
+	}
+}
@@ -0,0 +1,349 @@
+package pq
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ListenerEventType is the kind of a change to the connection of a
+// Listener.
+type ListenerEventType int
+
+const (
+	// ListenerEventConnected reports that the listener has connected
+	// again, and listens on its channels again. Notifications sent while
+	// it was disconnected are lost.
+	ListenerEventConnected ListenerEventType = iota
+
+	// ListenerEventDisconnected reports that the connection was lost,
+	// with the error that ended it.
+	ListenerEventDisconnected
+
+	// ListenerEventReconnectFailed reports a failed attempt to connect
+	// again, with its error. The listener keeps trying.
+	ListenerEventReconnectFailed
+)
+
+// Listener receives the notifications sent on the channels it listens on,
+// over a connection of its own that it keeps idle for them. When the
+// connection is lost, it connects again, waiting between attempts from a
+// minimum interval doubling up to a maximum, and listens on its channels
+// again.
+//
+// Read Notifications without stalling: the connection reads notifications
+// and the replies to Listen and Unlisten in order, so while one waits to
+// be delivered, those calls wait too. To manage the connection yourself,
+// use a ListenerConn.
+type Listener struct {
+	notify chan *Notification
+
+	dial                 func() (*Conn, error)
+	minReconnectInterval time.Duration
+	maxReconnectInterval time.Duration
+	eventCallback        func(ListenerEventType, error)
+
+	// mu guards the fields below, and serializes Listen, Unlisten and
+	// reconnections.
+	mu       sync.Mutex
+	lc       *ListenerConn // nil while disconnected
+	channels map[string]bool
+	closed   bool
+
+	// closing is closed by Close, and stopped once the listener has
+	// stopped reconnecting.
+	closeOnce sync.Once
+	closing   chan struct{}
+	stopped   chan struct{}
+}
+
+// NewListener opens a connection with the connection string name for a
+// Listener. After losing it, the listener waits minReconnectInterval
+// before connecting again, and doubles the wait after each failure up to
+// maxReconnectInterval. eventCallback, if not nil, is called with the
+// changes to the connection, one at a time: use it to learn when
+// notifications may have been missed.
+func NewListener(name string, minReconnectInterval, maxReconnectInterval time.Duration, eventCallback func(ev ListenerEventType, err error)) (*Listener, error) {
+	o, err := parseConnString(name)
+	if err != nil {
+		return nil, err
+	}
+	dial := func() (*Conn, error) {
+		return open(context.Background(), o, nil)
+	}
+	return newListener(dial, minReconnectInterval, maxReconnectInterval, eventCallback)
+}
+
+func newListener(dial func() (*Conn, error), minReconnectInterval, maxReconnectInterval time.Duration, eventCallback func(ListenerEventType, error)) (*Listener, error) {
+	cn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	if maxReconnectInterval < minReconnectInterval {
+		maxReconnectInterval = minReconnectInterval
+	}
+	l := &Listener{
+		notify:               make(chan *Notification, 32),
+		dial:                 dial,
+		minReconnectInterval: minReconnectInterval,
+		maxReconnectInterval: maxReconnectInterval,
+		eventCallback:        eventCallback,
+		channels:             make(map[string]bool),
+		closing:              make(chan struct{}),
+		stopped:              make(chan struct{}),
+	}
+	l.lc = newListenerConn(cn, l.notify, l.closing)
+	go l.run(l.lc)
+	return l, nil
+}
+
+// Notifications returns the channel the notifications are delivered on,
+// in the order the server sends them. It is closed once the listener is
+// closed.
+func (l *Listener) Notifications() <-chan *Notification {
+	return l.notify
+}
+
+// Listen starts listening on channel. Listening on a channel again does
+// nothing. While the listener is disconnected, Listen only records the
+// channel, to listen on once it has connected again.
+func (l *Listener) Listen(channel string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return errListenerClosed
+	}
+	if l.channels[channel] {
+		return nil
+	}
+	if err := l.exec("LISTEN " + QuoteIdentifier(channel)); err != nil {
+		return err
+	}
+	l.channels[channel] = true
+	return nil
+}
+
+// Unlisten stops listening on channel. Notifications already on their way
+// may still be delivered.
+func (l *Listener) Unlisten(channel string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return errListenerClosed
+	}
+	if !l.channels[channel] {
+		return nil
+	}
+	if err := l.exec("UNLISTEN " + QuoteIdentifier(channel)); err != nil {
+		return err
+	}
+	delete(l.channels, channel)
+	return nil
+}
+
+// UnlistenAll stops listening on every channel, in one round trip.
+func (l *Listener) UnlistenAll() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return errListenerClosed
+	}
+	if err := l.exec("UNLISTEN *"); err != nil {
+		return err
+	}
+	l.channels = make(map[string]bool)
+	return nil
+}
+
+// errListenerDisconnected is the error of a Ping while the listener is
+// reconnecting.
+var errListenerDisconnected = errf("listener is disconnected")
+
+// Ping checks that the connection of the listener is alive with a round
+// trip to the server. It fails while the listener is reconnecting.
+func (l *Listener) Ping() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return errListenerClosed
+	}
+	if l.lc == nil {
+		return errListenerDisconnected
+	}
+	return l.lc.Ping()
+}
+
+// ErrNotificationTimeout is the error of a NotificationTimeout that timed
+// out.
+var ErrNotificationTimeout = errf("timed out waiting for a notification")
+
+// NotificationTimeout waits up to timeout for the next notification. It
+// pings the server every pingInterval meanwhile, unless pingInterval is
+// 0, to find out about a connection lost without a word from the server,
+// and returns the error if a ping fails: the listener is then
+// reconnecting, and notifications may be missed. It returns
+// ErrNotificationTimeout if none arrives in time.
+func (l *Listener) NotificationTimeout(timeout, pingInterval time.Duration) (*Notification, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	var tick <-chan time.Time
+	if pingInterval > 0 {
+		t := time.NewTicker(pingInterval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	// pinged receives the result of the ping in flight, if any. The
+	// reply to a ping follows the notifications sent before it, so they
+	// are still received meanwhile.
+	var pinged chan error
+	for {
+		select {
+		case n, ok := <-l.notify:
+			if !ok {
+				return nil, errListenerClosed
+			}
+			return n, nil
+		case <-deadline.C:
+			return nil, ErrNotificationTimeout
+		case <-tick:
+			if pinged == nil {
+				pinged = make(chan error, 1)
+				go func(c chan<- error) {
+					c <- l.Ping()
+				}(pinged)
+			}
+		case err := <-pinged:
+			pinged = nil
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// exec runs q on the connection, if there is one. Losing the connection
+// isn't an error: the channels are listened on again after reconnecting.
+func (l *Listener) exec(q string) error {
+	if l.lc == nil {
+		return nil
+	}
+	err := l.lc.exec(q)
+	if err != nil && l.lc.lost() {
+		return nil
+	}
+	return err
+}
+
+// Close closes the connection of the listener, and stops it from
+// reconnecting. Notifications is closed once it has stopped.
+func (l *Listener) Close() error {
+	// Closing closing first stops the connection waiting to deliver a
+	// notification, which holds up the command of any Listen holding mu.
+	l.closeOnce.Do(func() {
+		close(l.closing)
+	})
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	var err error
+	if l.lc != nil {
+		err = l.lc.Close()
+	}
+	l.mu.Unlock()
+
+	<-l.stopped
+	return err
+}
+
+// run waits for the connection lc to be lost, and replaces it, until the
+// listener is closed.
+func (l *Listener) run(lc *ListenerConn) {
+	defer close(l.stopped)
+	defer close(l.notify)
+
+	for {
+		select {
+		case <-lc.Done():
+		case <-l.closing:
+			<-lc.Done()
+			return
+		}
+
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			return
+		}
+		l.lc = nil
+		l.mu.Unlock()
+		l.event(ListenerEventDisconnected, lc.Err())
+
+		if lc = l.reconnect(); lc == nil {
+			return
+		}
+		l.event(ListenerEventConnected, nil)
+	}
+}
+
+// reconnect connects until it succeeds, and returns the new connection,
+// or nil if the listener is closed first.
+func (l *Listener) reconnect() *ListenerConn {
+	interval := l.minReconnectInterval
+	for {
+		select {
+		case <-time.After(interval):
+		case <-l.closing:
+			return nil
+		}
+		if interval *= 2; interval > l.maxReconnectInterval {
+			interval = l.maxReconnectInterval
+		}
+
+		lc, err := l.connect()
+		if err != nil {
+			l.event(ListenerEventReconnectFailed, err)
+			continue
+		}
+		return lc
+	}
+}
+
+// connect opens a connection, and listens on the channels on it. It
+// returns nil, and no error, if the listener is closed meanwhile.
+func (l *Listener) connect() (*ListenerConn, error) {
+	cn, err := l.dial()
+	if err != nil {
+		return nil, err
+	}
+	lc := newListenerConn(cn, l.notify, l.closing)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		lc.Close()
+		return nil, nil
+	}
+	for channel := range l.channels {
+		if err := lc.Listen(channel); err != nil {
+			lc.Close()
+			return nil, err
+		}
+	}
+	l.lc = lc
+	return lc, nil
+}
+
+func (l *Listener) event(ev ListenerEventType, err error) {
+	if l.eventCallback != nil {
+		l.eventCallback(ev, err)
+	}
+}
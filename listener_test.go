@@ -0,0 +1,323 @@
+package pq
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer plays the server on the other end of a pipe.
+type testServer struct {
+	t *testing.T
+	c net.Conn
+}
+
+// testDialer opens pipes for a Listener, sending the server ends on
+// servers, or fails with err if it is set.
+type testDialer struct {
+	t       *testing.T
+	servers chan *testServer
+
+	mu  sync.Mutex
+	err error
+}
+
+func (d *testDialer) dial() (*Conn, error) {
+	d.mu.Lock()
+	err := d.err
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	client, server := net.Pipe()
+	d.servers <- &testServer{t: d.t, c: server}
+	return &Conn{c: client, msg: newMsg()}, nil
+}
+
+func (d *testDialer) fail(err error) {
+	d.mu.Lock()
+	d.err = err
+	d.mu.Unlock()
+}
+
+func newTestListener(t *testing.T, eventCallback func(ListenerEventType, error)) (*Listener, *testDialer, *testServer) {
+	d := &testDialer{t: t, servers: make(chan *testServer, 1)}
+	l, err := newListener(d.dial, time.Millisecond, 4*time.Millisecond, eventCallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, d, <-d.servers
+}
+
+// expect reads a message, which must be of type typ, and returns its body.
+func (s *testServer) expect(typ int8) string {
+	m := newMsg()
+	m.readFrom(s.c)
+	if m.T != typ {
+		s.t.Errorf("expected '%c' from the client, got '%c'", typ, m.T)
+	}
+	return m.b.String()
+}
+
+func (s *testServer) send(msgs ...testMsg) {
+	if _, err := s.c.Write(serverMsgs(msgs...)); err != nil {
+		s.t.Error(err)
+	}
+}
+
+// reply answers a command of the client with tag, or the error message
+// errMsg if it isn't empty.
+func (s *testServer) reply(q, tag, errMsg string) {
+	if body := s.expect('Q'); body != q+"\000" {
+		s.t.Errorf("expected the query %q, got %q", q, body)
+	}
+	if errMsg != "" {
+		s.send(testMsg{'E', []interface{}{byte('S'), "ERROR", byte('M'), errMsg, byte(0)}})
+	} else {
+		s.send(testMsg{'C', []interface{}{tag}})
+	}
+	s.send(testMsg{'Z', []interface{}{byte('I')}})
+}
+
+func recvNotification(t *testing.T, l *Listener) *Notification {
+	select {
+	case n := <-l.Notifications():
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a notification")
+	}
+	return nil
+}
+
+func TestListener(t *testing.T) {
+	l, _, s := newTestListener(t, nil)
+
+	go s.reply(`LISTEN "my chan"`, "LISTEN", "")
+	if err := l.Listen("my chan"); err != nil {
+		t.Fatal(err)
+	}
+	// Listening again sends nothing.
+	if err := l.Listen("my chan"); err != nil {
+		t.Fatal(err)
+	}
+
+	go s.send(testMsg{'A', []interface{}{int32(42), "my chan", "hello"}})
+	n := recvNotification(t, l)
+	if *n != (Notification{PID: 42, Channel: "my chan", Payload: "hello"}) {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+
+	go s.reply(`LISTEN "bad"`, "", "permission denied")
+	if err := l.Listen("bad"); err == nil {
+		t.Fatal("expected the server's error")
+	}
+
+	go s.reply(`UNLISTEN "my chan"`, "UNLISTEN", "")
+	if err := l.Unlisten("my chan"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlisten("my chan"); err != nil {
+		t.Fatal(err)
+	}
+
+	go s.expect('X')
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := <-l.Notifications(); ok {
+		t.Fatalf("expected the channel to be closed, got %+v", n)
+	}
+	if err := l.Listen("other"); err != errListenerClosed {
+		t.Fatalf("expected errListenerClosed, got %v", err)
+	}
+}
+
+type testEvent struct {
+	ev  ListenerEventType
+	err error
+}
+
+func TestListenerReconnect(t *testing.T) {
+	events := make(chan testEvent, 1000)
+	l, d, s := newTestListener(t, func(ev ListenerEventType, err error) {
+		events <- testEvent{ev, err}
+	})
+	defer l.Close()
+
+	go s.reply(`LISTEN "a"`, "LISTEN", "")
+	if err := l.Listen("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	fail := errors.New("connection refused")
+	d.fail(fail)
+	s.c.Close()
+	if e := <-events; e.ev != ListenerEventDisconnected || e.err == nil {
+		t.Fatalf("expected a disconnection, got %+v", e)
+	}
+	if e := <-events; e.ev != ListenerEventReconnectFailed || e.err != fail {
+		t.Fatalf("expected a failed reconnection, got %+v", e)
+	}
+
+	// Recorded while disconnected, and listened on after reconnecting.
+	if err := l.Listen("b"); err != nil {
+		t.Fatal(err)
+	}
+	d.fail(nil)
+	s = <-d.servers
+	var queries []string
+	for i := 0; i < 2; i++ {
+		q := s.expect('Q')
+		queries = append(queries, q)
+		s.send(testMsg{'C', []interface{}{"LISTEN"}}, testMsg{'Z', []interface{}{byte('I')}})
+	}
+	sort.Strings(queries)
+	if queries[0] != "LISTEN \"a\"\000" || queries[1] != "LISTEN \"b\"\000" {
+		t.Fatalf("expected the channels to be listened on again, got %q", queries)
+	}
+	for e := range events {
+		if e.ev == ListenerEventConnected {
+			break
+		}
+		if e.ev != ListenerEventReconnectFailed {
+			t.Fatalf("expected a reconnection, got %+v", e)
+		}
+	}
+
+	go s.send(testMsg{'A', []interface{}{int32(1), "b", ""}})
+	if n := recvNotification(t, l); n.Channel != "b" {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+
+	go s.expect('X')
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-l.Notifications(); ok {
+		t.Fatal("expected the channel to be closed")
+	}
+}
+
+func TestListenerCloseWhileDisconnected(t *testing.T) {
+	l, d, s := newTestListener(t, nil)
+	d.fail(errors.New("connection refused"))
+	s.c.Close()
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-l.Notifications(); ok {
+		t.Fatal("expected the channel to be closed")
+	}
+	if err := l.Listen("chan"); err != errListenerClosed {
+		t.Fatalf("expected errListenerClosed, got %v", err)
+	}
+}
+
+func TestListenerCloseUndelivered(t *testing.T) {
+	l, _, s := newTestListener(t, nil)
+
+	// Fill Notifications, and leave one more waiting to be delivered,
+	// which holds up the reply to the Listen.
+	for i := 0; i < cap(l.notify)+1; i++ {
+		s.send(testMsg{'A', []interface{}{int32(1), "chan", "x"}})
+	}
+	listened := make(chan error, 1)
+	go func() {
+		listened <- l.Listen("chan")
+	}()
+	s.expect('Q')
+	go io.Copy(ioutil.Discard, s.c)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- l.Close()
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close is stuck behind the undelivered notification")
+	}
+	if err := <-listened; err != errListenerClosed {
+		t.Fatalf("expected errListenerClosed, got %v", err)
+	}
+}
+
+func TestListenerPingAndUnlistenAll(t *testing.T) {
+	l, d, s := newTestListener(t, nil)
+
+	go func() {
+		s.expect('Q')
+		s.send(testMsg{'I', nil}, testMsg{'Z', []interface{}{byte('I')}})
+	}()
+	if err := l.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for _, q := range []string{`LISTEN "a"`, `LISTEN "b"`, `UNLISTEN *`} {
+			s.reply(q, strings.Fields(q)[0], "")
+		}
+	}()
+	for _, c := range []string{"a", "b"} {
+		if err := l.Listen(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.UnlistenAll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.channels) != 0 {
+		t.Fatalf("expected no channels left, got %v", l.channels)
+	}
+
+	d.fail(errors.New("connection refused"))
+	s.c.Close()
+	for l.Ping() == nil {
+		time.Sleep(time.Millisecond)
+	}
+	l.Close()
+	if err := l.Ping(); err != errListenerClosed {
+		t.Fatalf("expected errListenerClosed, got %v", err)
+	}
+}
+
+func TestListenerNotificationTimeout(t *testing.T) {
+	l, d, s := newTestListener(t, nil)
+
+	if _, err := l.NotificationTimeout(time.Millisecond, 0); err != ErrNotificationTimeout {
+		t.Fatalf("expected ErrNotificationTimeout, got %v", err)
+	}
+
+	// A ping, then the notification.
+	go func() {
+		s.expect('Q')
+		s.send(testMsg{'I', nil}, testMsg{'Z', []interface{}{byte('I')}})
+		s.send(testMsg{'A', []interface{}{int32(1), "a", "x"}})
+	}()
+	n, err := l.NotificationTimeout(5*time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Payload != "x" {
+		t.Fatalf("unexpected notification %+v", n)
+	}
+
+	// A lost connection fails the pings.
+	d.fail(errors.New("connection refused"))
+	s.c.Close()
+	if _, err := l.NotificationTimeout(5*time.Second, time.Millisecond); err == nil || err == ErrNotificationTimeout {
+		t.Fatalf("expected a failed ping, got %v", err)
+	}
+
+	l.Close()
+	if _, err := l.NotificationTimeout(time.Second, 0); err != errListenerClosed {
+		t.Fatalf("expected errListenerClosed, got %v", err)
+	}
+}
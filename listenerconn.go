@@ -0,0 +1,221 @@
+package pq
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// errListenerClosed is the error of the commands of a closed listener or
+// ListenerConn.
+var errListenerClosed = errf("listener has been closed")
+
+// ListenerConn is a connection dedicated to notifications, which it reads
+// as they arrive and sends on the channel it was opened with. It is the
+// connection of a Listener, without the reconnection and the bookkeeping
+// of channels: use it to wait for notifications in a select loop of your
+// own, alongside Done, and to decide what to do when the connection is
+// lost.
+//
+// Receive from the channel without stalling: the connection reads
+// notifications and the replies to commands in order, so while one waits
+// to be delivered, the commands wait too.
+type ListenerConn struct {
+	cn     *Conn
+	notify chan<- *Notification
+
+	// mu serializes commands: their replies are told apart by order.
+	mu      sync.Mutex
+	replies chan error
+
+	// closed is set once Close is called, and closing closed then. It
+	// stops recv waiting to deliver a notification nobody receives, as
+	// does stop, closed by the Listener owning the connection as it
+	// closes, if any.
+	closed  int32
+	closing chan struct{}
+	stop    <-chan struct{}
+
+	// done is closed once the connection is over, and err then tells why.
+	done chan struct{}
+	err  error
+}
+
+// NewListenerConn opens a connection with the connection string name, and
+// sends the notifications that arrive on it on notify, which it never
+// closes.
+func NewListenerConn(name string, notify chan<- *Notification) (*ListenerConn, error) {
+	cn, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return newListenerConn(cn, notify, nil), nil
+}
+
+func newListenerConn(cn *Conn, notify chan<- *Notification, stop <-chan struct{}) *ListenerConn {
+	lc := &ListenerConn{
+		cn:      cn,
+		notify:  notify,
+		replies: make(chan error, 1),
+		closing: make(chan struct{}),
+		stop:    stop,
+		done:    make(chan struct{}),
+	}
+	go lc.recvLoop()
+	return lc
+}
+
+// exec runs q, a command returning no rows.
+func (lc *ListenerConn) exec(q string) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	select {
+	case <-lc.done:
+		return lc.err
+	default:
+	}
+
+	if err := lc.send('Q', q); err != nil {
+		lc.cn.c.Close()
+		<-lc.done
+		return err
+	}
+	select {
+	case err := <-lc.replies:
+		return err
+	case <-lc.done:
+		return lc.err
+	}
+}
+
+// send sends a message of type t. The message buffer of the connection
+// belongs to the reader, so it has one of its own.
+func (lc *ListenerConn) send(t int8, body ...interface{}) (err error) {
+	defer recoverErr(&err)
+	m := newMsg()
+	m.setHead(t)
+	m.write(body...)
+	m.writeTo(lc.cn.c)
+	return nil
+}
+
+// Listen starts listening on channel.
+func (lc *ListenerConn) Listen(channel string) error {
+	return lc.exec("LISTEN " + QuoteIdentifier(channel))
+}
+
+// Unlisten stops listening on channel.
+func (lc *ListenerConn) Unlisten(channel string) error {
+	return lc.exec("UNLISTEN " + QuoteIdentifier(channel))
+}
+
+// UnlistenAll stops listening on every channel.
+func (lc *ListenerConn) UnlistenAll() error {
+	return lc.exec("UNLISTEN *")
+}
+
+// Ping checks that the connection is alive with a round trip to the
+// server.
+func (lc *ListenerConn) Ping() error {
+	// An empty query, which the server only acknowledges.
+	return lc.exec("")
+}
+
+// BackendPID returns the process ID of the backend serving the
+// connection.
+func (lc *ListenerConn) BackendPID() int32 {
+	return lc.cn.BackendPID()
+}
+
+// Done returns a channel that is closed once the connection is over,
+// closed or lost.
+func (lc *ListenerConn) Done() <-chan struct{} {
+	return lc.done
+}
+
+// Err returns why the connection is over, once Done is closed: the error
+// it was lost with, or an error saying it was closed. It returns nil
+// before.
+func (lc *ListenerConn) Err() error {
+	select {
+	case <-lc.done:
+		return lc.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the session, and waits for the connection to be over.
+func (lc *ListenerConn) Close() error {
+	if !atomic.CompareAndSwapInt32(&lc.closed, 0, 1) {
+		return nil
+	}
+	close(lc.closing)
+
+	lc.mu.Lock()
+	lc.send('X')
+	lc.mu.Unlock()
+
+	err := lc.cn.c.Close()
+	<-lc.done
+	return err
+}
+
+// lost reports whether the connection was lost, rather than closed.
+func (lc *ListenerConn) lost() bool {
+	select {
+	case <-lc.done:
+		return lc.err != errListenerClosed
+	default:
+		return false
+	}
+}
+
+// recvLoop reads the messages of the connection until it is over.
+func (lc *ListenerConn) recvLoop() {
+	err := lc.recv()
+	if atomic.LoadInt32(&lc.closed) != 0 {
+		err = errListenerClosed
+	}
+	lc.err = err
+	close(lc.done)
+}
+
+// recv delivers the notifications, and the result of each command once it
+// is ready for the next one, until reading fails.
+func (lc *ListenerConn) recv() (err error) {
+	defer recoverErr(&err)
+
+	cn := lc.cn
+	var res error
+	for {
+		cn.readFrom(cn.c)
+		switch cn.T {
+		case 'A':
+			if n := cn.readNotification(); n != nil {
+				select {
+				case lc.notify <- n:
+				case <-lc.closing:
+					return errListenerClosed
+				case <-lc.stop:
+					return errListenerClosed
+				}
+			}
+		case 'N':
+			cn.recvNotice()
+		case 'S':
+			cn.recvParameterStatus()
+		case 'E':
+			res = readError(cn)
+			if isFatal(res) {
+				panic(res)
+			}
+		case 'C', 'I':
+		case 'Z':
+			lc.replies <- res
+			res = nil
+		default:
+			panic(cn.unexpected("LISTEN"))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package pq
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListenerConn(t *testing.T) {
+	client, server := net.Pipe()
+	s := &testServer{t: t, c: server}
+	notify := make(chan *Notification)
+	lc := newListenerConn(&Conn{c: client, msg: newMsg(), pid: 99}, notify, nil)
+
+	if lc.BackendPID() != 99 {
+		t.Fatalf("expected the backend's pid, got %d", lc.BackendPID())
+	}
+	go s.reply(`LISTEN "a"`, "LISTEN", "")
+	if err := lc.Listen("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	go s.send(testMsg{'A', []interface{}{int32(1), "a", "x"}})
+	select {
+	case n := <-notify:
+		if n.Payload != "x" {
+			t.Fatalf("unexpected notification %+v", n)
+		}
+	case <-lc.Done():
+		t.Fatal(lc.Err())
+	}
+	if lc.Err() != nil {
+		t.Fatalf("expected no error while connected, got %v", lc.Err())
+	}
+
+	go s.reply(`UNLISTEN *`, "UNLISTEN", "")
+	if err := lc.UnlistenAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.c.Close()
+	<-lc.Done()
+	if err := lc.Err(); err == nil || err == errListenerClosed {
+		t.Fatalf("expected the error the connection was lost with, got %v", err)
+	}
+	if err := lc.Ping(); err != lc.Err() {
+		t.Fatalf("expected Ping to fail with %v, got %v", lc.Err(), err)
+	}
+	lc.Close()
+	if err := lc.Err(); err == errListenerClosed {
+		t.Fatal("expected Close to leave the error the connection was lost with")
+	}
+}
+
+func TestListenerConnCloseUndelivered(t *testing.T) {
+	client, server := net.Pipe()
+	s := &testServer{t: t, c: server}
+	lc := newListenerConn(&Conn{c: client, msg: newMsg()}, make(chan *Notification), nil)
+
+	// Nobody receives the notification, so the connection waits to
+	// deliver it.
+	s.send(testMsg{'A', []interface{}{int32(1), "a", "x"}})
+	go io.Copy(ioutil.Discard, server)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- lc.Close()
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close is stuck behind the undelivered notification")
+	}
+	if err := lc.Err(); err != errListenerClosed {
+		t.Fatalf("expected errListenerClosed, got %v", err)
+	}
+}
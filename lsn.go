@@ -0,0 +1,53 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LSN is a pg_lsn, a position in the write-ahead log. pg_lsn columns
+// decode to an LSN.
+type LSN uint64
+
+// ParseLSN parses the text form of an LSN, two hexadecimal numbers
+// separated by a slash such as 16/B374D848: the high and low 32 bits.
+func ParseLSN(s string) (LSN, error) {
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return 0, errf("invalid LSN %q", s)
+	}
+	hi, err1 := strconv.ParseUint(s[:i], 16, 32)
+	lo, err2 := strconv.ParseUint(s[i+1:], 16, 32)
+	if err1 != nil || err2 != nil {
+		return 0, errf("invalid LSN %q", s)
+	}
+	return LSN(hi<<32 | lo), nil
+}
+
+// String returns the text form of l.
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint64(l)>>32, uint32(l))
+}
+
+// Scan implements sql.Scanner, accepting an LSN or its text form.
+func (l *LSN) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case LSN:
+		*l = v
+	case []byte:
+		*l, err = ParseLSN(string(v))
+	case string:
+		*l, err = ParseLSN(v)
+	default:
+		err = fmt.Errorf("pq: cannot scan %T into an LSN", src)
+	}
+	return err
+}
+
+// Value implements driver.Valuer, sending l in its text form.
+func (l LSN) Value() (driver.Value, error) {
+	return l.String(), nil
+}
@@ -0,0 +1,48 @@
+package pq
+
+import (
+	"testing"
+)
+
+func TestLSN(t *testing.T) {
+	l, err := ParseLSN("16/B374D848")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l != 0x16B374D848 {
+		t.Fatalf("expected 0x16B374D848, got %#x", uint64(l))
+	}
+	if s := l.String(); s != "16/B374D848" {
+		t.Fatalf("expected 16/B374D848, got %s", s)
+	}
+	if s := LSN(0).String(); s != "0/0" {
+		t.Fatalf("expected 0/0, got %s", s)
+	}
+
+	for _, in := range []string{"", "16", "16/", "/1", "x/1", "100000000/0"} {
+		if _, err := ParseLSN(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+
+	if v := decodeText(OidPgLSN, []byte("0/1")); v != LSN(1) {
+		t.Fatalf("expected LSN(1), got %#v", v)
+	}
+	if v := decodeBinary(OidPgLSN, []byte{0, 0, 0, 0x16, 0xb3, 0x74, 0xd8, 0x48}); v != l {
+		t.Fatalf("expected %v, got %#v", l, v)
+	}
+
+	var got LSN
+	if err := got.Scan(l); err != nil || got != l {
+		t.Fatalf("unexpected %v, %v", got, err)
+	}
+	if err := got.Scan([]byte("0/2")); err != nil || got != 2 {
+		t.Fatalf("unexpected %v, %v", got, err)
+	}
+	if err := got.Scan(nil); err == nil {
+		t.Fatal("expected an error scanning NULL")
+	}
+	if v, err := checkParam(l); err != nil || v != "16/B374D848" {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
@@ -0,0 +1,79 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ltree is a value of the ltree extension's type, a path of labels such as
+// Top.Science.Astronomy. ltree columns decode to their text, which scans
+// into a string; scan them into an Ltree to get the labels, and ltree[]
+// columns into a []Ltree through Array.
+type Ltree []string
+
+// maxLtreeLabel is the longest label, in characters, the server accepts.
+const maxLtreeLabel = 1000
+
+// Scan implements sql.Scanner. NULL scans as a nil Ltree.
+func (l *Ltree) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return errf("cannot scan %T into an Ltree", src)
+	}
+	if s == "" {
+		*l = Ltree{}
+		return nil
+	}
+	labels := Ltree(strings.Split(s, "."))
+	if err := labels.validate(); err != nil {
+		return err
+	}
+	*l = labels
+	return nil
+}
+
+// Value implements driver.Valuer, checking the labels are ones the server
+// accepts. A nil Ltree is NULL.
+func (l Ltree) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	if err := l.validate(); err != nil {
+		return nil, err
+	}
+	return l.String(), nil
+}
+
+// String returns the text form of l.
+func (l Ltree) String() string {
+	return strings.Join(l, ".")
+}
+
+// validate checks each label is of letters, digits, underscores and
+// hyphens, and neither empty nor too long.
+func (l Ltree) validate() error {
+	for _, label := range l {
+		if label == "" {
+			return errf("invalid ltree %q: empty label", l.String())
+		}
+		if utf8.RuneCountInString(label) > maxLtreeLabel {
+			return errf("invalid ltree label %q: longer than %d characters", label, maxLtreeLabel)
+		}
+		for _, r := range label {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+				return errf("invalid ltree label %q", label)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package pq
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLtree(t *testing.T) {
+	var l Ltree
+	if err := l.Scan([]byte("Top.Science.Astro_nomy-1")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(l, Ltree{"Top", "Science", "Astro_nomy-1"}) {
+		t.Fatalf("unexpected %#v", l)
+	}
+	if v, err := l.Value(); err != nil || v != "Top.Science.Astro_nomy-1" {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	if err := l.Scan(""); err != nil || l == nil || len(l) != 0 {
+		t.Fatalf("unexpected %#v, %v", l, err)
+	}
+	if v, err := l.Value(); err != nil || v != "" {
+		t.Fatalf("unexpected %#v, %v", v, err)
+	}
+	if err := l.Scan(nil); err != nil || l != nil {
+		t.Fatalf("unexpected %#v, %v", l, err)
+	}
+	if v, err := l.Value(); err != nil || v != nil {
+		t.Fatalf("unexpected %#v, %v", v, err)
+	}
+
+	for _, bad := range []Ltree{{"a", ""}, {"a b"}, {"a.b"}, {strings.Repeat("x", 1001)}} {
+		if _, err := bad.Value(); err == nil {
+			t.Errorf("%q: expected an error", []string(bad))
+		}
+	}
+	if err := l.Scan("a..b"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestLtreeArray(t *testing.T) {
+	v, err := Array([]Ltree{{"a", "b"}, nil, {}}).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != `{"a.b",NULL,""}` {
+		t.Fatalf("unexpected %v", v)
+	}
+
+	var got []Ltree
+	if err := Array(&got).Scan([]byte(`{a.b,NULL,""}`)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []Ltree{{"a", "b"}, nil, {}}) {
+		t.Fatalf("unexpected %#v", got)
+	}
+}
@@ -0,0 +1,41 @@
+package pq
+
+// SetNoticeHandler installs h to receive the notices (RAISE NOTICE output,
+// warnings and the like) the server sends on the connections opened
+// through cr, including those already open, unless they have a handler of
+// their own. Notices are dropped when no handler is set. It is safe to
+// call while the connections are in use.
+func (cr *Connector) SetNoticeHandler(h func(*Error)) {
+	cr.noticeHandler.Store(h)
+}
+
+// SetNoticeHandler installs h to receive the notices the server sends on
+// cn, in place of the handler of its Connector. A nil h goes back to the
+// Connector's.
+func (cn *Conn) SetNoticeHandler(h func(*Error)) {
+	cn.noticeHandler = h
+}
+
+// noticeFunc returns the handler of the notices of cn: its own, or else
+// the one its Connector has at the moment, or nil.
+func (cn *Conn) noticeFunc() func(*Error) {
+	if cn.noticeHandler != nil || cn.connector == nil {
+		return cn.noticeHandler
+	}
+	h, _ := cn.connector.noticeHandler.Load().(func(*Error))
+	return h
+}
+
+// recvNotice reads the NoticeResponse in the message buffer and hands it
+// to the notice handler.
+func (cn *Conn) recvNotice() {
+	err := readError(cn)
+	n, ok := err.(*Error)
+	if !ok {
+		panic(err)
+	}
+
+	if h := cn.noticeFunc(); h != nil {
+		h(n)
+	}
+}
@@ -0,0 +1,88 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestNoticeBetweenRows(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		{'N', []interface{}{byte('M'), "first", byte(0)}},
+		{'D', []interface{}{int16(1), int32(1), []byte("x")}},
+		{'N', []interface{}{byte('M'), "second", byte(0)}},
+		{'C', []interface{}{"SELECT 1"}},
+		{'Z', []interface{}{byte('I')}},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	var notices []string
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg(), state: stateExtendedQuery}
+	cn.SetNoticeHandler(func(n *Error) {
+		notices = append(notices, n.Message)
+	})
+
+	r := &rows{Conn: cn, fields: []fieldDesc{{name: "a"}}}
+	dest := make([]driver.Value, 1)
+	n := 0
+	for r.Next(dest) == nil {
+		n++
+	}
+
+	if n != 1 || !r.done {
+		t.Fatalf("expected 1 row and the end of the result, got %d rows", n)
+	}
+
+	if len(notices) != 2 || notices[0] != "first" || notices[1] != "second" {
+		t.Fatalf("unexpected notices %q", notices)
+	}
+}
+
+func TestConnectorNoticeHandler(t *testing.T) {
+	cr, err := NewConnector("host=localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'N', []interface{}{byte('M'), "first", byte(0)}},
+		testMsg{'N', []interface{}{byte('M'), "second", byte(0)}},
+		testMsg{'N', []interface{}{byte('M'), "third", byte(0)}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg(), connector: cr}
+
+	recv := func() {
+		cn.readFrom(cn.c)
+		cn.recvNotice()
+	}
+
+	// Set once the connection is open, and still reaching it.
+	var fromConnector, fromConn []string
+	cr.SetNoticeHandler(func(n *Error) {
+		fromConnector = append(fromConnector, n.Message)
+	})
+	recv()
+
+	cn.SetNoticeHandler(func(n *Error) {
+		fromConn = append(fromConn, n.Message)
+	})
+	recv()
+
+	cn.SetNoticeHandler(nil)
+	recv()
+
+	if len(fromConnector) != 2 || fromConnector[0] != "first" || fromConnector[1] != "third" {
+		t.Fatalf("unexpected notices through the Connector %q", fromConnector)
+	}
+	if len(fromConn) != 1 || fromConn[0] != "second" {
+		t.Fatalf("unexpected notices through the Conn %q", fromConn)
+	}
+}
@@ -0,0 +1,139 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+const (
+	// maxPayloadLen is the longest payload the server accepts in NOTIFY.
+	maxPayloadLen = 7999
+
+	// maxNotificationLen bounds a whole NotificationResponse body: the
+	// sender's pid, a channel name of at most NAMEDATALEN-1 bytes, the
+	// payload, and their terminators.
+	maxNotificationLen = 4 + 63 + 1 + maxPayloadLen + 1
+)
+
+// Notify sends a notification on channel. Payloads the server would reject
+// are refused before anything is sent, so the error can't arrive in the
+// middle of other traffic.
+func (cn *Conn) Notify(channel, payload string) error {
+	if len(payload) > maxPayloadLen {
+		return errf("notification payload of %d bytes exceeds the %d byte limit", len(payload), maxPayloadLen)
+	}
+
+	_, err := cn.Exec("SELECT pg_notify($1, $2)", []driver.Value{channel, payload})
+	return err
+}
+
+// BackendPID returns the process ID of the backend serving cn.
+func (cn *Conn) BackendPID() int32 {
+	return cn.pid
+}
+
+// Notification is a notification received from the server.
+type Notification struct {
+	// PID is the process ID of the backend that sent the notification:
+	// that of the listening connection itself, as BackendPID reports it,
+	// for the notifications it sent.
+	PID int32
+
+	// Channel is the channel the notification was sent on.
+	Channel string
+
+	// Payload is the payload given to NOTIFY, "" if none was.
+	Payload string
+}
+
+// readNotification reads the NotificationResponse in the message buffer.
+// It returns nil for a notification over the limits, which is dropped
+// with a warning to the notice handler instead of failing the connection.
+func (cn *Conn) readNotification() *Notification {
+	if cn.L-4 > maxNotificationLen {
+		// readFrom skipped its body.
+		cn.dropNotification(fmt.Sprintf("notification of %d bytes exceeds the %d byte limit", cn.L-4, maxNotificationLen))
+		return nil
+	}
+
+	n := new(Notification)
+	cn.read(&n.PID)
+	n.Channel = cn.readCString()
+	n.Payload = cn.readCString()
+	if len(n.Payload) > maxPayloadLen {
+		cn.dropNotification(fmt.Sprintf("notification payload of %d bytes on channel %q exceeds the %d byte limit", len(n.Payload), n.Channel, maxPayloadLen))
+		return nil
+	}
+	return n
+}
+
+// dropNotification reports a notification dropped for reason to the
+// notice handler, the only place it can be heard of.
+func (cn *Conn) dropNotification(reason string) {
+	if h := cn.noticeFunc(); h != nil {
+		h(&Error{Severity: "WARNING", Message: "dropped a notification: " + reason})
+	}
+}
+
+// SetNotificationHandler installs h to receive the notifications that
+// arrive on the connections opened through cr, including those already
+// open, unless they have a handler of their own: those for the channels
+// they LISTEN on, in between the results of their queries. Notifications
+// are dropped when no handler is set. It is safe to call while the
+// connections are in use.
+func (cr *Connector) SetNotificationHandler(h func(*Notification)) {
+	cr.notificationHandler.Store(h)
+}
+
+// SetListenChannels makes the connections opened through cr LISTEN on
+// channels as they open, and again after a session_reset clears their
+// registrations; connections already open switch to channels at their next
+// reset. With a handler set by SetNotificationHandler, the connections of a
+// sql.DB pool then all receive the notifications, to invalidate a cache,
+// say. A connection only reads them along with the replies to its
+// commands, so those sent while it sits idle in the pool arrive when it is
+// next used. It is safe to call while the connections are in use.
+func (cr *Connector) SetListenChannels(channels ...string) {
+	cr.listenChannels.Store(append([]string(nil), channels...))
+}
+
+// listenQuery returns the LISTENs of the channels set with
+// SetListenChannels on the Connector of cn, if any.
+func (cn *Conn) listenQuery() string {
+	if cn.connector == nil {
+		return ""
+	}
+	channels, _ := cn.connector.listenChannels.Load().([]string)
+	var q string
+	for _, c := range channels {
+		q += "LISTEN " + QuoteIdentifier(c) + ";"
+	}
+	return q
+}
+
+// SetNotificationHandler installs h to receive the notifications that
+// arrive on cn, in place of the handler of its Connector; a nil h goes
+// back to the Connector's. They are only read along with the replies to
+// commands: a connection kept idle to wait for them is a Listener's job.
+func (cn *Conn) SetNotificationHandler(h func(*Notification)) {
+	cn.notificationHandler = h
+}
+
+// notificationFunc returns the handler of the notifications of cn: its
+// own, or else the one its Connector has at the moment, or nil.
+func (cn *Conn) notificationFunc() func(*Notification) {
+	if cn.notificationHandler != nil || cn.connector == nil {
+		return cn.notificationHandler
+	}
+	h, _ := cn.connector.notificationHandler.Load().(func(*Notification))
+	return h
+}
+
+// recvNotification reads the NotificationResponse in the message buffer
+// and hands it to the notification handler.
+func (cn *Conn) recvNotification() {
+	n := cn.readNotification()
+	if h := cn.notificationFunc(); n != nil && h != nil {
+		h(n)
+	}
+}
@@ -0,0 +1,128 @@
+package pq
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNotifyPayloadTooLarge(t *testing.T) {
+	cn := &Conn{msg: newMsg()}
+	err := cn.Notify("chan", strings.Repeat("x", 8000))
+	if err == nil {
+		t.Fatal("expected an error for an oversized payload")
+	}
+}
+
+func TestRecvOversizedNotification(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'A', []interface{}{int32(1), "chan", strings.Repeat("x", 9000)}},
+		testMsg{'A', []interface{}{int32(1), "chan", "ok"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg(), state: stateSimpleQuery}
+
+	var notices []*Error
+	cn.SetNoticeHandler(func(e *Error) { notices = append(notices, e) })
+	var got []Notification
+	cn.SetNotificationHandler(func(n *Notification) { got = append(got, *n) })
+
+	var err error
+	func() {
+		defer recoverErr(&err)
+		cn.recvMsg()
+	}()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cn.T != 'Z' {
+		t.Fatalf("expected to stay in sync and read 'Z', got '%c'", cn.T)
+	}
+	if len(got) != 1 || got[0].Payload != "ok" {
+		t.Fatalf("expected only the notification within the limit, got %v", got)
+	}
+	if len(notices) != 1 || notices[0].Severity != "WARNING" {
+		t.Fatalf("expected a warning for the dropped notification, got %v", notices)
+	}
+}
+
+func TestNotificationHandler(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'A', []interface{}{int32(7), "jobs", "42"}},
+		testMsg{'C', []interface{}{"NOTIFY"}},
+		testMsg{'A', []interface{}{int32(7), "jobs", ""}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+
+	var got []Notification
+	cn.SetNotificationHandler(func(n *Notification) {
+		got = append(got, *n)
+	})
+	if _, err := cn.Exec("NOTIFY jobs, '42'", nil); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Notification{{PID: 7, Channel: "jobs", Payload: "42"}, {PID: 7, Channel: "jobs"}}
+	if len(got) != 2 || got[0] != expected[0] || got[1] != expected[1] {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestConnectorNotificationHandler(t *testing.T) {
+	cr, err := NewConnector("host=localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'A', []interface{}{int32(7), "jobs", "1"}},
+		testMsg{'A', []interface{}{int32(7), "jobs", "2"}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg(), connector: cr}
+
+	// Set once the connection is open, and still reaching it.
+	var fromConnector, fromConn []string
+	cr.SetNotificationHandler(func(n *Notification) {
+		fromConnector = append(fromConnector, n.Payload)
+	})
+	cn.readFrom(cn.c)
+	cn.recvNotification()
+
+	cn.SetNotificationHandler(func(n *Notification) {
+		fromConn = append(fromConn, n.Payload)
+	})
+	cn.readFrom(cn.c)
+	cn.recvNotification()
+
+	if len(fromConnector) != 1 || fromConnector[0] != "1" {
+		t.Fatalf("unexpected notifications through the Connector %q", fromConnector)
+	}
+	if len(fromConn) != 1 || fromConn[0] != "2" {
+		t.Fatalf("unexpected notifications through the Conn %q", fromConn)
+	}
+}
+
+func TestBackendPID(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'R', []interface{}{int32(0)}},
+		testMsg{'K', []interface{}{int32(1234), int32(5678)}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg()}
+	cn.startup(Values{"user": "u"})
+	if cn.BackendPID() != 1234 || cn.key != 5678 {
+		t.Fatalf("unexpected backend key data: pid %d, key %d", cn.BackendPID(), cn.key)
+	}
+}
+
+func TestListenQuery(t *testing.T) {
+	cn := &Conn{}
+	if q := cn.listenQuery(); q != "" {
+		t.Fatalf("expected no LISTEN without a Connector, got %q", q)
+	}
+	cn.connector = &Connector{}
+	cn.connector.SetListenChannels("a", `b"c`)
+	if q := cn.listenQuery(); q != `LISTEN "a";LISTEN "b""c";` {
+		t.Fatalf("unexpected query %q", q)
+	}
+}
@@ -0,0 +1,160 @@
+package pq
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// The Null types hold values of nullable columns, with Valid false for
+// NULL. They scan what database/sql's own do, and the forms pq's
+// decoders give values in besides: the text of values inside a Record or
+// hstore, and the driver's own types, such as LSN and net.IP.
+
+// NullTime is a time.Time that may be NULL. It takes the text of a
+// timestamp or date too, including the infinities when EnableInfinityTs
+// is on.
+type NullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+// Scan implements sql.Scanner.
+func (n *NullTime) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	switch v := src.(type) {
+	case nil:
+		*n = NullTime{}
+	case time.Time:
+		*n = NullTime{Time: v, Valid: true}
+	case []byte:
+		*n = NullTime{Time: parseArrayTime(v), Valid: true}
+	case string:
+		*n = NullTime{Time: parseArrayTime([]byte(v)), Valid: true}
+	default:
+		return errf("cannot scan %T into a NullTime", src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (n NullTime) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Time, nil
+}
+
+// NullString is a string that may be NULL. It takes the values of the
+// driver's types that have a text form, such as LSN, Ltree and net.IP,
+// as that text.
+type NullString struct {
+	String string
+	Valid  bool
+}
+
+// Scan implements sql.Scanner.
+func (n *NullString) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*n = NullString{}
+	case string:
+		*n = NullString{String: v, Valid: true}
+	case []byte:
+		*n = NullString{String: string(v), Valid: true}
+	case fmt.Stringer:
+		*n = NullString{String: v.String(), Valid: true}
+	default:
+		var s sql.NullString
+		if err := s.Scan(src); err != nil {
+			return err
+		}
+		*n = NullString{String: s.String, Valid: true}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (n NullString) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.String, nil
+}
+
+// NullInt64 is an int64 that may be NULL. It takes any integer, including
+// an Oid, and the text of one.
+type NullInt64 struct {
+	Int64 int64
+	Valid bool
+}
+
+// Scan implements sql.Scanner.
+func (n *NullInt64) Scan(src interface{}) error {
+	var i sql.NullInt64
+	if err := i.Scan(src); err != nil {
+		return err
+	}
+	*n = NullInt64{Int64: i.Int64, Valid: i.Valid}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (n NullInt64) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Int64, nil
+}
+
+// NullFloat64 is a float64 that may be NULL. It takes any number,
+// including the text numeric results decode to without a NumericCodec.
+type NullFloat64 struct {
+	Float64 float64
+	Valid   bool
+}
+
+// Scan implements sql.Scanner.
+func (n *NullFloat64) Scan(src interface{}) error {
+	var f sql.NullFloat64
+	if err := f.Scan(src); err != nil {
+		return err
+	}
+	*n = NullFloat64{Float64: f.Float64, Valid: f.Valid}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (n NullFloat64) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Float64, nil
+}
+
+// NullBool is a bool that may be NULL. It takes the text of a boolean
+// as well, t or f as the server writes them.
+type NullBool struct {
+	Bool  bool
+	Valid bool
+}
+
+// Scan implements sql.Scanner.
+func (n *NullBool) Scan(src interface{}) error {
+	var b sql.NullBool
+	if err := b.Scan(src); err != nil {
+		return err
+	}
+	*n = NullBool{Bool: b.Bool, Valid: b.Valid}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (n NullBool) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Bool, nil
+}
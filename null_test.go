@@ -0,0 +1,82 @@
+package pq
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNullTime(t *testing.T) {
+	var n NullTime
+	if err := n.Scan([]byte("2001-02-03 04:05:06+02")); err != nil || !n.Valid {
+		t.Fatalf("unexpected %v, %v", n, err)
+	}
+	if expected := time.Date(2001, 2, 3, 2, 5, 6, 0, time.UTC); !n.Time.Equal(expected) {
+		t.Fatalf("expected %v, got %v", expected, n.Time)
+	}
+	if err := n.Scan("0044-03-15 BC"); err != nil || n.Time.Year() != -43 {
+		t.Fatalf("unexpected %v, %v", n, err)
+	}
+	if err := n.Scan(nil); err != nil || n.Valid {
+		t.Fatalf("unexpected %v, %v", n, err)
+	}
+	if v, err := n.Value(); err != nil || v != nil {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+	if err := n.Scan("x"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := n.Scan(1); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNullString(t *testing.T) {
+	var n NullString
+	for _, test := range []struct {
+		in       interface{}
+		expected string
+	}{
+		{"x", "x"},
+		{[]byte("y"), "y"},
+		{LSN(1), "0/1"},
+		{net.IPv4(10, 0, 0, 1).To4(), "10.0.0.1"},
+		{int64(5), "5"},
+	} {
+		if err := n.Scan(test.in); err != nil || !n.Valid || n.String != test.expected {
+			t.Errorf("%#v: unexpected %v, %v", test.in, n, err)
+		}
+	}
+	if err := n.Scan(nil); err != nil || n.Valid {
+		t.Fatalf("unexpected %v, %v", n, err)
+	}
+}
+
+func TestNullNumbers(t *testing.T) {
+	var i NullInt64
+	if err := i.Scan(Oid(26)); err != nil || !i.Valid || i.Int64 != 26 {
+		t.Fatalf("unexpected %v, %v", i, err)
+	}
+	if err := i.Scan([]byte("-3")); err != nil || i.Int64 != -3 {
+		t.Fatalf("unexpected %v, %v", i, err)
+	}
+	if v, err := (NullInt64{}).Value(); err != nil || v != nil {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	var f NullFloat64
+	if err := f.Scan("12.5"); err != nil || !f.Valid || f.Float64 != 12.5 {
+		t.Fatalf("unexpected %v, %v", f, err)
+	}
+	if err := f.Scan(nil); err != nil || f.Valid {
+		t.Fatalf("unexpected %v, %v", f, err)
+	}
+
+	var b NullBool
+	if err := b.Scan("t"); err != nil || !b.Valid || !b.Bool {
+		t.Fatalf("unexpected %v, %v", b, err)
+	}
+	if v, err := b.Value(); err != nil || v != true {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
@@ -0,0 +1,59 @@
+package pq
+
+import (
+	"math/big"
+)
+
+// NumericCodec plugs a decimal type, such as shopspring/decimal's, into
+// the driver for numeric columns and parameters. Install one with
+// RegisterNumeric.
+type NumericCodec struct {
+	// Decode converts the text of a numeric result, which then scans
+	// into a destination of its type. Without it numeric results are
+	// strings, which lose nothing.
+	Decode func(s string) (interface{}, error)
+
+	// Encode formats a parameter as a numeric literal, reporting false
+	// for values it doesn't handle. Types implementing driver.Valuer
+	// don't need it.
+	Encode func(v interface{}) (s string, ok bool)
+}
+
+var numericCodec NumericCodec
+
+// RegisterNumeric installs c for all connections. It isn't safe to call
+// concurrently with queries: call it from an init function.
+func RegisterNumeric(c NumericCodec) {
+	numericCodec = c
+}
+
+// decodeNumeric returns the value of the text of a numeric result.
+func decodeNumeric(b []byte) interface{} {
+	if numericCodec.Decode == nil {
+		return string(b)
+	}
+	v, err := numericCodec.Decode(string(b))
+	if err != nil {
+		panic(errf("invalid numeric %q: %v", b, err))
+	}
+	return v
+}
+
+// encodeNumeric returns the numeric literal for v if it is of a decimal
+// type: *big.Int, *big.Float, or one the registered codec handles.
+func encodeNumeric(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case *big.Int:
+		if n != nil {
+			return n.String(), true
+		}
+	case *big.Float:
+		if n != nil && !n.IsInf() {
+			return n.Text('f', -1), true
+		}
+	}
+	if numericCodec.Encode != nil {
+		return numericCodec.Encode(v)
+	}
+	return "", false
+}
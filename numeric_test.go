@@ -0,0 +1,53 @@
+package pq
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+func TestNumericDefault(t *testing.T) {
+	if v := decodeText(OidNumeric, []byte("12345678901234567890.000000000001")); v != "12345678901234567890.000000000001" {
+		t.Fatalf("expected the numeric as a string, got %#v", v)
+	}
+
+	n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if v, err := checkParam(n); err != nil || v != "123456789012345678901234567890" {
+		t.Fatalf("unexpected *big.Int parameter %#v, %v", v, err)
+	}
+	if v, err := checkParam(big.NewFloat(0.5)); err != nil || v != "0.5" {
+		t.Fatalf("unexpected *big.Float parameter %#v, %v", v, err)
+	}
+}
+
+type cents int64
+
+func TestRegisterNumeric(t *testing.T) {
+	defer RegisterNumeric(NumericCodec{})
+	RegisterNumeric(NumericCodec{
+		Decode: func(s string) (interface{}, error) {
+			f, err := strconv.ParseFloat(s, 64)
+			return cents(f * 100), err
+		},
+		Encode: func(v interface{}) (string, bool) {
+			c, ok := v.(cents)
+			return strconv.FormatFloat(float64(c)/100, 'f', 2, 64), ok
+		},
+	})
+
+	if v := decodeText(OidNumeric, []byte("1.25")); v != cents(125) {
+		t.Fatalf("expected the registered type, got %#v", v)
+	}
+	if v, err := checkParam(cents(250)); err != nil || v != "2.50" {
+		t.Fatalf("unexpected parameter %#v, %v", v, err)
+	}
+
+	var err error
+	func() {
+		defer recoverErr(&err)
+		decodeText(OidNumeric, []byte("NaNx"))
+	}()
+	if err == nil {
+		t.Fatal("expected the decoder's error")
+	}
+}
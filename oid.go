@@ -0,0 +1,104 @@
+package pq
+
+// Oid is a PostgreSQL object identifier, as used for the types of
+// parameters and result columns.
+type Oid uint32
+
+// The OIDs of the built-in types the driver knows about.
+const (
+	OidBool           Oid = 16
+	OidBytea          Oid = 17
+	OidChar           Oid = 18
+	OidName           Oid = 19
+	OidInt8           Oid = 20
+	OidInt2           Oid = 21
+	OidInt4           Oid = 23
+	OidRegproc        Oid = 24
+	OidText           Oid = 25
+	OidOid            Oid = 26
+	OidJSON           Oid = 114
+	OidPoint          Oid = 600
+	OidLseg           Oid = 601
+	OidPath           Oid = 602
+	OidBox            Oid = 603
+	OidPolygon        Oid = 604
+	OidLine           Oid = 628
+	OidCidr           Oid = 650
+	OidFloat4         Oid = 700
+	OidFloat8         Oid = 701
+	OidUnknown        Oid = 705
+	OidCircle         Oid = 718
+	OidMacaddr8       Oid = 774
+	OidMacaddr        Oid = 829
+	OidInet           Oid = 869
+	OidBpchar         Oid = 1042
+	OidVarchar        Oid = 1043
+	OidDate           Oid = 1082
+	OidTime           Oid = 1083
+	OidTimestamp      Oid = 1114
+	OidTimestamptz    Oid = 1184
+	OidInterval       Oid = 1186
+	OidTimetz         Oid = 1266
+	OidBit            Oid = 1560
+	OidVarbit         Oid = 1562
+	OidNumeric        Oid = 1700
+	OidRegprocedure   Oid = 2202
+	OidRegoper        Oid = 2203
+	OidRegoperator    Oid = 2204
+	OidRegclass       Oid = 2205
+	OidRegtype        Oid = 2206
+	OidRecord         Oid = 2249
+	OidUUID           Oid = 2950
+	OidPgLSN          Oid = 3220
+	OidTSVector       Oid = 3614
+	OidTSQuery        Oid = 3615
+	OidRegconfig      Oid = 3734
+	OidRegdictionary  Oid = 3769
+	OidJSONB          Oid = 3802
+	OidInt4Range      Oid = 3904
+	OidNumRange       Oid = 3906
+	OidTsRange        Oid = 3908
+	OidTstzRange      Oid = 3910
+	OidDateRange      Oid = 3912
+	OidInt8Range      Oid = 3926
+	OidRegnamespace   Oid = 4089
+	OidRegrole        Oid = 4096
+	OidRegcollation   Oid = 4191
+	OidInt4Multirange Oid = 4451
+	OidNumMultirange  Oid = 4532
+	OidTsMultirange   Oid = 4533
+	OidTstzMultirange Oid = 4534
+	OidDateMultirange Oid = 4535
+	OidInt8Multirange Oid = 4536
+)
+
+// Format codes for parameters and result columns.
+const (
+	formatText   int16 = 0
+	formatBinary int16 = 1
+)
+
+// resultFormat returns the format to request for result columns of type
+// typ. A binary bytea is the raw bytes, sparing the server the hex
+// encoding, so it is always requested. The other types decodeBinary
+// handles are requested in binary with the binary_results=true option.
+func (cn *Conn) resultFormat(typ Oid) int16 {
+	if _, ok := cn.types[typ]; ok {
+		// Registered decoders take the text format.
+		return formatText
+	}
+	switch typ {
+	case OidBytea:
+		return formatBinary
+	case OidBool, OidInt2, OidInt4, OidInt8, OidFloat4, OidFloat8,
+		OidUUID, OidTimestamp, OidTimestamptz, OidDate, OidTime, OidTimetz, OidPgLSN, OidOid:
+		if cn.binaryResults {
+			return formatBinary
+		}
+	}
+	if isArray, ok := cn.enums[typ]; ok && !isArray && cn.binaryResults {
+		// The binary format of an enum is its label.
+		return formatBinary
+	}
+	return formatText
+}
@@ -0,0 +1,55 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestResultFormats(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		// Prepare
+		{'1', nil},
+		{'t', []interface{}{int16(0)}},
+		{'T', []interface{}{int16(2),
+			"b", int32(0), int16(0), int32(OidBytea), int16(-1), int32(-1), int16(0),
+			"i", int32(0), int16(0), int32(OidInt4), int16(4), int32(-1), int16(0),
+		}},
+		{'Z', []interface{}{byte('I')}},
+		// Query
+		{'2', nil},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	conn := &replayConn{r: bytes.NewReader(buf.Bytes())}
+	cn := &Conn{c: conn, msg: newMsg()}
+	st := cn.prepare("SELECT b, i FROM t")
+
+	conn.w.Reset()
+	st.sendExec(nil, 0)
+	r := st.recvRows()
+
+	if r.fields[0].format != formatBinary || r.fields[1].format != formatText {
+		t.Fatalf("unexpected formats %+v", r.fields)
+	}
+
+	bind := newMsg()
+	bind.readFrom(&conn.w)
+	if bind.T != 'B' {
+		t.Fatalf("expected Bind, got '%c'", bind.T)
+	}
+
+	// The Bind ends with the count of result formats and the formats.
+	expected := []byte{0, 2, 0, 1, 0, 0}
+	if !bytes.HasSuffix(bind.b.Bytes(), expected) {
+		t.Fatalf("unexpected Bind %q", bind.b.Bytes())
+	}
+}
@@ -0,0 +1,108 @@
+package pq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// protoState is the stage of the exchange with the server, which
+// determines the messages that may arrive next. Sending a message moves to
+// the state of the exchange it starts, and ReadyForQuery ends it.
+type protoState byte
+
+const (
+	// stateReady is between exchanges, once ReadyForQuery has arrived.
+	stateReady protoState = iota
+	stateStartup
+	stateSimpleQuery
+	stateExtendedQuery
+	stateFunctionCall
+)
+
+var stateNames = [...]string{
+	stateReady:         "idle",
+	stateStartup:       "starting up",
+	stateSimpleQuery:   "running a simple query",
+	stateExtendedQuery: "running an extended query",
+	stateFunctionCall:  "calling a function",
+}
+
+// stateMsgs lists the messages valid in each state besides ErrorResponse
+// and the asynchronous NoticeResponse, NotificationResponse and
+// ParameterStatus, which are valid in all of them.
+var stateMsgs = [...]string{
+	stateReady:         "",
+	stateStartup:       "RKZ",
+	stateSimpleQuery:   "TDCIGHdcZ",
+	stateExtendedQuery: "123tTnDCIsZ",
+	stateFunctionCall:  "VZ",
+}
+
+func (s protoState) String() string {
+	return stateNames[s]
+}
+
+// sentState returns the state following the message of type t sent in
+// state s.
+func sentState(s protoState, t int8) protoState {
+	switch t {
+	case 0, 'p':
+		return stateStartup
+	case 'Q':
+		return stateSimpleQuery
+	case 'P', 'B', 'D', 'E', 'C', 'H', 'S':
+		return stateExtendedQuery
+	case 'F':
+		return stateFunctionCall
+	}
+	return s
+}
+
+// checkState panics if the message in the buffer isn't valid in the
+// current state, which means the driver and the server no longer agree on
+// where the exchange is.
+func (cn *Conn) checkState() {
+	if cn.T == 'E' || strings.IndexByte(stateMsgs[cn.state], byte(cn.T)) >= 0 {
+		return
+	}
+	panic(errf("protocol out of sync: unexpected %s from the server while %s", msgName(cn.T), cn.state))
+}
+
+// unexpected returns the error for a message that is valid in the current
+// state but not at this point of the exchange started by what.
+func (cn *Conn) unexpected(what string) error {
+	return errf("unexpected %s in response to %s", msgName(cn.T), what)
+}
+
+var msgNames = map[int8]string{
+	'1': "ParseComplete",
+	'2': "BindComplete",
+	'3': "CloseComplete",
+	'A': "NotificationResponse",
+	'C': "CommandComplete",
+	'D': "DataRow",
+	'E': "ErrorResponse",
+	'G': "CopyInResponse",
+	'H': "CopyOutResponse",
+	'I': "EmptyQueryResponse",
+	'K': "BackendKeyData",
+	'N': "NoticeResponse",
+	'R': "Authentication",
+	'S': "ParameterStatus",
+	'T': "RowDescription",
+	'V': "FunctionCallResponse",
+	'Z': "ReadyForQuery",
+	'c': "CopyDone",
+	'd': "CopyData",
+	'n': "NoData",
+	's': "PortalSuspended",
+	't': "ParameterDescription",
+}
+
+// msgName describes the backend message type t for errors.
+func msgName(t int8) string {
+	if name, ok := msgNames[t]; ok {
+		return fmt.Sprintf("%s ('%c')", name, t)
+	}
+	return fmt.Sprintf("message type %q", byte(t))
+}
@@ -0,0 +1,66 @@
+package pq
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSentState(t *testing.T) {
+	tests := []struct {
+		from     protoState
+		sent     int8
+		expected protoState
+	}{
+		{stateReady, 0, stateStartup},
+		{stateStartup, 'p', stateStartup},
+		{stateReady, 'Q', stateSimpleQuery},
+		{stateReady, 'P', stateExtendedQuery},
+		{stateExtendedQuery, 'S', stateExtendedQuery},
+		{stateReady, 'F', stateFunctionCall},
+		{stateSimpleQuery, 'X', stateSimpleQuery},
+	}
+
+	for _, test := range tests {
+		if s := sentState(test.from, test.sent); s != test.expected {
+			t.Errorf("sending %q while %s: expected %s, got %s", byte(test.sent), test.from, test.expected, s)
+		}
+	}
+}
+
+func TestProtocolOutOfSync(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	m.setHead('D')
+	m.write(int16(0))
+	m.writeTo(&buf)
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg()}
+
+	var err error
+	func() {
+		defer cn.errRecover(&err)
+		cn.recvMsg()
+	}()
+
+	if err == nil || !strings.Contains(err.Error(), "DataRow ('D')") || !strings.Contains(err.Error(), "idle") {
+		t.Fatalf("expected an out of sync error naming the message and state, got %v", err)
+	}
+	if !cn.bad {
+		t.Fatal("expected the connection to be marked bad")
+	}
+}
+
+func TestReadyForQueryEndsExchange(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	m.setHead('Z')
+	m.write(byte('I'))
+	m.writeTo(&buf)
+
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(buf.Bytes())}, msg: newMsg(), state: stateSimpleQuery}
+	cn.recvMsg()
+	if cn.state != stateReady {
+		t.Fatalf("expected to be idle, got %s", cn.state)
+	}
+}
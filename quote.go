@@ -0,0 +1,135 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"strconv"
+	"strings"
+)
+
+// interpolate replaces the $n placeholders in q with the quoted literal form
+// of args[n-1], so a parameterized query can be sent with the simple query
+// protocol. Placeholders inside string literals, quoted identifiers,
+// dollar-quoted bodies and comments are left alone.
+func interpolate(q string, args []driver.Value) (string, error) {
+	var buf strings.Builder
+	for i := 0; i < len(q); i++ {
+		c := q[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := skipQuoted(q, i, c, c == '\'' && i > 0 && (q[i-1] == 'E' || q[i-1] == 'e'))
+			buf.WriteString(q[i:j])
+			i = j - 1
+		case c == '-' && strings.HasPrefix(q[i:], "--"):
+			j := strings.IndexByte(q[i:], '\n')
+			if j < 0 {
+				j = len(q) - i
+			}
+			buf.WriteString(q[i : i+j])
+			i += j - 1
+		case c == '/' && strings.HasPrefix(q[i:], "/*"):
+			j := strings.Index(q[i+2:], "*/")
+			if j < 0 {
+				j = len(q) - i
+			} else {
+				j += 4
+			}
+			buf.WriteString(q[i : i+j])
+			i += j - 1
+		case c == '$' && (i == 0 || !isIdentChar(q[i-1])):
+			j := i + 1
+			for j < len(q) && q[j] >= '0' && q[j] <= '9' {
+				j++
+			}
+			if j > i+1 {
+				n, _ := strconv.Atoi(q[i+1 : j])
+				if n < 1 || n > len(args) {
+					return "", errf("placeholder $%d out of range; %d argument(s) given", n, len(args))
+				}
+				s, err := quoteValue(args[n-1])
+				if err != nil {
+					return "", paramError(n, err)
+				}
+				buf.WriteString(s)
+				i = j - 1
+				continue
+			}
+			// Dollar-quoted string: $tag$ ... $tag$
+			for j < len(q) && isIdentChar(q[j]) {
+				j++
+			}
+			if j < len(q) && q[j] == '$' {
+				tag := q[i : j+1]
+				end := strings.Index(q[j+1:], tag)
+				if end < 0 {
+					end = len(q)
+				} else {
+					end += j + 1 + len(tag)
+				}
+				buf.WriteString(q[i:end])
+				i = end - 1
+				continue
+			}
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), nil
+}
+
+// skipQuoted returns the index just past the quoted section starting at
+// q[i], treating a doubled quote character as an escaped one.
+func skipQuoted(q string, i int, quote byte, backslashes bool) int {
+	for j := i + 1; j < len(q); j++ {
+		switch q[j] {
+		case '\\':
+			if backslashes {
+				j++
+			}
+		case quote:
+			if j+1 < len(q) && q[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(q)
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// quoteValue renders v as an SQL literal.
+func quoteValue(v driver.Value) (s string, err error) {
+	defer recoverErr(&err)
+
+	l, b := encodeParam(v)
+	if l < 0 {
+		return "NULL", nil
+	}
+	return quoteLiteral(string(b)), nil
+}
+
+// quoteLiteral quotes s as a string literal. Backslashes force the
+// escape string syntax so the result means the same regardless of
+// standard_conforming_strings.
+func quoteLiteral(s string) string {
+	s = strings.Replace(s, "'", "''", -1)
+	if strings.Contains(s, `\`) {
+		return " E'" + strings.Replace(s, `\`, `\\`, -1) + "'"
+	}
+	return "'" + s + "'"
+}
+
+// QuoteIdentifier quotes name to be used as an identifier, such as a table
+// or column name, in a query. It is taken as is, case and all, and as one
+// name: quote the schema of a qualified name separately. The name ends at
+// any NUL byte, which the server can't take.
+func QuoteIdentifier(name string) string {
+	if i := strings.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,69 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		q        string
+		args     []driver.Value
+		expected string
+	}{
+		{"SELECT $1, $2", []driver.Value{int64(1), "a'b"}, "SELECT '1', 'a''b'"},
+		{"SELECT $1", []driver.Value{nil}, "SELECT NULL"},
+		{"SELECT $1", []driver.Value{`a\b`}, `SELECT  E'a\\b'`},
+		{"SELECT '$1', \"$1\", $1", []driver.Value{"x"}, "SELECT '$1', \"$1\", 'x'"},
+		{"SELECT $$ $1 $$, $tag$ $1 $tag$, $1", []driver.Value{"x"}, "SELECT $$ $1 $$, $tag$ $1 $tag$, 'x'"},
+		{"SELECT $1 -- $1\n/* $1 */", []driver.Value{"x"}, "SELECT 'x' -- $1\n/* $1 */"},
+		{"SELECT E'\\'$1', $1", []driver.Value{"x"}, "SELECT E'\\'$1', 'x'"},
+		{"SELECT a$1 FROM t", []driver.Value{"x"}, "SELECT a$1 FROM t"},
+	}
+
+	for _, test := range tests {
+		s, err := interpolate(test.q, test.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != test.expected {
+			t.Errorf("interpolate(%q):\n+ %s\n- %s", test.q, s, test.expected)
+		}
+	}
+}
+
+func TestInterpolateOutOfRange(t *testing.T) {
+	_, err := interpolate("SELECT $2", []driver.Value{"x"})
+	if err == nil {
+		t.Fatal("expected an error for an out of range placeholder")
+	}
+}
+
+func TestInterpolateUnsupported(t *testing.T) {
+	_, err := interpolate("SELECT $1, $2", []driver.Value{"x", []int{1}})
+	e, ok := err.(*ErrUnsupportedParameterType)
+	if !ok || e.Index != 2 || e.Type != reflect.TypeOf([]int{}) {
+		t.Fatalf("expected an ErrUnsupportedParameterType for $2, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "argument $2: unsupported type []int") {
+		t.Fatalf("unexpected message %q", err)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"foo", `"foo"`},
+		{"Foo Bar", `"Foo Bar"`},
+		{`a"b`, `"a""b"`},
+		{"a\x00b", `"a"`},
+	}
+	for _, test := range tests {
+		if s := QuoteIdentifier(test.in); s != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.in, test.expected, s)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package pq
+
+// raceEnabled is set when the tests run with the race detector, which
+// changes allocation counts.
+const raceEnabled = true
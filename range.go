@@ -0,0 +1,343 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"strconv"
+	"time"
+)
+
+// RangeBounds holds what a range says about its bounds besides their
+// values. The zero value is a range including its lower bound and
+// excluding its upper one, as the server writes discrete ranges.
+type RangeBounds struct {
+	// Empty is set for the empty range, whose bounds are ignored.
+	Empty bool
+
+	// LowerInf and UpperInf are set for unbounded ends, whose values are
+	// ignored.
+	LowerInf, UpperInf bool
+
+	// LowerExc is set if the lower bound is excluded, UpperInc if the
+	// upper bound is included.
+	LowerExc, UpperInc bool
+}
+
+// Int64Range is an int4range or int8range.
+type Int64Range struct {
+	Lower, Upper int64
+	RangeBounds
+}
+
+// Scan implements sql.Scanner.
+func (r *Int64Range) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	lower, upper, bounds := parseRange(src, "Int64Range")
+	*r = Int64Range{RangeBounds: bounds}
+	if lower != nil {
+		if r.Lower, err = strconv.ParseInt(string(lower), 10, 64); err != nil {
+			return errf("invalid Int64Range bound %q", lower)
+		}
+	}
+	if upper != nil {
+		if r.Upper, err = strconv.ParseInt(string(upper), 10, 64); err != nil {
+			return errf("invalid Int64Range bound %q", upper)
+		}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (r Int64Range) Value() (driver.Value, error) {
+	return formatRange(strconv.FormatInt(r.Lower, 10), strconv.FormatInt(r.Upper, 10), r.RangeBounds), nil
+}
+
+// NumericRange is a numrange, its bounds the text of numerics as numeric
+// results are.
+type NumericRange struct {
+	Lower, Upper string
+	RangeBounds
+}
+
+// Scan implements sql.Scanner.
+func (r *NumericRange) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	lower, upper, bounds := parseRange(src, "NumericRange")
+	*r = NumericRange{Lower: string(lower), Upper: string(upper), RangeBounds: bounds}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (r NumericRange) Value() (driver.Value, error) {
+	return formatRange(r.Lower, r.Upper, r.RangeBounds), nil
+}
+
+// TimeRange is a tsrange, tstzrange or daterange. Infinite bounds, as
+// distinct from unbounded ends, need EnableInfinityTs.
+type TimeRange struct {
+	Lower, Upper time.Time
+	RangeBounds
+}
+
+// Scan implements sql.Scanner.
+func (r *TimeRange) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	lower, upper, bounds := parseRange(src, "TimeRange")
+	*r = TimeRange{RangeBounds: bounds}
+	if lower != nil {
+		r.Lower = parseArrayTime(lower)
+	}
+	if upper != nil {
+		r.Upper = parseArrayTime(upper)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (r TimeRange) Value() (driver.Value, error) {
+	return formatRange(formatRangeTime(r.Lower), formatRangeTime(r.Upper), r.RangeBounds), nil
+}
+
+func formatRangeTime(t time.Time) string {
+	if sign, ok := isInfinity(t); ok {
+		if sign < 0 {
+			return "-infinity"
+		}
+		return "infinity"
+	}
+	return formatTimestamp(t)
+}
+
+// parseRange parses the text form of a range, returning its bounds, nil
+// for unbounded ends, and the rest of what it says about them.
+func parseRange(src interface{}, into string) (lower, upper []byte, bounds RangeBounds) {
+	var b []byte
+	switch v := src.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		panic(errf("cannot scan %T into a %s", src, into))
+	}
+
+	if string(b) == "empty" {
+		return nil, nil, RangeBounds{Empty: true}
+	}
+	if len(b) < 2 || (b[0] != '[' && b[0] != '(') || (b[len(b)-1] != ']' && b[len(b)-1] != ')') {
+		panic(errf("invalid range %q", b))
+	}
+	f := splitFields(b[1:len(b)-1], "range")
+	if len(f) != 2 {
+		panic(errf("invalid range %q", b))
+	}
+	bounds = RangeBounds{
+		LowerInf: f[0] == nil,
+		UpperInf: f[1] == nil,
+		LowerExc: b[0] == '(',
+		UpperInc: b[len(b)-1] == ']',
+	}
+	return f[0], f[1], bounds
+}
+
+// formatRange returns the range literal of the bounds lower and upper.
+func formatRange(lower, upper string, bounds RangeBounds) string {
+	if bounds.Empty {
+		return "empty"
+	}
+	b := []byte{'['}
+	if bounds.LowerExc || bounds.LowerInf {
+		b[0] = '('
+	}
+	if !bounds.LowerInf {
+		b = appendArrayQuoted(b, lower)
+	}
+	b = append(b, ',')
+	if !bounds.UpperInf {
+		b = appendArrayQuoted(b, upper)
+	}
+	if bounds.UpperInc && !bounds.UpperInf {
+		return string(append(b, ']'))
+	}
+	return string(append(b, ')'))
+}
+
+// Int64Multirange is an int4multirange or int8multirange.
+type Int64Multirange []Int64Range
+
+// Scan implements sql.Scanner.
+func (m *Int64Multirange) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	ranges := parseMultirange(src, "Int64Multirange")
+	r := make(Int64Multirange, len(ranges))
+	for i, b := range ranges {
+		if err := r[i].Scan(b); err != nil {
+			return err
+		}
+	}
+	*m = r
+	return nil
+}
+
+// Value implements driver.Valuer. A nil multirange is NULL.
+func (m Int64Multirange) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	ranges := make([]driver.Valuer, len(m))
+	for i := range m {
+		ranges[i] = m[i]
+	}
+	return formatMultirange(ranges)
+}
+
+// NumericMultirange is a nummultirange.
+type NumericMultirange []NumericRange
+
+// Scan implements sql.Scanner.
+func (m *NumericMultirange) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	ranges := parseMultirange(src, "NumericMultirange")
+	r := make(NumericMultirange, len(ranges))
+	for i, b := range ranges {
+		if err := r[i].Scan(b); err != nil {
+			return err
+		}
+	}
+	*m = r
+	return nil
+}
+
+// Value implements driver.Valuer. A nil multirange is NULL.
+func (m NumericMultirange) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	ranges := make([]driver.Valuer, len(m))
+	for i := range m {
+		ranges[i] = m[i]
+	}
+	return formatMultirange(ranges)
+}
+
+// TimeMultirange is a tsmultirange, tstzmultirange or datemultirange.
+type TimeMultirange []TimeRange
+
+// Scan implements sql.Scanner.
+func (m *TimeMultirange) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	ranges := parseMultirange(src, "TimeMultirange")
+	r := make(TimeMultirange, len(ranges))
+	for i, b := range ranges {
+		if err := r[i].Scan(b); err != nil {
+			return err
+		}
+	}
+	*m = r
+	return nil
+}
+
+// Value implements driver.Valuer. A nil multirange is NULL.
+func (m TimeMultirange) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	ranges := make([]driver.Valuer, len(m))
+	for i := range m {
+		ranges[i] = m[i]
+	}
+	return formatMultirange(ranges)
+}
+
+// parseMultirange splits the text form of a multirange, such as
+// {[1,3),[5,7)}, into the text of its ranges.
+func parseMultirange(src interface{}, into string) [][]byte {
+	var b []byte
+	switch v := src.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		panic(errf("cannot scan %T into a %s", src, into))
+	}
+
+	if len(b) < 2 || b[0] != '{' || b[len(b)-1] != '}' {
+		panic(errf("invalid multirange %q", b))
+	}
+	s := b[1 : len(b)-1]
+
+	ranges := [][]byte{}
+	for i := 0; ; {
+		for i < len(s) && isArraySpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			if len(ranges) > 0 {
+				panic(errf("invalid multirange %q: missing range", b))
+			}
+			return ranges
+		}
+
+		start := i
+		i = rangeEnd(s, i)
+		ranges = append(ranges, bytes.TrimRight(s[start:i], " \t\n\r"))
+
+		for i < len(s) && isArraySpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			return ranges
+		}
+		if s[i] != ',' {
+			panic(errf("invalid multirange %q: expected ',' at offset %d", b, i+1))
+		}
+		i++
+	}
+}
+
+// rangeEnd returns the end of the range starting at s[i]: past its
+// closing bracket outside quotes, or at the next comma for the word empty.
+func rangeEnd(s []byte, i int) int {
+	if s[i] != '[' && s[i] != '(' {
+		for i < len(s) && s[i] != ',' {
+			i++
+		}
+		return i
+	}
+
+	quoted := false
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case (c == ')' || c == ']') && !quoted:
+			return i + 1
+		}
+	}
+	return i
+}
+
+// formatMultirange returns the multirange literal of ranges.
+func formatMultirange(ranges []driver.Valuer) (driver.Value, error) {
+	b := []byte{'{'}
+	for i, r := range ranges {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		v, err := r.Value()
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, v.(string)...)
+	}
+	return string(append(b, '}')), nil
+}
@@ -0,0 +1,137 @@
+package pq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInt64Range(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected Int64Range
+		out      string
+	}{
+		{"[1,10)", Int64Range{Lower: 1, Upper: 10}, `["1","10")`},
+		{"(1,10]", Int64Range{Lower: 1, Upper: 10, RangeBounds: RangeBounds{LowerExc: true, UpperInc: true}}, `("1","10"]`},
+		{"[-5,)", Int64Range{Lower: -5, RangeBounds: RangeBounds{UpperInf: true}}, `["-5",)`},
+		{"(,3)", Int64Range{Upper: 3, RangeBounds: RangeBounds{LowerInf: true, LowerExc: true}}, `(,"3")`},
+		{"(,)", Int64Range{RangeBounds: RangeBounds{LowerInf: true, UpperInf: true, LowerExc: true}}, `(,)`},
+		{"empty", Int64Range{RangeBounds: RangeBounds{Empty: true}}, "empty"},
+	}
+	for _, tt := range tests {
+		var r Int64Range
+		if err := r.Scan([]byte(tt.in)); err != nil {
+			t.Fatalf("%s: %v", tt.in, err)
+		}
+		if r != tt.expected {
+			t.Errorf("%s: expected %+v, got %+v", tt.in, tt.expected, r)
+		}
+		if v, err := r.Value(); err != nil || v != tt.out {
+			t.Errorf("%s: expected %s, got %v, %v", tt.in, tt.out, v, err)
+		}
+	}
+
+	var r Int64Range
+	for _, in := range []string{"", "[1,2", "1,2)", "[1,2,3)", "[a,2)", "[1)"} {
+		if err := r.Scan(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+	if err := r.Scan(nil); err == nil {
+		t.Error("expected an error scanning NULL")
+	}
+}
+
+func TestNumericRange(t *testing.T) {
+	var r NumericRange
+	if err := r.Scan("[1.5,123456789012345678901234567890.5]"); err != nil {
+		t.Fatal(err)
+	}
+	if r.Lower != "1.5" || r.Upper != "123456789012345678901234567890.5" || !r.UpperInc {
+		t.Fatalf("unexpected %+v", r)
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	lower := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	upper := time.Date(2020, 2, 1, 12, 30, 0, 0, time.UTC)
+
+	var r TimeRange
+	if err := r.Scan(`["2020-01-01 00:00:00+00","2020-02-01 12:30:00+00")`); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Lower.Equal(lower) || !r.Upper.Equal(upper) || r.LowerExc || r.UpperInc {
+		t.Fatalf("unexpected %+v", r)
+	}
+	v, err := r.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `["2020-01-01 00:00:00.000000+00","2020-02-01 12:30:00.000000+00")`; v != expected {
+		t.Fatalf("expected %s, got %v", expected, v)
+	}
+
+	if err := r.Scan(`[2020-01-01,)`); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Lower.Equal(lower) || !r.UpperInf {
+		t.Fatalf("unexpected %+v", r)
+	}
+
+	if err := r.Scan(`[-infinity,2020-01-01)`); err == nil {
+		t.Fatal("expected an error for an infinite bound without EnableInfinityTs")
+	}
+}
+
+func TestRangeArray(t *testing.T) {
+	var ranges []Int64Range
+	if err := Array(&ranges).Scan(`{"[1,3)",empty}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 2 || ranges[0].Upper != 3 || !ranges[1].Empty {
+		t.Fatalf("unexpected %+v", ranges)
+	}
+	v, err := Array(ranges).Value()
+	if err != nil || v != `{"[\"1\",\"3\")","empty"}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+}
+
+func TestMultirange(t *testing.T) {
+	var m Int64Multirange
+	if err := m.Scan([]byte("{[1,3), [5,7]}")); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m[0].Lower != 1 || m[0].Upper != 3 || m[1].Lower != 5 || !m[1].UpperInc {
+		t.Fatalf("unexpected %+v", m)
+	}
+	v, err := m.Value()
+	if err != nil || v != `{["1","3"),["5","7"]}` {
+		t.Fatalf("unexpected %v, %v", v, err)
+	}
+
+	if err := m.Scan("{}"); err != nil || m == nil || len(m) != 0 {
+		t.Fatalf("expected an empty multirange, got %+v, %v", m, err)
+	}
+	if v, _ := Int64Multirange(nil).Value(); v != nil {
+		t.Fatalf("expected a nil multirange to be NULL, got %v", v)
+	}
+	for _, in := range []string{"", "{[1,3)", "{[1,3),}", "{[1,3) [5,7)}", "{[1,x)}"} {
+		if err := m.Scan(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+
+	var tm TimeMultirange
+	if err := tm.Scan(`{["2020-01-01 00:00:00+00","2020-02-01 00:00:00+00"),["2021-01-01 00:00:00+00",)}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(tm) != 2 || tm[0].Upper.Month() != 2 || !tm[1].UpperInf {
+		t.Fatalf("unexpected %+v", tm)
+	}
+
+	var nm NumericMultirange
+	if err := nm.Scan("{(1.5,2.5]}"); err != nil || len(nm) != 1 || nm[0].Lower != "1.5" || !nm[0].LowerExc {
+		t.Fatalf("unexpected %+v, %v", nm, err)
+	}
+}
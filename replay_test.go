@@ -0,0 +1,86 @@
+package pq
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// The fixtures below play back a server's side of the protocol to a Conn
+// without a server, for the tests and benchmarks of the package.
+
+// replayConn is a net.Conn that reads from a fixed buffer and records
+// what is written to it.
+type replayConn struct {
+	net.Conn
+	r *bytes.Reader
+	w bytes.Buffer
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func (c *replayConn) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
+
+func (c *replayConn) Close() error {
+	return nil
+}
+
+// dataRowStream returns the messages a server sends for n copies of row.
+func dataRowStream(row [][]byte, n int) []byte {
+	var buf bytes.Buffer
+	m := newMsg()
+	for i := 0; i < n; i++ {
+		m.setHead('D')
+		m.write(int16(len(row)))
+		for _, col := range row {
+			if col == nil {
+				m.write(int32(-1))
+				continue
+			}
+			m.write(int32(len(col)), col)
+		}
+		m.writeTo(&buf)
+	}
+
+	m.setHead('C')
+	m.write("SELECT 1")
+	m.writeTo(&buf)
+
+	m.setHead('Z')
+	m.write(byte('I'))
+	m.writeTo(&buf)
+	return buf.Bytes()
+}
+
+type testMsg struct {
+	typ  int8
+	body []interface{}
+}
+
+// serverMsgs returns the stream of the messages msgs.
+func serverMsgs(msgs ...testMsg) []byte {
+	var buf bytes.Buffer
+	m := newMsg()
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+	return buf.Bytes()
+}
+
+// sentTypes returns the types of the frontend messages in b.
+func sentTypes(t *testing.T, b []byte) string {
+	var types []byte
+	r := bytes.NewReader(b)
+	m := newMsg()
+	for r.Len() > 0 {
+		m.readFrom(r)
+		types = append(types, byte(m.T))
+	}
+	return string(types)
+}
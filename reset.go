@@ -0,0 +1,57 @@
+package pq
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+// discardSession is DISCARD ALL without its DEALLOCATE ALL, which would
+// pull prepared statements out from under database/sql and the statement
+// cache.
+const discardSession = `CLOSE ALL;
+SET SESSION AUTHORIZATION DEFAULT;
+RESET ALL;
+UNLISTEN *;
+SELECT pg_advisory_unlock_all();
+DISCARD PLANS;
+DISCARD TEMP;
+DISCARD SEQUENCES`
+
+// ResetSession implements driver.SessionResetter. database/sql calls it
+// before reusing a pooled connection. A connection that is broken or
+// still inside a transaction is reported as driver.ErrBadConn. With the
+// session_reset option it also clears the state the previous user left
+// behind: "unlisten" drops LISTEN registrations, "discard" additionally
+// resets settings, temporary tables, cursors and advisory locks.
+func (cn *Conn) ResetSession(ctx context.Context) error {
+	if cn.bad || cn.TxStatus() != TxIdle {
+		return driver.ErrBadConn
+	}
+
+	var q string
+	switch cn.sessionReset {
+	case "unlisten":
+		q = "UNLISTEN *"
+	case "discard":
+		q = discardSession
+	default:
+		return nil
+	}
+	if l := cn.listenQuery(); l != "" {
+		// Listen again on the channels of the Connector.
+		q += ";" + l
+	}
+
+	if d, ok := ctx.Deadline(); ok {
+		cn.c.SetDeadline(d)
+		defer cn.c.SetDeadline(time.Time{})
+	}
+
+	_, err := cn.Exec(q, nil)
+	if err != nil {
+		cn.bad = true
+		return driver.ErrBadConn
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package pq
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestResetSessionInTransaction(t *testing.T) {
+	cn := &Conn{msg: newMsg(), status: 'T'}
+	if err := cn.ResetSession(context.Background()); err != driver.ErrBadConn {
+		t.Fatalf("expected driver.ErrBadConn, got %v", err)
+	}
+}
+
+func TestResetSessionUnlisten(t *testing.T) {
+	var buf bytes.Buffer
+	m := newMsg()
+	m.setHead('C')
+	m.write("UNLISTEN")
+	m.writeTo(&buf)
+	m.setHead('Z')
+	m.write(byte('I'))
+	m.writeTo(&buf)
+
+	conn := &replayConn{r: bytes.NewReader(buf.Bytes())}
+	cn := &Conn{c: conn, msg: newMsg(), status: 'I', sessionReset: "unlisten"}
+	if err := cn.ResetSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(conn.w.Bytes(), []byte("UNLISTEN *")) {
+		t.Fatalf("expected UNLISTEN * to be sent, got %q", conn.w.Bytes())
+	}
+}
+
+func TestResetSessionListenAgain(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'C', []interface{}{"UNLISTEN"}},
+		testMsg{'C', []interface{}{"LISTEN"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cr := &Connector{}
+	cr.SetListenChannels("cache")
+	cn := &Conn{c: conn, msg: newMsg(), status: 'I', sessionReset: "unlisten", connector: cr}
+	if err := cn.ResetSession(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(conn.w.Bytes(), []byte(`UNLISTEN *;LISTEN "cache";`)) {
+		t.Fatalf("expected the channel to be listened on again, got %q", conn.w.Bytes())
+	}
+}
@@ -0,0 +1,107 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrLastInsertId is returned by Result.LastInsertId. PostgreSQL has no
+// notion of a last insert id; ask for generated values with RETURNING.
+var ErrLastInsertId = errors.New("pq: LastInsertId is not supported; use INSERT ... RETURNING id with QueryRow, or Conn.ExecReturning")
+
+// Result is the driver.Result of an Exec.
+type Result struct {
+	tag          string
+	rowsAffected int64
+	returning    [][]driver.Value
+}
+
+// CommandTag returns the tag the server completed the command with, e.g.
+// "INSERT 0 1", "UPDATE 3" or "COPY 42". It is empty for an empty query.
+func (r *Result) CommandTag() string {
+	return r.tag
+}
+
+// Command returns the command part of the tag, without the counts: e.g.
+// "INSERT", "UPDATE" or "CREATE TABLE".
+func (r *Result) Command() string {
+	f := strings.Fields(r.tag)
+	for len(f) > 0 && isDigits(f[len(f)-1]) {
+		f = f[:len(f)-1]
+	}
+	return strings.Join(f, " ")
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// LastInsertId always fails with ErrLastInsertId.
+func (r *Result) LastInsertId() (int64, error) {
+	return 0, ErrLastInsertId
+}
+
+// RowsAffected returns the number of rows the command processed.
+func (r *Result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// Returning returns the rows the command produced, such as the values of
+// an INSERT ... RETURNING clause, decoded as Query decodes them. Only
+// ExecReturning keeps them: it is nil for the result of an Exec.
+func (r *Result) Returning() [][]driver.Value {
+	return r.returning
+}
+
+// ExecReturning is Exec for commands whose rows are wanted along with
+// their result, such as INSERT ... RETURNING: they are returned by the
+// result's Returning. The rows are kept in memory, so use Query for large
+// results. database/sql hides the driver's result; reach ExecReturning
+// through sql.Conn.Raw.
+func (cn *Conn) ExecReturning(q string, args []driver.Value) (res *Result, err error) {
+	dr, err := cn.Query(q, args)
+	if err != nil {
+		return nil, err
+	}
+	r := dr.(*rows)
+	defer r.Close()
+
+	var returning [][]driver.Value
+	for {
+		dest := make([]driver.Value, len(r.fields))
+		err := r.Next(dest)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		returning = append(returning, dest)
+	}
+	if err := r.Close(); err != nil {
+		return nil, err
+	}
+
+	res = parseCommandTag(r.tag)
+	res.returning = returning
+	return res, nil
+}
+
+// parseCommandTag returns the result for a CommandComplete tag such as
+// "INSERT 0 5" or "UPDATE 3", whose last field is the row count.
+func parseCommandTag(tag string) *Result {
+	res := &Result{tag: tag}
+	i := strings.LastIndex(tag, " ")
+	if n, err := strconv.ParseInt(tag[i+1:], 10, 64); err == nil {
+		res.rowsAffected = n
+	}
+	return res
+}
@@ -0,0 +1,77 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestExecReturning(t *testing.T) {
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'1', nil},
+		testMsg{'t', []interface{}{int16(1), int32(OidText)}},
+		testMsg{'T', []interface{}{int16(1), "id", int32(0), int16(0), int32(OidInt8), int16(8), int32(-1), int16(0)}},
+		testMsg{'2', nil},
+		testMsg{'D', []interface{}{int16(1), int32(2), []byte("42")}},
+		testMsg{'C', []interface{}{"INSERT 0 1"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}, msg: newMsg()}
+
+	r, err := cn.ExecReturning("INSERT INTO t (a) VALUES ($1) RETURNING id", []driver.Value{"x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := r.RowsAffected(); n != 1 {
+		t.Fatalf("expected 1 row affected, got %d", n)
+	}
+
+	returning := r.Returning()
+	if len(returning) != 1 || returning[0][0] != int64(42) {
+		t.Fatalf("unexpected returned rows %v", returning)
+	}
+
+	if _, err := r.LastInsertId(); err != ErrLastInsertId {
+		t.Fatalf("expected ErrLastInsertId, got %v", err)
+	}
+}
+
+func TestExecKeepsNoRows(t *testing.T) {
+	cn := &Conn{c: &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'T', []interface{}{int16(1), "id", int32(0), int16(0), int32(OidInt8), int16(8), int32(-1), int16(0)}},
+		testMsg{'D', []interface{}{int16(1), int32(2), []byte("42")}},
+		testMsg{'C', []interface{}{"INSERT 0 1"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}, msg: newMsg()}
+
+	res, err := cn.Exec("INSERT INTO t (a) VALUES ('x') RETURNING id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := res.(*Result)
+	if n, _ := r.RowsAffected(); n != 1 {
+		t.Fatalf("expected 1 row affected, got %d", n)
+	}
+	if r.Returning() != nil {
+		t.Fatalf("expected Exec to keep no rows, got %v", r.Returning())
+	}
+}
+
+func TestResultCommand(t *testing.T) {
+	tests := map[string]string{
+		"INSERT 0 1":   "INSERT",
+		"UPDATE 3":     "UPDATE",
+		"CREATE TABLE": "CREATE TABLE",
+		"COPY 42":      "COPY",
+		"":             "",
+	}
+
+	for tag, expected := range tests {
+		r := parseCommandTag(tag)
+		if r.CommandTag() != tag {
+			t.Errorf("expected tag %q, got %q", tag, r.CommandTag())
+		}
+		if r.Command() != expected {
+			t.Errorf("%q: expected command %q, got %q", tag, expected, r.Command())
+		}
+	}
+}
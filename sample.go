@@ -0,0 +1,50 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"math/rand"
+)
+
+// ParamSampler records the full parameter values of a random fraction of
+// queries, to give enough material to reproduce bugs that only show up
+// with production data without logging every query.
+type ParamSampler struct {
+	// Rate is the fraction of parameterized queries sampled, between 0
+	// and 1.
+	Rate float64
+
+	// Redact, if non-nil, is called for each parameter of a sampled query
+	// and returns the value to log in its place, e.g. "[redacted]" for
+	// anything that looks like a password or card number.
+	Redact func(query string, i int, v driver.Value) driver.Value
+
+	// Log receives the sampled query and its redacted parameters. It must
+	// be safe for concurrent use.
+	Log func(query string, args []driver.Value)
+}
+
+// SetParamSampler installs s on the connections opened through cr,
+// including those already open, from their next query on. A nil s turns
+// sampling off. It is safe to call while the connections are in use.
+func (cr *Connector) SetParamSampler(s *ParamSampler) {
+	cr.sampler.Store(s)
+}
+
+func (cn *Conn) sampleParams(q string, args []driver.Value) {
+	if cn.connector == nil || len(args) == 0 {
+		return
+	}
+	s, _ := cn.connector.sampler.Load().(*ParamSampler)
+	if s == nil || s.Log == nil || rand.Float64() >= s.Rate {
+		return
+	}
+
+	logged := make([]driver.Value, len(args))
+	for i, v := range args {
+		if s.Redact != nil {
+			v = s.Redact(q, i, v)
+		}
+		logged[i] = v
+	}
+	s.Log(q, logged)
+}
@@ -0,0 +1,50 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestSampleParams(t *testing.T) {
+	cr, err := NewConnector("host=localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logged [][]driver.Value
+	cr.SetParamSampler(&ParamSampler{
+		Rate: 1,
+		Redact: func(q string, i int, v driver.Value) driver.Value {
+			if i == 1 {
+				return "[redacted]"
+			}
+			return v
+		},
+		Log: func(q string, args []driver.Value) {
+			logged = append(logged, args)
+		},
+	})
+
+	cn := &Conn{connector: cr}
+	args := []driver.Value{"alice", "hunter2"}
+	cn.sampleParams("SELECT $1, $2", args)
+
+	if len(logged) != 1 {
+		t.Fatalf("expected 1 sample, got %d", len(logged))
+	}
+	if logged[0][0] != "alice" || logged[0][1] != "[redacted]" {
+		t.Fatalf("unexpected sample %v", logged[0])
+	}
+	if args[1] != "hunter2" {
+		t.Fatal("redaction must not modify the query arguments")
+	}
+
+	// Applies to the connection already open.
+	cr.SetParamSampler(&ParamSampler{Rate: 0, Log: func(string, []driver.Value) {
+		t.Fatal("expected no sample at rate 0")
+	}})
+	cn.sampleParams("SELECT $1, $2", args)
+
+	cr.SetParamSampler(nil)
+	cn.sampleParams("SELECT $1, $2", args)
+}
@@ -0,0 +1,74 @@
+package pq
+
+// SimpleResult is the outcome of one of the statements run by SimpleQuery.
+type SimpleResult struct {
+	Columns    []string
+	Rows       [][][]byte // a nil value is NULL
+	CommandTag string
+}
+
+// SimpleQuery runs q through the simple query protocol and returns the
+// result of every statement in it, along with any notices the server sent
+// on the way. Unlike Query and Exec it accepts anything the server does,
+// including several statements and utility commands the extended protocol
+// refuses, which makes it the tool of choice for migrations.
+//
+// Reach it from database/sql through sql.Conn.Raw.
+func (cn *Conn) SimpleQuery(q string) (results []*SimpleResult, notices []*Error, err error) {
+	defer cn.errRecover(&err)
+	cn.checkBad()
+	cn.enter()
+	defer cn.leave()
+	cn.checkReady()
+	cn.checkTx(q)
+
+	h, next := cn.noticeHandler, cn.noticeFunc()
+	cn.noticeHandler = func(n *Error) {
+		notices = append(notices, n)
+		if next != nil {
+			next(n)
+		}
+	}
+	defer func() {
+		cn.noticeHandler = h
+	}()
+
+	cn.setHead('Q')
+	cn.write(q)
+	cn.sendMsg()
+
+	res := new(SimpleResult)
+	for {
+		cn.recvMsg()
+		switch cn.T {
+		case 'T':
+			res.Columns = columnNames(cn.readRowDescription())
+		case 'D':
+			var n int16
+			var l int32
+			cn.read(&n)
+			row := make([][]byte, n)
+			for i := range row {
+				cn.read(&l)
+				if l < 0 {
+					continue
+				}
+				row[i] = make([]byte, l)
+				cn.read(row[i])
+			}
+			res.Rows = append(res.Rows, row)
+		case 'C':
+			res.CommandTag = cn.readCString()
+			results = append(results, res)
+			res = new(SimpleResult)
+		case 'I':
+			results = append(results, res)
+			res = new(SimpleResult)
+		case 'Z':
+			cn.read(&cn.status)
+			return results, notices, nil
+		default:
+			panic(cn.unexpected("simple query"))
+		}
+	}
+}
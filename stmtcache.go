@@ -0,0 +1,54 @@
+package pq
+
+import (
+	"container/list"
+)
+
+// stmtCache is a least-recently-used cache of prepared statements keyed by
+// query text. Enable it with the statement_cache_size connection option;
+// it is off by default since named statements do not survive transaction
+// pooling proxies. A statement whose execution reports it stale is evicted
+// and prepared again on the next use; see Conn.evictStale.
+type stmtCache struct {
+	size int
+	l    *list.List
+	m    map[string]*list.Element
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{size: size, l: list.New(), m: make(map[string]*list.Element)}
+}
+
+func (c *stmtCache) get(q string) *stmt {
+	e, ok := c.m[q]
+	if !ok {
+		return nil
+	}
+	c.l.MoveToFront(e)
+	return e.Value.(*stmt)
+}
+
+// put adds st to the cache, returning the statement it evicted, if any.
+func (c *stmtCache) put(st *stmt) (evicted *stmt) {
+	c.m[st.q] = c.l.PushFront(st)
+	if c.l.Len() <= c.size {
+		return nil
+	}
+
+	e := c.l.Back()
+	c.l.Remove(e)
+	evicted = e.Value.(*stmt)
+	delete(c.m, evicted.q)
+	return evicted
+}
+
+// remove drops the statement cached for q, returning it, if any.
+func (c *stmtCache) remove(q string) *stmt {
+	e, ok := c.m[q]
+	if !ok {
+		return nil
+	}
+	c.l.Remove(e)
+	delete(c.m, q)
+	return e.Value.(*stmt)
+}
@@ -0,0 +1,68 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestStmtCache(t *testing.T) {
+	c := newStmtCache(2)
+	a := &stmt{name: "a", q: "SELECT 1"}
+	b := &stmt{name: "b", q: "SELECT 2"}
+	d := &stmt{name: "d", q: "SELECT 3"}
+
+	if c.put(a) != nil || c.put(b) != nil {
+		t.Fatal("unexpected eviction")
+	}
+
+	if c.get("SELECT 1") != a {
+		t.Fatal("expected a to be cached")
+	}
+
+	if evicted := c.put(d); evicted != b {
+		t.Fatalf("expected b to be evicted, got %v", evicted)
+	}
+
+	if c.get("SELECT 2") != nil {
+		t.Fatal("expected b to be gone")
+	}
+	if c.get("SELECT 1") != a || c.get("SELECT 3") != d {
+		t.Fatal("expected a and d to be cached")
+	}
+}
+
+func TestEvictStaleStmt(t *testing.T) {
+	for _, tt := range []struct {
+		code, sent string
+	}{
+		// The statement is still on the server: it is closed.
+		{"0A000", "BESCS"},
+		// It was deallocated already.
+		{"26000", "BES"},
+	} {
+		msgs := []testMsg{
+			{'E', []interface{}{byte('S'), "ERROR", byte('C'), tt.code, byte('M'), "stale", byte(0)}},
+			{'Z', []interface{}{byte('I')}},
+		}
+		if tt.code == "0A000" {
+			msgs = append(msgs, testMsg{'3', nil}, testMsg{'Z', []interface{}{byte('I')}})
+		}
+		conn := &replayConn{r: bytes.NewReader(serverMsgs(msgs...))}
+		cn := &Conn{c: conn, msg: newMsg(), stmts: newStmtCache(2)}
+
+		q := "SELECT * FROM t WHERE a = $1"
+		cn.stmts.put(&stmt{Conn: cn, name: "pq_1", q: q})
+
+		_, err := cn.Exec(q, []driver.Value{"x"})
+		if e, ok := err.(*Error); !ok || e.Code != tt.code {
+			t.Fatalf("%s: expected the server error, got %v", tt.code, err)
+		}
+		if cn.stmts.get(q) != nil {
+			t.Fatalf("%s: expected the statement to be evicted", tt.code)
+		}
+		if types := sentTypes(t, conn.w.Bytes()); types != tt.sent {
+			t.Fatalf("%s: expected %q to be sent, got %q", tt.code, tt.sent, types)
+		}
+	}
+}
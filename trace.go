@@ -0,0 +1,95 @@
+package pq
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// QueryTrace holds the timings of one query, measured from the moment it
+// was sent. FirstRow and Ready separate the time the server spent before
+// producing results from the time spent transferring them.
+type QueryTrace struct {
+	Query string
+	Start time.Time
+
+	// FirstRow is the time until the first DataRow arrived, or zero if
+	// the query returned no rows.
+	FirstRow time.Duration
+
+	// Ready is the time until the server sent ReadyForQuery.
+	Ready time.Duration
+
+	// Err is the server error the query failed with, if any.
+	Err error
+}
+
+// Stats are cumulative query timings for all connections opened through a
+// Connector.
+type Stats struct {
+	Queries  int64
+	Errors   int64
+	FirstRow time.Duration // summed over queries that returned rows
+	Ready    time.Duration
+}
+
+type stats struct {
+	queries  int64
+	errors   int64
+	firstRow int64
+	ready    int64
+}
+
+// SetTracer installs f to be called with the trace of every query on
+// connections opened through cr once the query completes, including
+// queries already running. f must be safe for concurrent use; SetTracer
+// may be called while the connections are in use.
+func (cr *Connector) SetTracer(f func(*QueryTrace)) {
+	cr.tracer.Store(f)
+}
+
+// Stats returns the query timings accumulated so far.
+func (cr *Connector) Stats() Stats {
+	return Stats{
+		Queries:  atomic.LoadInt64(&cr.stats.queries),
+		Errors:   atomic.LoadInt64(&cr.stats.errors),
+		FirstRow: time.Duration(atomic.LoadInt64(&cr.stats.firstRow)),
+		Ready:    time.Duration(atomic.LoadInt64(&cr.stats.ready)),
+	}
+}
+
+func (cn *Conn) startTrace(q string) {
+	if cn.connector == nil {
+		return
+	}
+	cn.trace = &QueryTrace{Query: q, Start: time.Now()}
+}
+
+// traceMsg updates the current trace with the message just received.
+func (cn *Conn) traceMsg() {
+	t := cn.trace
+	if t == nil {
+		return
+	}
+
+	switch cn.T {
+	case 'D':
+		if t.FirstRow == 0 {
+			t.FirstRow = time.Since(t.Start)
+		}
+	case 'Z':
+		t.Ready = time.Since(t.Start)
+		cn.trace = nil
+
+		s := &cn.connector.stats
+		atomic.AddInt64(&s.queries, 1)
+		if t.Err != nil {
+			atomic.AddInt64(&s.errors, 1)
+		}
+		atomic.AddInt64(&s.firstRow, int64(t.FirstRow))
+		atomic.AddInt64(&s.ready, int64(t.Ready))
+
+		if f, _ := cn.connector.tracer.Load().(func(*QueryTrace)); f != nil {
+			f(t)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package pq
+
+import (
+	"testing"
+)
+
+func TestTraceMsg(t *testing.T) {
+	cr, err := NewConnector("host=localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var traces []*QueryTrace
+	cr.SetTracer(func(t *QueryTrace) {
+		traces = append(traces, t)
+	})
+
+	cn := &Conn{connector: cr, msg: newMsg()}
+	cn.startTrace("SELECT 1")
+	for _, typ := range []int8{'T', 'D', 'D', 'C', 'Z'} {
+		cn.T = typ
+		cn.traceMsg()
+	}
+
+	if len(traces) != 1 {
+		t.Fatalf("expected 1 trace, got %d", len(traces))
+	}
+	tr := traces[0]
+	if tr.Query != "SELECT 1" || tr.FirstRow <= 0 || tr.Ready < tr.FirstRow {
+		t.Fatalf("unexpected trace %+v", tr)
+	}
+
+	st := cr.Stats()
+	if st.Queries != 1 || st.Ready != tr.Ready || st.FirstRow != tr.FirstRow {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestSetTracerWhileTracing(t *testing.T) {
+	cr, err := NewConnector("host=localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cn := &Conn{connector: cr, msg: newMsg()}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			cr.SetTracer(func(*QueryTrace) {})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		cn.startTrace("SELECT 1")
+		cn.T = 'Z'
+		cn.traceMsg()
+	}
+	<-done
+}
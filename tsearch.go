@@ -0,0 +1,322 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"strconv"
+	"strings"
+)
+
+// TSVector is a tsvector: its lexemes, sorted as the server keeps them.
+// tsvector columns decode to their text, which scans into a string; scan
+// them into a TSVector to inspect the lexemes.
+type TSVector []Lexeme
+
+// Lexeme is a lexeme of a tsvector, with the positions it occurs at, if
+// the vector has them.
+type Lexeme struct {
+	Word      string
+	Positions []LexemePosition
+}
+
+// LexemePosition is a position of a lexeme, counting words from 1, with
+// its weight: 'A', 'B', 'C', or 'D', the default.
+type LexemePosition struct {
+	Pos    int
+	Weight byte
+}
+
+// Scan implements sql.Scanner.
+func (v *TSVector) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	p := &tsParser{s: tsText(src, "TSVector")}
+	vec := TSVector{}
+	for p.space(); p.i < len(p.s); p.space() {
+		l := Lexeme{Word: p.word()}
+		if p.peek(':') {
+			for {
+				p.i++
+				l.Positions = append(l.Positions, p.position())
+				if !p.peek(',') {
+					break
+				}
+			}
+		}
+		vec = append(vec, l)
+	}
+	*v = vec
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (v TSVector) Value() (driver.Value, error) {
+	return v.String(), nil
+}
+
+// String returns the text form of v.
+func (v TSVector) String() string {
+	var b strings.Builder
+	for i, l := range v {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		writeTSWord(&b, l.Word)
+		for j, p := range l.Positions {
+			if j == 0 {
+				b.WriteByte(':')
+			} else {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Itoa(p.Pos))
+			if p.Weight != 'D' && p.Weight != 0 {
+				b.WriteByte(p.Weight)
+			}
+		}
+	}
+	return b.String()
+}
+
+// TSQuery is a tsquery, as the tree of its operators. A leaf is a lexeme,
+// with Op empty; the empty query is the zero TSQuery.
+type TSQuery struct {
+	// Op is the operator: "&", "|", "!", which has no Right operand, or
+	// a phrase operator, "<->" or "<N>" for a distance of N.
+	Op          string
+	Left, Right *TSQuery
+
+	// Lexeme is the lexeme of a leaf, matching words with it as a prefix
+	// if Prefix is set, and only at the weights listed in Weights, such
+	// as "AB", if any.
+	Lexeme  string
+	Prefix  bool
+	Weights string
+}
+
+// Scan implements sql.Scanner.
+func (q *TSQuery) Scan(src interface{}) (err error) {
+	defer recoverErr(&err)
+
+	p := &tsParser{s: tsText(src, "TSQuery")}
+	p.space()
+	if p.i == len(p.s) {
+		*q = TSQuery{}
+		return nil
+	}
+	r := p.or()
+	p.space()
+	if p.i != len(p.s) {
+		p.fail()
+	}
+	*q = *r
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (q TSQuery) Value() (driver.Value, error) {
+	return q.String(), nil
+}
+
+// String returns the text form of q, with every operation bracketed.
+func (q TSQuery) String() string {
+	var b strings.Builder
+	q.write(&b, true)
+	return b.String()
+}
+
+func (q *TSQuery) write(b *strings.Builder, top bool) {
+	switch {
+	case q.Op == "" && q.Lexeme == "":
+	case q.Op == "":
+		writeTSWord(b, q.Lexeme)
+		if q.Prefix || q.Weights != "" {
+			b.WriteByte(':')
+			if q.Prefix {
+				b.WriteByte('*')
+			}
+			b.WriteString(q.Weights)
+		}
+	case q.Op == "!":
+		b.WriteByte('!')
+		q.Left.write(b, false)
+	default:
+		if !top {
+			b.WriteString("( ")
+		}
+		q.Left.write(b, false)
+		b.WriteString(" " + q.Op + " ")
+		q.Right.write(b, false)
+		if !top {
+			b.WriteString(" )")
+		}
+	}
+}
+
+// writeTSWord writes a lexeme quoted, as the server does.
+func writeTSWord(b *strings.Builder, w string) {
+	b.WriteByte('\'')
+	for i := 0; i < len(w); i++ {
+		switch w[i] {
+		case '\'':
+			b.WriteString("''")
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteByte(w[i])
+		}
+	}
+	b.WriteByte('\'')
+}
+
+func tsText(src interface{}, into string) string {
+	switch v := src.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	panic(errf("cannot scan %T into a %s", src, into))
+}
+
+// tsParser reads the text forms of tsvectors and tsqueries.
+type tsParser struct {
+	s string
+	i int
+}
+
+func (p *tsParser) fail() {
+	panic(errf("invalid text search value %q at offset %d", p.s, p.i))
+}
+
+func (p *tsParser) space() {
+	for p.i < len(p.s) && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
+
+func (p *tsParser) peek(c byte) bool {
+	return p.i < len(p.s) && p.s[p.i] == c
+}
+
+// word reads a lexeme: quoted, as the server writes them, or bare.
+func (p *tsParser) word() string {
+	if !p.peek('\'') {
+		start := p.i
+		for p.i < len(p.s) && !strings.ContainsRune(" :'&|!()<", rune(p.s[p.i])) {
+			p.i++
+		}
+		if p.i == start {
+			p.fail()
+		}
+		return p.s[start:p.i]
+	}
+
+	var b strings.Builder
+	for p.i++; ; p.i++ {
+		if p.i == len(p.s) {
+			p.fail()
+		}
+		switch c := p.s[p.i]; {
+		case c == '\'' && p.i+1 < len(p.s) && p.s[p.i+1] == '\'':
+			p.i++
+			b.WriteByte('\'')
+		case c == '\'':
+			p.i++
+			return b.String()
+		case c == '\\' && p.i+1 < len(p.s):
+			p.i++
+			b.WriteByte(p.s[p.i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// position reads a lexeme position of a tsvector, and its weight.
+func (p *tsParser) position() LexemePosition {
+	start := p.i
+	for p.i < len(p.s) && isDigit(p.s[p.i]) {
+		p.i++
+	}
+	n, err := strconv.Atoi(p.s[start:p.i])
+	if err != nil {
+		p.fail()
+	}
+	pos := LexemePosition{Pos: n, Weight: 'D'}
+	if p.i < len(p.s) && p.s[p.i] >= 'A' && p.s[p.i] <= 'D' {
+		pos.Weight = p.s[p.i]
+		p.i++
+	}
+	return pos
+}
+
+// or, and, phrase and unary read a tsquery by the precedence of its
+// operators, from the loosest.
+func (p *tsParser) or() *TSQuery {
+	q := p.and()
+	for p.space(); p.peek('|'); p.space() {
+		p.i++
+		q = &TSQuery{Op: "|", Left: q, Right: p.and()}
+	}
+	return q
+}
+
+func (p *tsParser) and() *TSQuery {
+	q := p.phrase()
+	for p.space(); p.peek('&'); p.space() {
+		p.i++
+		q = &TSQuery{Op: "&", Left: q, Right: p.phrase()}
+	}
+	return q
+}
+
+func (p *tsParser) phrase() *TSQuery {
+	q := p.unary()
+	for p.space(); p.peek('<'); p.space() {
+		end := strings.IndexByte(p.s[p.i:], '>')
+		if end < 0 {
+			p.fail()
+		}
+		op := p.s[p.i : p.i+end+1]
+		if op != "<->" {
+			if _, err := strconv.Atoi(op[1 : len(op)-1]); err != nil {
+				p.fail()
+			}
+		}
+		p.i += end + 1
+		q = &TSQuery{Op: op, Left: q, Right: p.unary()}
+	}
+	return q
+}
+
+func (p *tsParser) unary() *TSQuery {
+	p.space()
+	switch {
+	case p.peek('!'):
+		p.i++
+		return &TSQuery{Op: "!", Left: p.unary()}
+	case p.peek('('):
+		p.i++
+		q := p.or()
+		p.space()
+		if !p.peek(')') {
+			p.fail()
+		}
+		p.i++
+		return q
+	}
+
+	q := &TSQuery{Lexeme: p.word()}
+	if p.peek(':') {
+		for p.i++; p.i < len(p.s); p.i++ {
+			c := p.s[p.i]
+			if c == '*' {
+				q.Prefix = true
+			} else if c >= 'A' && c <= 'D' || c >= 'a' && c <= 'd' {
+				q.Weights += strings.ToUpper(string(c))
+			} else {
+				break
+			}
+		}
+	}
+	return q
+}
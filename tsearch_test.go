@@ -0,0 +1,75 @@
+package pq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTSVector(t *testing.T) {
+	var v TSVector
+	in := `'a':1A,2 'cat':3 'fat':2B,4C 'it''s' 'back\\slash'`
+	if err := v.Scan([]byte(in)); err != nil {
+		t.Fatal(err)
+	}
+	expected := TSVector{
+		{Word: "a", Positions: []LexemePosition{{1, 'A'}, {2, 'D'}}},
+		{Word: "cat", Positions: []LexemePosition{{3, 'D'}}},
+		{Word: "fat", Positions: []LexemePosition{{2, 'B'}, {4, 'C'}}},
+		{Word: "it's"},
+		{Word: `back\slash`},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, v)
+	}
+	if s, err := v.Value(); err != nil || s != in {
+		t.Fatalf("expected %s, got %v, %v", in, s, err)
+	}
+
+	if err := v.Scan(""); err != nil || v == nil || len(v) != 0 {
+		t.Fatalf("expected an empty vector, got %+v, %v", v, err)
+	}
+	for _, in := range []string{"'a", "'a':", "'a':x", "'a':1,"} {
+		if err := v.Scan(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
+
+func TestTSQuery(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{`'fat'`, `'fat'`},
+		{`'fat' & 'rat'`, `'fat' & 'rat'`},
+		{`'fat' & ( 'rat' | 'cat' )`, `'fat' & ( 'rat' | 'cat' )`},
+		{`'fat' & 'rat' | 'cat'`, `( 'fat' & 'rat' ) | 'cat'`},
+		{`!'a' <-> 'b' <2> 'c'`, `( !'a' <-> 'b' ) <2> 'c'`},
+		{`'super':*AB & !( 'a' | 'b' )`, `'super':*AB & !( 'a' | 'b' )`},
+		{``, ``},
+	}
+	for _, tt := range tests {
+		var q TSQuery
+		if err := q.Scan([]byte(tt.in)); err != nil {
+			t.Fatalf("%s: %v", tt.in, err)
+		}
+		if s := q.String(); s != tt.out {
+			t.Errorf("%s: expected %s, got %s", tt.in, tt.out, s)
+		}
+	}
+
+	var q TSQuery
+	if err := q.Scan(`'fat' & ( 'rat' | !'cat' ) <-> 'x':*`); err != nil {
+		t.Fatal(err)
+	}
+	if q.Op != "&" || q.Left.Lexeme != "fat" || q.Right.Op != "<->" || q.Right.Left.Op != "|" ||
+		q.Right.Left.Right.Op != "!" || q.Right.Left.Right.Left.Lexeme != "cat" || !q.Right.Right.Prefix {
+		t.Fatalf("unexpected tree for %s", q)
+	}
+
+	for _, in := range []string{"'a' &", "( 'a'", "'a' 'b'", "'a' <x> 'b'", "&"} {
+		if err := q.Scan(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+}
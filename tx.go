@@ -0,0 +1,59 @@
+package pq
+
+import (
+	"strings"
+)
+
+// TxStatus is the transaction status of a connection, as reported by the
+// server at the end of every exchange.
+type TxStatus byte
+
+const (
+	TxIdle          TxStatus = 'I' // not in a transaction
+	TxInTransaction TxStatus = 'T' // in a transaction block
+	TxFailed        TxStatus = 'E' // in a failed transaction block
+)
+
+func (s TxStatus) String() string {
+	switch s {
+	case TxIdle:
+		return "idle"
+	case TxInTransaction:
+		return "in transaction"
+	case TxFailed:
+		return "in failed transaction"
+	}
+	return "unknown"
+}
+
+// TxStatus returns the transaction status as of the end of the last
+// exchange with the server.
+func (cn *Conn) TxStatus() TxStatus {
+	return TxStatus(cn.status)
+}
+
+// errTxAborted is the error of a statement run in a failed transaction,
+// which the server would refuse.
+var errTxAborted = errf("current transaction is aborted, commands ignored until end of transaction block; roll it back first")
+
+// checkTx refuses to run q in a failed transaction unless it ends the
+// transaction, saving the round trip to have the server refuse it.
+func (cn *Conn) checkTx(q string) {
+	if TxStatus(cn.status) == TxFailed && !isTxExit(q) {
+		panic(errTxAborted)
+	}
+}
+
+// isTxExit reports whether q is one of the statements a failed
+// transaction accepts: those ending it or rolling back to a savepoint.
+func isTxExit(q string) bool {
+	f := strings.Fields(q)
+	if len(f) == 0 {
+		return false
+	}
+	switch strings.ToUpper(strings.TrimRight(f[0], ";")) {
+	case "ROLLBACK", "ABORT", "COMMIT", "END":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package pq
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestTxFailedShortCircuit(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(nil)}
+	cn := &Conn{c: conn, msg: newMsg(), status: byte(TxFailed)}
+
+	if cn.TxStatus() != TxFailed {
+		t.Fatalf("expected %s, got %s", TxFailed, cn.TxStatus())
+	}
+
+	if _, err := cn.Exec("SELECT 1", []driver.Value{int64(1)}); err != errTxAborted {
+		t.Fatalf("expected errTxAborted, got %v", err)
+	}
+	if conn.w.Len() != 0 {
+		t.Fatal("expected nothing to be sent")
+	}
+	if cn.bad {
+		t.Fatal("expected the connection to stay usable")
+	}
+}
+
+func TestIsTxExit(t *testing.T) {
+	tests := []struct {
+		q        string
+		expected bool
+	}{
+		{"ROLLBACK", true},
+		{"  rollback to savepoint a", true},
+		{"COMMIT;", true},
+		{"abort", true},
+		{"END", true},
+		{"SELECT 1", false},
+		{"RELEASE SAVEPOINT a", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := isTxExit(test.q); got != test.expected {
+			t.Errorf("%q: expected %v, got %v", test.q, test.expected, got)
+		}
+	}
+}
+
+func TestBeginThenExec(t *testing.T) {
+	conn := &replayConn{r: bytes.NewReader(serverMsgs(
+		testMsg{'C', []interface{}{"BEGIN"}},
+		testMsg{'Z', []interface{}{byte('T')}},
+		testMsg{'C', []interface{}{"INSERT 0 1"}},
+		testMsg{'Z', []interface{}{byte('T')}},
+		testMsg{'C', []interface{}{"COMMIT"}},
+		testMsg{'Z', []interface{}{byte('I')}},
+	))}
+	cn := &Conn{c: conn, msg: newMsg(), status: byte(TxIdle)}
+
+	tx, err := cn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cn.state != stateReady || cn.TxStatus() != TxInTransaction {
+		t.Fatalf("expected to be ready in a transaction, got %s, %s", cn.state, cn.TxStatus())
+	}
+	if _, err := cn.Exec("INSERT INTO t VALUES (1)", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if cn.TxStatus() != TxIdle {
+		t.Fatalf("expected to be idle, got %s", cn.TxStatus())
+	}
+	if types := sentTypes(t, conn.w.Bytes()); types != "QQQ" {
+		t.Fatalf("expected three simple queries, sent %q", types)
+	}
+}
@@ -0,0 +1,109 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"strings"
+)
+
+// TypeCodec plugs a type the driver doesn't know, such as citext or
+// PostGIS's geometry, into it. Install one with RegisterType or
+// RegisterTypeOid.
+type TypeCodec struct {
+	// Decode converts the text of a result of the type, which then
+	// scans into a destination of what it returns. Without it results
+	// are the raw bytes.
+	Decode func(b []byte) (interface{}, error)
+
+	// Encode formats a parameter as a literal of the type, reporting
+	// false for values it doesn't handle. Types implementing
+	// driver.Valuer don't need it.
+	Encode func(v interface{}) (s string, ok bool)
+}
+
+type registeredType struct {
+	name  string
+	oid   Oid
+	codec TypeCodec
+}
+
+// registeredTypes holds the codecs in the order they were registered,
+// which is the order their encoders are tried in.
+var registeredTypes []registeredType
+
+// RegisterType installs c for all connections for the type name, which
+// may be schema-qualified. Extension types have no fixed oid, so
+// connections look it up as they open; they ignore names that don't
+// exist in their database. It isn't safe to call concurrently with
+// queries: call it from an init function.
+func RegisterType(name string, c TypeCodec) {
+	registeredTypes = append(registeredTypes, registeredType{name: name, codec: c})
+}
+
+// RegisterTypeOid installs c for all connections for the type of the
+// given oid, which must be the same in every database, as the oids of
+// built-in types are. It isn't safe to call concurrently with queries:
+// call it from an init function.
+func RegisterTypeOid(oid Oid, c TypeCodec) {
+	registeredTypes = append(registeredTypes, registeredType{oid: oid, codec: c})
+}
+
+// loadTypes sets up the decoders of the registered types, looking up the
+// oids of those registered by name.
+func (cn *Conn) loadTypes() (err error) {
+	defer recoverErr(&err)
+
+	cn.types = make(map[Oid]TypeCodec)
+	var named []registeredType
+	var cols []string
+	for _, t := range registeredTypes {
+		if t.name == "" {
+			cn.types[t.oid] = t.codec
+			continue
+		}
+		named = append(named, t)
+		cols = append(cols, "to_regtype("+quoteLiteral(t.name)+")::oid")
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+
+	r := cn.simpleQuery("SELECT " + strings.Join(cols, ", "))
+	defer r.Close()
+
+	row := make([]driver.Value, len(cols))
+	if err := r.Next(row); err != nil {
+		return err
+	}
+	for i, v := range row {
+		if v != nil {
+			cn.types[v.(Oid)] = named[i].codec
+		}
+	}
+	return nil
+}
+
+// decodeType decodes the text of a result of a registered type.
+func decodeType(c TypeCodec, typ Oid, b []byte) driver.Value {
+	if c.Decode == nil {
+		return b
+	}
+	v, err := c.Decode(b)
+	if err != nil {
+		panic(errf("invalid value %q for type %d: %v", b, typ, err))
+	}
+	return v
+}
+
+// typeParam returns the literal for v from the first registered encoder
+// that handles it.
+func typeParam(v interface{}) (string, bool) {
+	for _, t := range registeredTypes {
+		if t.codec.Encode == nil {
+			continue
+		}
+		if s, ok := t.codec.Encode(v); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package pq
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testCitext string
+
+func TestRegisterType(t *testing.T) {
+	defer func(saved []registeredType) { registeredTypes = saved }(registeredTypes)
+	RegisterType("citext", TypeCodec{
+		Decode: func(b []byte) (interface{}, error) {
+			return testCitext(b), nil
+		},
+		Encode: func(v interface{}) (string, bool) {
+			s, ok := v.(testCitext)
+			return string(s), ok
+		},
+	})
+	RegisterType("missing", TypeCodec{})
+	RegisterTypeOid(OidInt4, TypeCodec{
+		Decode: func(b []byte) (interface{}, error) {
+			return nil, errors.New("no")
+		},
+	})
+
+	var buf bytes.Buffer
+	m := newMsg()
+	msgs := []struct {
+		typ  int8
+		body []interface{}
+	}{
+		{'T', []interface{}{int16(2),
+			"to_regtype", int32(0), int16(0), int32(OidOid), int16(4), int32(-1), int16(0),
+			"to_regtype", int32(0), int16(0), int32(OidOid), int16(4), int32(-1), int16(0),
+		}},
+		{'D', []interface{}{int16(2), int32(5), []byte("16500"), int32(-1)}},
+		{'C', []interface{}{"SELECT 1"}},
+		{'Z', []interface{}{byte('I')}},
+	}
+	for _, msg := range msgs {
+		m.setHead(msg.typ)
+		m.write(msg.body...)
+		m.writeTo(&buf)
+	}
+
+	conn := &replayConn{r: bytes.NewReader(buf.Bytes())}
+	cn := &Conn{c: conn, msg: newMsg(), binaryResults: true}
+	if err := cn.loadTypes(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := conn.w.String(); !strings.Contains(sent, "to_regtype('citext')::oid, to_regtype('missing')::oid") {
+		t.Fatalf("expected the query to look up the names, sent %q", sent)
+	}
+	if len(cn.types) != 2 {
+		t.Fatalf("unexpected types %v", cn.types)
+	}
+
+	if v := cn.decode(&fieldDesc{typ: 16500}, []byte("Abc")); v != testCitext("Abc") {
+		t.Fatalf("unexpected %#v", v)
+	}
+	if cn.resultFormat(OidInt4) != formatText {
+		t.Fatal("expected a registered type to be requested as text")
+	}
+	var err error
+	func() {
+		defer recoverErr(&err)
+		cn.decode(&fieldDesc{typ: OidInt4}, []byte("1"))
+	}()
+	if err == nil || !strings.Contains(err.Error(), "no") {
+		t.Fatalf("expected the decoder's error, got %v", err)
+	}
+
+	if v, err := checkParam(testCitext("x")); err != nil || v != "x" {
+		t.Fatalf("unexpected %#v, %v", v, err)
+	}
+}
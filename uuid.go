@@ -0,0 +1,91 @@
+package pq
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+)
+
+// UUID is a uuid as its 16 bytes. uuid columns decode to their canonical
+// text, which scans into a string; scan them into a UUID to get the bytes
+// instead. Parameters may be a UUID, or of any other [16]byte type.
+type UUID [16]byte
+
+// Scan implements sql.Scanner, accepting the canonical text of a uuid, with
+// or without hyphens, or its 16 bytes.
+func (u *UUID) Scan(src interface{}) error {
+	var b []byte
+	ok := false
+	switch v := src.(type) {
+	case string:
+		b, ok = parseUUID(v)
+	case []byte:
+		if len(v) == 16 {
+			b, ok = v, true
+		} else {
+			b, ok = parseUUID(string(v))
+		}
+	}
+	if !ok {
+		return fmt.Errorf("pq: cannot scan %T %v into a UUID", src, src)
+	}
+	copy(u[:], b)
+	return nil
+}
+
+// Value implements driver.Valuer, sending u in its canonical text form.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// String returns the canonical text form of u.
+func (u UUID) String() string {
+	return formatUUID(u[:])
+}
+
+// uuidParam returns the canonical text form of v if it is a [16]byte, of
+// whatever named type.
+func uuidParam(v interface{}) (string, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Array || rv.Len() != 16 || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return "", false
+	}
+	var b [16]byte
+	reflect.Copy(reflect.ValueOf(b[:]), rv)
+	return formatUUID(b[:]), true
+}
+
+// parseUUID returns the 16 bytes of a uuid in its canonical form, with or
+// without the hyphens.
+func parseUUID(s string) ([]byte, bool) {
+	if len(s) == 36 {
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return nil, false
+		}
+		s = s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	}
+	if len(s) != 32 {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// formatUUID returns the canonical text form of a 16-byte uuid.
+func formatUUID(b []byte) string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf)
+}
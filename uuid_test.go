@@ -0,0 +1,47 @@
+package pq
+
+import (
+	"testing"
+)
+
+const testUUID = "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+
+func TestUUIDScan(t *testing.T) {
+	var u UUID
+	if err := u.Scan(testUUID); err != nil {
+		t.Fatal(err)
+	}
+	if u.String() != testUUID {
+		t.Fatalf("expected %s, got %s", testUUID, u)
+	}
+
+	var raw UUID
+	if err := raw.Scan(u[:]); err != nil || raw != u {
+		t.Fatalf("expected the raw bytes to scan, got %v, %v", raw, err)
+	}
+
+	if err := u.Scan("not a uuid"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+type otherUUID [16]byte
+
+func TestUUIDParam(t *testing.T) {
+	var u UUID
+	u.Scan(testUUID)
+
+	for _, in := range []interface{}{u, [16]byte(u), otherUUID(u)} {
+		v, err := checkParam(in)
+		if err != nil {
+			t.Fatalf("%T: %v", in, err)
+		}
+		if v != testUUID {
+			t.Errorf("%T: expected %s, got %#v", in, testUUID, v)
+		}
+	}
+
+	if v := decodeText(OidUUID, []byte(testUUID)); v != testUUID {
+		t.Fatalf("expected the uuid as a string, got %#v", v)
+	}
+}